@@ -0,0 +1,169 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Access holds the access statistics of a key, as tracked when TrackAccess
+// is set.
+type Access[K any] struct {
+	Key        K
+	Hits       uint64
+	LastAccess time.Time
+}
+
+// HotKeys returns the n live keys with the most hits, by descending number
+// of hits. Keys are only tracked while TrackAccess is set, and their
+// statistics are dropped along with them when they expire.
+func (cache *Cache[K, V]) HotKeys(n int) []Access[K] {
+	if n <= 0 {
+		return nil
+	}
+
+	cache.mux.RLock()
+	defer cache.mux.RUnlock()
+
+	now := cache.instant()
+	hot := make([]Access[K], 0, len(cache.expireList.elts))
+	for _, bucket := range cache.expireList.elts {
+		if !bucket.expiry.After(now) {
+			continue
+		}
+		if access, ok := bucket.access(); ok {
+			hot = append(hot, access)
+		}
+	}
+	sort.Slice(hot, func(i, j int) bool {
+		return hot[i].Hits > hot[j].Hits
+	})
+	if n < len(hot) {
+		hot = hot[:n]
+	}
+	return hot
+}
+
+// KeyCount associates a key with a count, like a number of misses or a
+// size in bytes.
+type KeyCount[K any] struct {
+	Key   K
+	Count uint64
+}
+
+// TopMisses returns the n keys missed the most since TrackAccess was set or
+// since the last call to ResetAccess, by descending number of misses.
+//
+// Misses are counted for any key looked up, so the number of tracked keys
+// is unbounded: ResetAccess should be called regularly, which also makes the
+// reports cover a window of time.
+func (cache *Cache[K, V]) TopMisses(n int) []KeyCount[K] {
+	if n <= 0 {
+		return nil
+	}
+
+	cache.misses.mux.Lock()
+	defer cache.misses.mux.Unlock()
+
+	top := make([]KeyCount[K], 0, len(cache.misses.counts))
+	for key, count := range cache.misses.counts {
+		top = append(top, KeyCount[K]{Key: key, Count: count})
+	}
+	return topCounts(top, n)
+}
+
+// Largest returns the n live keys with the largest values, by descending
+// size as reported by SizeFunc, or nil if SizeFunc is not set.
+func (cache *Cache[K, V]) Largest(n int) []KeyCount[K] {
+	if n <= 0 {
+		return nil
+	}
+
+	cache.mux.RLock()
+	defer cache.mux.RUnlock()
+
+	sizeFunc := cache.SizeFunc
+	if sizeFunc == nil {
+		return nil
+	}
+	now := cache.instant()
+	top := make([]KeyCount[K], 0, len(cache.expireList.elts))
+	for _, bucket := range cache.expireList.elts {
+		if !bucket.expiry.After(now) {
+			continue
+		}
+		value, _ := cache.load(bucket)
+		var size int
+		cache.guard("SizeFunc", func() { size = sizeFunc(bucket.key, value) })
+		if size > 0 {
+			top = append(top, KeyCount[K]{Key: bucket.key, Count: uint64(size)})
+		}
+	}
+	return topCounts(top, n)
+}
+
+// ResetAccess resets the hits and misses tracked for every key, starting a
+// new window for HotKeys and TopMisses.
+func (cache *Cache[K, V]) ResetAccess() {
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	for _, bucket := range cache.expireList.elts {
+		bucket.hits = 0
+		bucket.accessed = 0
+	}
+	cache.misses.mux.Lock()
+	cache.misses.counts = nil
+	cache.misses.mux.Unlock()
+}
+
+func topCounts[K any](counts []KeyCount[K], n int) []KeyCount[K] {
+	sort.Slice(counts, func(i, j int) bool {
+		return counts[i].Count > counts[j].Count
+	})
+	if n <= 0 {
+		return nil
+	}
+	if n < len(counts) {
+		counts = counts[:n]
+	}
+	return counts
+}
+
+// missCounter counts misses by key. Misses happen with the cache locked for
+// reading, so it has its own lock.
+type missCounter[K comparable] struct {
+	counts map[K]uint64
+	mux    sync.Mutex
+}
+
+func (c *missCounter[K]) add(key K) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if c.counts == nil {
+		c.counts = make(map[K]uint64)
+	}
+	c.counts[key]++
+}
+
+// access returns the access statistics of bucket, and false if it was never
+// accessed.
+func (bucket *cacheBucket[K, V]) access() (Access[K], bool) {
+	hits := atomic.LoadUint64(&bucket.hits)
+	if hits == 0 {
+		return Access[K]{}, false
+	}
+	return Access[K]{
+		Key:        bucket.key,
+		Hits:       hits,
+		LastAccess: time.Unix(0, atomic.LoadInt64(&bucket.accessed)),
+	}, true
+}
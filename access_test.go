@@ -0,0 +1,81 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHotKeys(t *testing.T) {
+	c := New[int, int]()
+	c.TrackAccess = true
+	for i := 0; i < 10; i++ {
+		c.Set(i, i, time.Hour)
+		for j := 0; j < i; j++ {
+			c.Get(i)
+		}
+	}
+
+	hot := c.HotKeys(3)
+	if len(hot) != 3 {
+		t.Fatalf("expected 3 hot keys, got %v", hot)
+	}
+	for i, access := range hot {
+		if access.Key != 9-i || access.Hits != uint64(9-i) || access.LastAccess.IsZero() {
+			t.Fatalf("unexpected hot key at %d: %+v", i, access)
+		}
+	}
+	if hot := c.HotKeys(100); len(hot) != 9 {
+		t.Fatalf("expected keys that were never read to be left out, got %v", hot)
+	}
+	if hot := c.HotKeys(-1); hot != nil {
+		t.Fatalf("expected no hot keys for a negative count, got %v", hot)
+	}
+
+	c.TrackAccess = false
+	c.Get(1)
+	if hot := c.HotKeys(100); hot[len(hot)-1].Hits != 1 {
+		t.Fatalf("expected hits not to be tracked anymore, got %v", hot)
+	}
+}
+
+func TestTopMisses(t *testing.T) {
+	c := New[string, []byte]()
+	c.TrackAccess = true
+	for i, key := range []string{"a", "b", "c"} {
+		for j := 0; j <= i; j++ {
+			c.Get(key)
+		}
+	}
+	c.Set("a", make([]byte, 10), time.Hour)
+	c.Set("b", make([]byte, 30), time.Hour)
+	c.Set("c", make([]byte, 20), time.Hour)
+
+	top := c.TopMisses(2)
+	if len(top) != 2 || top[0] != (KeyCount[string]{"c", 3}) || top[1] != (KeyCount[string]{"b", 2}) {
+		t.Fatalf("unexpected top misses: %v", top)
+	}
+
+	if largest := c.Largest(1); largest != nil {
+		t.Fatalf("expected no sizes without SizeFunc, got %v", largest)
+	}
+	c.SizeFunc = func(key string, value []byte) int { return len(value) }
+	largest := c.Largest(2)
+	if len(largest) != 2 || largest[0] != (KeyCount[string]{"b", 30}) || largest[1] != (KeyCount[string]{"c", 20}) {
+		t.Fatalf("unexpected largest keys: %v", largest)
+	}
+	if top, largest := c.TopMisses(-1), c.Largest(-1); top != nil || largest != nil {
+		t.Fatalf("expected nothing for a negative count, got %v and %v", top, largest)
+	}
+
+	c.Get("a")
+	c.ResetAccess()
+	if top, hot := c.TopMisses(10), c.HotKeys(10); len(top) != 0 || len(hot) != 0 {
+		t.Fatalf("expected no statistics after a reset, got %v and %v", top, hot)
+	}
+}
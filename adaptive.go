@@ -0,0 +1,87 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// AdaptiveTTL adjusts the TTLs of keys depending on how often they are read:
+// each time a key is set, its TTL doubles if it was read at least Threshold
+// times since the last time it was set, and halves otherwise, staying
+// within [Min, Max]. Keys start with a TTL of Min.
+//
+// This balances freshness against load on the source of truth: frequently
+// read keys stay cached longer, while rarely read ones make room sooner.
+type AdaptiveTTL[K comparable] struct {
+	Min       time.Duration
+	Max       time.Duration
+	Threshold uint64
+
+	// Keys are remembered for a while after their last set, so that their
+	// TTL survives them expiring and being set again.
+	state *Cache[K, *adaptiveState]
+	mux   sync.Mutex
+}
+
+type adaptiveState struct {
+	hits uint64 // accessed atomically; kept first to be 64-bit aligned on 32-bit platforms
+	ttl  time.Duration
+}
+
+// NewAdaptiveTTL returns a controller adjusting TTLs between min and max,
+// doubling the TTL of keys that were read at least once between two sets.
+//
+// Set it as the Adaptive field of a cache to have it determine the TTL of
+// keys set with DefaultTTL.
+func NewAdaptiveTTL[K comparable](min, max time.Duration) *AdaptiveTTL[K] {
+	return &AdaptiveTTL[K]{
+		Min:       min,
+		Max:       max,
+		Threshold: 1,
+		state:     New[K, *adaptiveState](),
+	}
+}
+
+// TTL returns the TTL a key being set should get, and starts counting reads
+// anew for that key.
+func (a *AdaptiveTTL[K]) TTL(key K) time.Duration {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	st, ok := a.state.Get(key)
+	switch {
+	case !ok:
+		st = &adaptiveState{ttl: a.Min}
+	case atomic.LoadUint64(&st.hits) >= a.Threshold:
+		st.ttl *= 2
+	default:
+		st.ttl /= 2
+	}
+	if st.ttl > a.Max {
+		st.ttl = a.Max
+	}
+	if st.ttl < a.Min {
+		st.ttl = a.Min
+	}
+	atomic.StoreUint64(&st.hits, 0)
+
+	// Past twice the longest TTL without being set again, a key is
+	// unlikely to come back soon, so forget about it.
+	a.state.Set(key, st, 2*a.Max)
+	return st.ttl
+}
+
+// Hit records a read of the specified key. Reads of different keys, and
+// concurrent reads of the same key, do not contend on a lock.
+func (a *AdaptiveTTL[K]) Hit(key K) {
+	if st, ok := a.state.Get(key); ok {
+		atomic.AddUint64(&st.hits, 1)
+	}
+}
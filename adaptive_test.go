@@ -0,0 +1,74 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdaptiveTTL(t *testing.T) {
+	c := New[string, int]()
+	c.Adaptive = NewAdaptiveTTL[string](time.Minute, 4*time.Minute)
+
+	ttlOf := func(key string) time.Duration {
+		for _, e := range c.ExpiringSoon(10) {
+			if e.Key == key {
+				return time.Until(e.Expiry).Round(time.Minute)
+			}
+		}
+		return 0
+	}
+
+	expected := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 4 * time.Minute}
+	for _, ttl := range expected {
+		c.Set("hot", 1, DefaultTTL)
+		c.Set("cold", 1, DefaultTTL)
+		if got := ttlOf("hot"); got != ttl {
+			t.Fatalf("expected hot key to have a TTL of %v, got %v", ttl, got)
+		}
+		if got := ttlOf("cold"); got != time.Minute {
+			t.Fatalf("expected cold key to have a TTL of 1m, got %v", got)
+		}
+		c.Get("hot")
+	}
+
+	// Without reads, TTLs shrink back.
+	c.Set("hot", 1, DefaultTTL)
+	c.Set("hot", 1, DefaultTTL)
+	if got := ttlOf("hot"); got != 2*time.Minute {
+		t.Fatalf("expected hot key to have a TTL of 2m, got %v", got)
+	}
+
+	// Explicit TTLs are left alone.
+	c.Set("hot", 1, time.Hour)
+	if got := ttlOf("hot"); got != time.Hour {
+		t.Fatalf("expected hot key to have a TTL of 1h, got %v", got)
+	}
+}
+
+func TestAdaptiveTTLConcurrentHits(t *testing.T) {
+	a := NewAdaptiveTTL[string](time.Minute, 4*time.Minute)
+	a.Threshold = 400
+	a.TTL("hot")
+
+	done := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		go func() {
+			for j := 0; j < 100; j++ {
+				a.Hit("hot")
+			}
+			done <- struct{}{}
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		<-done
+	}
+	if ttl := a.TTL("hot"); ttl != 2*time.Minute {
+		t.Fatalf("expected all 400 hits to count towards the threshold, got a TTL of %v", ttl)
+	}
+}
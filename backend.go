@@ -0,0 +1,104 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"sync"
+)
+
+// Backend stores the values of a cache. The cache keeps track of keys and
+// their expiration times itself, and only delegates storing values to the
+// backend, which lets alternative storages (on disk, in shared memory,
+// remote) reuse the expiry machinery.
+//
+// The cache calls backends with its lock held, sometimes only for reading,
+// so implementations must be safe for concurrent use. Backends that can fail
+// should report values they cannot load as missing.
+//
+// Values returned by Load may alias storage of the backend, but must then
+// stay valid after their key is deleted: the values of removed keys are
+// still passed to OnExpire and returned by Expire. Backends reusing the
+// storage of deleted values must have Load return copies instead.
+type Backend[K comparable, V any] interface {
+	// Load returns the value stored for key, and whether it was found.
+	Load(key K) (value V, found bool)
+
+	// Store stores value for key, replacing any previous value.
+	Store(key K, value V)
+
+	// Delete removes the value stored for key, if any.
+	Delete(key K)
+}
+
+// backendTaker is implemented by backends whose values alias storage that
+// gets reused once deleted. take deletes the value of key, and returns a
+// copy of it that stays valid.
+type backendTaker[K, V any] interface {
+	take(key K) (value V, found bool)
+}
+
+// NewWithBackend returns a cache storing its values in the specified
+// backend. Values already in the backend are ignored until they are set
+// through the cache, since their expiration time is unknown.
+func NewWithBackend[K comparable, V any](backend Backend[K, V]) *Cache[K, V] {
+	cache := New[K, V]()
+	cache.backend = backend
+	return cache
+}
+
+// load returns the value of bucket. Without a custom backend, values are
+// kept in the buckets themselves.
+func (cache *Cache[K, V]) load(bucket *cacheBucket[K, V]) (V, bool) {
+	if cache.backend == nil {
+		return bucket.val, true
+	}
+	return cache.backend.Load(bucket.key)
+}
+
+func (cache *Cache[K, V]) store(bucket *cacheBucket[K, V], value V) {
+	if cache.backend == nil {
+		bucket.val = value
+		return
+	}
+	cache.backend.Store(bucket.key, value)
+}
+
+// MapBackend is a Backend storing values in a map in memory. Caches created
+// with New store values in memory without needing a backend; MapBackend is
+// meant as a reference implementation, and as a building block for other
+// backends.
+type MapBackend[K comparable, V any] struct {
+	values map[K]V
+	mux    sync.RWMutex
+}
+
+// NewMapBackend returns an empty MapBackend.
+func NewMapBackend[K comparable, V any]() *MapBackend[K, V] {
+	return &MapBackend[K, V]{values: make(map[K]V)}
+}
+
+func (b *MapBackend[K, V]) Load(key K) (value V, found bool) {
+	b.mux.RLock()
+	defer b.mux.RUnlock()
+
+	value, found = b.values[key]
+	return value, found
+}
+
+func (b *MapBackend[K, V]) Store(key K, value V) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	b.values[key] = value
+}
+
+func (b *MapBackend[K, V]) Delete(key K) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	delete(b.values, key)
+}
@@ -0,0 +1,51 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackend(t *testing.T) {
+	backend := NewMapBackend[string, int]()
+	c := NewWithBackend[string, int](backend)
+
+	var expired []int
+	c.OnExpire = func(key string, value int) {
+		expired = append(expired, value)
+	}
+
+	c.Set("foo", 1, time.Hour)
+	c.Set("bar", 2, time.Nanosecond)
+	c.Set("baz", 3, time.Hour) // flushes bar
+
+	if v, ok := backend.Load("foo"); !ok || v != 1 {
+		t.Fatalf("expected foo to be stored in the backend, got %v", v)
+	}
+	if v, ok := c.Get("foo"); !ok || v != 1 {
+		t.Fatalf("expected foo to be 1, got %v", v)
+	}
+	if _, ok := backend.Load("bar"); ok {
+		t.Fatal("expected bar to be deleted from the backend")
+	}
+	if len(expired) != 1 || expired[0] != 2 {
+		t.Fatalf("expected OnExpire to get the value of bar, got %v", expired)
+	}
+
+	// Values lost by the backend are misses.
+	backend.Delete("baz")
+	if _, ok := c.Get("baz"); ok {
+		t.Fatal("expected baz to be missing")
+	}
+
+	clone := c.Clone()
+	c.Expire("foo")
+	if v, ok := clone.Get("foo"); !ok || v != 1 {
+		t.Fatalf("expected clone to hold its own copy of foo, got %v", v)
+	}
+}
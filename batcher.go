@@ -0,0 +1,160 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// MissBatcher collapses the misses of individual reads into batched loads:
+// the first miss opens a window, and all the keys missed within that window
+// are loaded by a single call to the BulkLoader of the cache. This turns
+// bursts of concurrent reads fanning out to many keys into a single query of
+// the source of truth.
+type MissBatcher[K comparable, V any] struct {
+	// Window is how long misses are held to be batched together.
+	Window time.Duration
+
+	// MaxKeys, if positive, is the most keys loaded at once: a batch is
+	// loaded as soon as that many keys missed, without waiting for the end
+	// of the window.
+	MaxKeys int
+
+	cache *Cache[K, V]
+	batch *missBatch[K, V]
+	mux   sync.Mutex
+}
+
+type missBatch[K comparable, V any] struct {
+	keys   []K
+	seen   map[K]struct{}
+	full   chan struct{} // closed once MaxKeys keys missed
+	done   chan struct{} // closed once loaded
+	values map[K]V
+	err    error
+}
+
+// NewMissBatcher returns a batcher for the cache, holding misses for window.
+func (cache *Cache[K, V]) NewMissBatcher(window time.Duration) *MissBatcher[K, V] {
+	return &MissBatcher[K, V]{Window: window, cache: cache}
+}
+
+// Get retrieves the value for the specified key from the cache like Get,
+// loading it along with the other keys missed in the same window if it is
+// missing. Loaded values are set in the cache.
+//
+// found is false if the key could not be found, even after loading. The
+// batch is loaded in the background, with the values of the context of the
+// call that opened the window but not its cancellation, so that it does not
+// fail every other call in the batch; calls only stop waiting for it when
+// their own context is done.
+func (b *MissBatcher[K, V]) Get(ctx context.Context, key K) (value V, found bool, err error) {
+	if value, found := b.cache.Get(key); found {
+		return value, true, nil
+	}
+	if b.cache.BulkLoader == nil {
+		return value, false, ErrNoLoader
+	}
+
+	batch, leader := b.join(key)
+	if leader {
+		go b.load(detachedContext{ctx}, batch)
+	}
+	select {
+	case <-batch.done:
+	case <-ctx.Done():
+		return value, false, ctx.Err()
+	}
+	if batch.err != nil {
+		return value, false, batch.err
+	}
+	value, found = batch.values[key]
+	return value, found, nil
+}
+
+// join adds key to the current batch, opening a new one if needed, in which
+// case leader is true and the caller must load it.
+func (b *MissBatcher[K, V]) join(key K) (batch *missBatch[K, V], leader bool) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	if b.batch == nil {
+		b.batch = &missBatch[K, V]{
+			seen: make(map[K]struct{}),
+			full: make(chan struct{}),
+			done: make(chan struct{}),
+		}
+		leader = true
+	}
+	batch = b.batch
+	if _, dup := batch.seen[key]; !dup {
+		batch.seen[key] = struct{}{}
+		batch.keys = append(batch.keys, key)
+		if len(batch.keys) == b.MaxKeys {
+			close(batch.full)
+			b.batch = nil
+		}
+	}
+	return batch, leader
+}
+
+// load waits for the window of batch to be over, then loads its keys.
+func (b *MissBatcher[K, V]) load(ctx context.Context, batch *missBatch[K, V]) {
+	defer close(batch.done)
+
+	timer := time.NewTimer(b.Window)
+	select {
+	case <-timer.C:
+	case <-batch.full:
+		timer.Stop()
+	}
+
+	b.mux.Lock()
+	if b.batch == batch {
+		b.batch = nil
+	}
+	keys := batch.keys
+	b.mux.Unlock()
+
+	cache := b.cache
+	var values map[K]V
+	var ttl time.Duration
+	// Left as is if the BulkLoader panics, for the other calls to fail.
+	batch.err = errors.New("ttlcache: BulkLoader panicked")
+	start := cache.now()
+	cache.guard("BulkLoader", func() {
+		values, ttl, batch.err = cache.BulkLoader(ctx, keys)
+	})
+	if batch.err != nil {
+		return
+	}
+	elapsed := cache.now().Sub(start)
+
+	cache.mux.Lock()
+	for key, value := range values {
+		cache.setComputed(key, value, ttl, elapsed)
+	}
+	cache.mux.Unlock()
+	batch.values = values
+}
+
+// detachedContext carries the values of a context, but neither its deadline
+// nor its cancellation.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+func (ctx detachedContext) Value(key any) any {
+	return ctx.parent.Value(key)
+}
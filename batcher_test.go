@@ -0,0 +1,145 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMissBatcher(t *testing.T) {
+	c := New[int, int]()
+	var loads [][]int
+	var mux sync.Mutex
+	c.BulkLoader = func(ctx context.Context, keys []int) (map[int]int, time.Duration, error) {
+		mux.Lock()
+		loads = append(loads, keys)
+		mux.Unlock()
+		values := make(map[int]int)
+		for _, key := range keys {
+			if key%2 == 0 {
+				values[key] = key * 10
+			}
+		}
+		return values, time.Hour, nil
+	}
+	b := c.NewMissBatcher(50 * time.Millisecond)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(key int) {
+			defer wg.Done()
+			value, found, err := b.Get(context.Background(), key%5)
+			if err != nil {
+				t.Error(err)
+			}
+			if want := key%5%2 == 0; found != want || found && value != key%5*10 {
+				t.Errorf("expected %d to be found: %v, with %d, got %v, %v", key%5, want, key%5*10, value, found)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if len(loads) != 1 || len(loads[0]) != 5 {
+		t.Fatalf("expected a single load of the 5 distinct keys, got %v", loads)
+	}
+	if v, ok := c.Get(4); !ok || v != 40 {
+		t.Fatalf("expected loaded values to be cached, got %v, %v", v, ok)
+	}
+	if _, found, _ := b.Get(context.Background(), 2); !found || len(loads) != 1 {
+		t.Fatal("expected cached keys not to be loaded again")
+	}
+}
+
+func TestMissBatcherMaxKeys(t *testing.T) {
+	c := New[int, int]()
+	c.BulkLoader = func(ctx context.Context, keys []int) (map[int]int, time.Duration, error) {
+		return nil, 0, errors.New("unavailable")
+	}
+	b := c.NewMissBatcher(time.Hour)
+	b.MaxKeys = 1
+
+	// With a single key per batch, loads do not wait for the window.
+	if _, _, err := b.Get(context.Background(), 1); err == nil || err.Error() != "unavailable" {
+		t.Fatalf("expected the load error, got %v", err)
+	}
+
+	c.BulkLoader = nil
+	if _, _, err := b.Get(context.Background(), 1); err != ErrNoLoader {
+		t.Fatalf("expected ErrNoLoader, got %v", err)
+	}
+}
+
+func TestMissBatcherCancel(t *testing.T) {
+	type ctxKey struct{}
+	c := New[int, int]()
+	c.BulkLoader = func(ctx context.Context, keys []int) (map[int]int, time.Duration, error) {
+		if ctx.Value(ctxKey{}) != "leader" {
+			t.Error("expected the load to carry the values of the context of the leader")
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, 0, err
+		}
+		values := make(map[int]int)
+		for _, key := range keys {
+			values[key] = key
+		}
+		return values, time.Hour, nil
+	}
+	b := c.NewMissBatcher(50 * time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "leader"))
+	leader := make(chan error)
+	go func() {
+		_, _, err := b.Get(ctx, 1)
+		leader <- err
+	}()
+	for {
+		b.mux.Lock()
+		opened := b.batch != nil
+		b.mux.Unlock()
+		if opened {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	select {
+	case err := <-leader:
+		if err != context.Canceled {
+			t.Fatalf("expected the leader to stop waiting when canceled, got %v", err)
+		}
+	case <-time.After(25 * time.Millisecond):
+		t.Fatal("expected the leader not to wait for the window once canceled")
+	}
+
+	if v, found, err := b.Get(context.Background(), 2); err != nil || !found || v != 2 {
+		t.Fatalf("expected followers to be loaded despite the leader being canceled, got %v, %v, %v", v, found, err)
+	}
+}
+
+func TestMissBatcherPanic(t *testing.T) {
+	c := New[int, int]()
+	var reported error
+	c.OnError = func(err error) { reported = err }
+	c.BulkLoader = func(ctx context.Context, keys []int) (map[int]int, time.Duration, error) {
+		panic("boom")
+	}
+	b := c.NewMissBatcher(0)
+
+	if _, found, err := b.Get(context.Background(), 1); err == nil || found {
+		t.Fatalf("expected a panicking BulkLoader to fail the batch, got %v, %v", found, err)
+	}
+	var perr *PanicError
+	if !errors.As(reported, &perr) || perr.Callback != "BulkLoader" {
+		t.Fatalf("expected the panic to be reported, got %v", reported)
+	}
+}
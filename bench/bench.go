@@ -0,0 +1,146 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package bench compares cache configurations against synthetic workloads.
+//
+// A workload is a stream of keys to read. For every key, the driver reads
+// the cache, and sets the key on a miss, like a read-through cache would;
+// it then reports the hit rate and the latency of both operations for every
+// configuration, in a form that can be encoded to JSON.
+package bench
+
+import (
+	"encoding/json"
+	"io"
+	"math/rand"
+	"time"
+
+	"snai.pe/go-ttlcache"
+)
+
+// Workload generates the keys read by a benchmark.
+type Workload interface {
+	Next() uint64
+}
+
+type zipf struct {
+	z *rand.Zipf
+}
+
+// Zipf returns a workload reading keys in [0, keys) following a Zipfian
+// distribution: the lower the key, the more often it is read. s, which must
+// be greater than 1, controls how skewed the distribution is.
+func Zipf(seed int64, s float64, keys uint64) Workload {
+	return &zipf{z: rand.NewZipf(rand.New(rand.NewSource(seed)), s, 1, keys-1)}
+}
+
+func (w *zipf) Next() uint64 {
+	return w.z.Uint64()
+}
+
+type scan struct {
+	next uint64
+}
+
+// Scan returns a workload reading every key once, in ascending order,
+// which no cache can serve.
+func Scan() Workload {
+	return &scan{}
+}
+
+func (w *scan) Next() uint64 {
+	w.next++
+	return w.next - 1
+}
+
+type loop struct {
+	next, keys uint64
+}
+
+// Loop returns a workload reading keys in [0, keys) in ascending order over
+// and over, which defeats recency-based policies when the cache holds fewer
+// keys.
+func Loop(keys uint64) Workload {
+	return &loop{keys: keys}
+}
+
+func (w *loop) Next() uint64 {
+	key := w.next
+	w.next = (w.next + 1) % w.keys
+	return key
+}
+
+// Config is a cache configuration to benchmark.
+type Config struct {
+	// Name identifies the configuration in results.
+	Name string
+
+	// New returns a fresh cache configured for the benchmark.
+	New func() *ttlcache.Cache[uint64, uint64]
+
+	// TTL is the TTL keys are set with on a miss.
+	TTL time.Duration
+}
+
+// Result holds the outcome of benchmarking a configuration.
+type Result struct {
+	Name    string        `json:"name"`
+	Ops     int           `json:"ops"`
+	Hits    int           `json:"hits"`
+	Misses  int           `json:"misses"`
+	HitRate float64       `json:"hit_rate"`
+	Get     time.Duration `json:"get_ns"`
+	Set     time.Duration `json:"set_ns"`
+}
+
+// Run reads ops keys from a fresh workload returned by workload for each of
+// the configurations, and returns their results in the same order.
+func Run(workload func() Workload, ops int, configs ...Config) []Result {
+	results := make([]Result, 0, len(configs))
+	for _, cfg := range configs {
+		results = append(results, run(workload(), ops, cfg))
+	}
+	return results
+}
+
+func run(w Workload, ops int, cfg Config) Result {
+	cache := cfg.New()
+	res := Result{Name: cfg.Name, Ops: ops}
+
+	var get, set time.Duration
+	for i := 0; i < ops; i++ {
+		key := w.Next()
+
+		start := time.Now()
+		_, ok := cache.Get(key)
+		get += time.Since(start)
+		if ok {
+			res.Hits++
+			continue
+		}
+		res.Misses++
+
+		start = time.Now()
+		cache.Set(key, key, cfg.TTL)
+		set += time.Since(start)
+	}
+
+	if ops > 0 {
+		res.HitRate = float64(res.Hits) / float64(ops)
+		res.Get = get / time.Duration(ops)
+	}
+	if res.Misses > 0 {
+		res.Set = set / time.Duration(res.Misses)
+	}
+	return res
+}
+
+// WriteJSON writes results to w as a JSON array.
+func WriteJSON(w io.Writer, results []Result) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(results)
+}
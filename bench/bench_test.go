@@ -0,0 +1,63 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package bench
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"snai.pe/go-ttlcache"
+)
+
+func capped(n int) func() *ttlcache.Cache[uint64, uint64] {
+	return func() *ttlcache.Cache[uint64, uint64] {
+		c := ttlcache.New[uint64, uint64]()
+		c.Capacity = n
+		return c
+	}
+}
+
+func TestRun(t *testing.T) {
+	configs := []Config{
+		{Name: "small", New: capped(10), TTL: time.Hour},
+		{Name: "large", New: capped(1000), TTL: time.Hour},
+	}
+
+	scan := Run(Scan, 1000, configs...)
+	for _, res := range scan {
+		if res.Hits != 0 || res.Misses != 1000 {
+			t.Fatalf("expected a scan to always miss, got %+v", res)
+		}
+	}
+
+	loop := Run(func() Workload { return Loop(100) }, 1000, configs...)
+	if loop[0].Name != "small" || loop[0].Hits != 0 {
+		t.Fatalf("expected a loop larger than the cache to always miss, got %+v", loop[0])
+	}
+	if loop[1].Hits != 900 {
+		t.Fatalf("expected a loop fitting in the cache to miss once per key, got %+v", loop[1])
+	}
+
+	zipf := Run(func() Workload { return Zipf(1, 1.2, 1000) }, 1000, configs...)
+	if zipf[0].HitRate <= 0 || zipf[0].HitRate >= zipf[1].HitRate {
+		t.Fatalf("expected a larger cache to do better on a skewed workload, got %+v", zipf)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, zipf); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 2 || decoded[1]["name"] != "large" {
+		t.Fatalf("unexpected JSON results: %s", buf.String())
+	}
+}
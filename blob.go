@@ -0,0 +1,53 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrBlobNotFound is returned by BlobStores for blobs that do not exist.
+var ErrBlobNotFound = errors.New("ttlcache: blob not found")
+
+// BlobStore stores named blobs, like an object storage bucket. It lets
+// persisted snapshots live outside of the machine running the cache, so
+// that ephemeral instances can warm up from the last snapshot when they
+// start. See the s3blob package for an implementation on top of S3 and
+// compatible services.
+type BlobStore interface {
+	// Put stores the contents of r as the blob called name, replacing it if
+	// it exists.
+	Put(ctx context.Context, name string, r io.Reader) error
+
+	// Get returns the contents of the blob called name, or ErrBlobNotFound
+	// if there is none.
+	Get(ctx context.Context, name string) (io.ReadCloser, error)
+}
+
+// Upload persists the snapshot as the blob called name in store. See
+// WriteTo.
+func (s *Snapshot[K, V]) Upload(ctx context.Context, store BlobStore, name string) error {
+	var buf bytes.Buffer
+	if _, err := s.WriteTo(&buf); err != nil {
+		return err
+	}
+	return store.Put(ctx, name, &buf)
+}
+
+// RestoreBlob restores the cache from the snapshot stored as the blob called
+// name in store, or returns ErrBlobNotFound if there is none. See Restore.
+func (cache *Cache[K, V]) RestoreBlob(ctx context.Context, store BlobStore, name string) error {
+	r, err := store.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return cache.Restore(r)
+}
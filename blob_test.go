@@ -0,0 +1,53 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+type memBlobStore map[string][]byte
+
+func (s memBlobStore) Put(ctx context.Context, name string, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	s[name] = data
+	return err
+}
+
+func (s memBlobStore) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	data, ok := s[name]
+	if !ok {
+		return nil, ErrBlobNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func TestBlobSnapshot(t *testing.T) {
+	ctx := context.Background()
+	store := memBlobStore{}
+
+	c := New[string, int]()
+	c.Set("foo", 1, time.Hour)
+	if err := c.Snapshot().Upload(ctx, store, "cache"); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := New[string, int]()
+	if err := restored.RestoreBlob(ctx, store, "missing"); err != ErrBlobNotFound {
+		t.Fatalf("expected ErrBlobNotFound, got %v", err)
+	}
+	if err := restored.RestoreBlob(ctx, store, "cache"); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := restored.Get("foo"); !ok || v != 1 {
+		t.Fatalf("expected foo to be restored, got %v, %v", v, ok)
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// BlueGreen serves a live cache while a standby cache gets built from
+// scratch, then swaps them, so that whole datasets can be reloaded without
+// ever serving from a cold cache.
+//
+// The live cache must be fetched with Cache every time it is used, rather
+// than kept around: the previous cache gets closed when swapped out.
+type BlueGreen[K comparable, V any] struct {
+	// New returns the empty caches that Reload warms up. It should set them
+	// up like the live cache, with the same callbacks and loaders. If nil,
+	// standby caches are created with New.
+	New func() *Cache[K, V]
+
+	live atomic.Value // *Cache[K, V]
+	mux  sync.Mutex   // serializes swaps
+}
+
+// NewBlueGreen returns a blue/green pair serving live until the first swap.
+func NewBlueGreen[K comparable, V any](live *Cache[K, V]) *BlueGreen[K, V] {
+	bg := &BlueGreen[K, V]{}
+	bg.live.Store(live)
+	return bg
+}
+
+// Cache returns the live cache.
+func (bg *BlueGreen[K, V]) Cache() *Cache[K, V] {
+	return bg.live.Load().(*Cache[K, V])
+}
+
+// Reload builds a standby cache populated by the specified warmers, run one
+// after the other, then swaps it in for the live cache. If a warmer fails,
+// the standby cache is dropped and the live cache is kept.
+func (bg *BlueGreen[K, V]) Reload(ctx context.Context, warmers ...Warmer[K, V]) error {
+	var standby *Cache[K, V]
+	if newCache := bg.New; newCache != nil {
+		standby = newCache()
+	} else {
+		standby = New[K, V]()
+	}
+	if err := standby.WarmFrom(ctx, warmers...); err != nil {
+		standby.Close(ctx)
+		return err
+	}
+	return bg.Swap(ctx, standby)
+}
+
+// Swap makes standby the live cache, then closes the previous one, waiting
+// for its background work to stop until ctx is done, in which case the
+// context error is returned. Expiry callbacks of the previous cache fire
+// for its remaining keys if it has ExpireOnClose set.
+func (bg *BlueGreen[K, V]) Swap(ctx context.Context, standby *Cache[K, V]) error {
+	bg.mux.Lock()
+	defer bg.mux.Unlock()
+
+	old := bg.Cache()
+	if old == standby {
+		return nil
+	}
+	bg.live.Store(standby)
+	if err := old.Close(ctx); err != nil && err != ErrClosed {
+		return err
+	}
+	return nil
+}
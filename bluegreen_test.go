@@ -0,0 +1,74 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBlueGreen(t *testing.T) {
+	live := New[string, int]()
+	live.Set("foo", 1, time.Hour)
+	live.Set("stale", 1, time.Hour)
+
+	bg := NewBlueGreen(live)
+	bg.New = func() *Cache[string, int] {
+		c := New[string, int]()
+		c.Capacity = 10
+		return c
+	}
+
+	err := bg.Reload(context.Background(), WarmerFunc[string, int](func(ctx context.Context, set func(string, int, time.Duration)) error {
+		// The live cache keeps serving while the standby one is warmed.
+		if v, ok := bg.Cache().Get("foo"); !ok || v != 1 {
+			t.Errorf("expected the live cache to serve foo during the reload, got %v, %v", v, ok)
+		}
+		set("foo", 2, time.Hour)
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := bg.Cache()
+	if c == live || c.Capacity != 10 {
+		t.Fatal("expected the standby cache built by New to be live")
+	}
+	if v, ok := c.Get("foo"); !ok || v != 2 {
+		t.Fatalf("expected foo to be reloaded, got %v, %v", v, ok)
+	}
+	if _, ok := c.Get("stale"); ok {
+		t.Fatal("expected keys missing from the new dataset to be gone")
+	}
+	if err := live.Close(context.Background()); err != ErrClosed {
+		t.Fatalf("expected the previous cache to be closed, got %v", err)
+	}
+}
+
+func TestBlueGreenReloadError(t *testing.T) {
+	live := New[string, int]()
+	live.Set("foo", 1, time.Hour)
+	bg := NewBlueGreen(live)
+
+	fail := errors.New("unavailable")
+	err := bg.Reload(context.Background(), WarmerFunc[string, int](func(ctx context.Context, set func(string, int, time.Duration)) error {
+		set("foo", 2, time.Hour)
+		return fail
+	}))
+	if err != fail {
+		t.Fatalf("expected the warmer error, got %v", err)
+	}
+	if bg.Cache() != live {
+		t.Fatal("expected the live cache to be kept")
+	}
+	if v, _ := live.Get("foo"); v != 1 {
+		t.Fatalf("expected the live cache to be untouched, got %v", v)
+	}
+}
@@ -7,21 +7,185 @@
 package ttlcache
 
 import (
-	"container/heap"
+	"context"
+	"math"
+	"math/bits"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// DefaultTTL can be passed instead of a TTL when setting a key, to have the
+// TTL determined by the value itself (see ValueTTL), or else by the TTLFunc
+// of the cache.
+const DefaultTTL time.Duration = math.MinInt64
+
 // Cache is an implementation of an in-memory cache using TTLs. It only expires
 // items on write, which means that it is possible for a value to survive
 // past the expiration time that it was inserted with.
+//
+// Each key is held both by the map of the cache and by its entry in the
+// expiry heap. For strings, and other keys referencing their contents, only
+// the fixed-size part of the key is copied: the contents themselves are
+// shared, so long keys do not take twice their length in memory.
 type Cache[K comparable, V any] struct {
+	// accessed atomically; kept first to be 64-bit aligned on 32-bit platforms
+	droppedEvents uint64
+
 	// OnExpire gets called whenever a key expires from the cache.
 	OnExpire func(key K, value V)
 
+	// OnError, if set, gets called with errors happening in the cache that
+	// cannot be returned to callers. In particular, panics in the callbacks
+	// of the cache get recovered and reported to OnError as *PanicError,
+	// leaving the cache in a consistent state, rather than unwinding through
+	// the cache operation that triggered them.
+	//
+	// OnError is called with the cache locked from cache operations, but
+	// without the lock, and possibly concurrently, from background work like
+	// Refresher, MissBatcher or Journal.Run. It must not call methods of the
+	// cache either way.
+	OnError func(err error)
+
+	// ExpireOnClose makes Close expire all the keys remaining in the cache,
+	// calling OnExpire for each of them, rather than dropping them silently.
+	ExpireOnClose bool
+
+	// Renew, if set, gets called whenever a key reaches its expiration time.
+	// If it returns true, the key is kept with the returned value and TTL
+	// instead of expiring, turning the cache into a self-refreshing store.
+	// A non-positive TTL expires the key regardless.
+	//
+	// Like OnExpire, Renew is called with the cache locked, and should
+	// return quickly.
+	Renew func(ctx context.Context, key K, value V) (V, time.Duration, bool)
+
+	// TTLFunc, if set, gets called to determine the TTL of keys set with
+	// DefaultTTL, letting one central policy give different lifetimes to
+	// different families of keys. Keys set with DefaultTTL expire
+	// immediately if TTLFunc is nil.
+	TTLFunc func(key K, value V) time.Duration
+
+	// Adaptive, if set, determines the TTL of keys set with DefaultTTL
+	// depending on how often they are read, taking precedence over TTLFunc.
+	// It is told about every hit.
+	Adaptive *AdaptiveTTL[K]
+
+	// Trace, if set, gets called for every Get, Set and Expire. It may be
+	// called concurrently, with the cache locked. See Recorder.
+	Trace func(rec TraceRecord[K])
+
+	// Clock, if set, tells the time to the cache instead of the system clock.
+	Clock Clock
+
+	// Loader loads values from their source of truth. It is only used by
+	// operations that explicitly need to load values, like background
+	// refreshes.
+	Loader LoadFunc[K, V]
+
+	// BulkLoader loads many values at once from their source of truth. If
+	// set, it is preferred over Loader by operations loading several
+	// values, like GetMulti.
+	BulkLoader BulkLoadFunc[K, V]
+
+	// Capacity, if positive, is the maximum number of keys in the cache.
+	// Setting a new key in a full cache first flushes expired keys, then
+	// evicts keys by ascending priority and expiration time if needed. See
+	// SetWithPriority.
+	Capacity int
+
+	// EvictionSamples, if positive, makes evictions approximate: rather than
+	// following the expiry heap, the cache picks that many keys at random
+	// and evicts the best candidate among them, by ascending priority, then
+	// least recently read while TrackAccess is set, or least recently
+	// inserted otherwise: setting a key already in the cache does not count.
+	// Like in Redis, a handful of samples is enough to evict keys close to
+	// the least recently used ones.
+	EvictionSamples int
+
+	// EarlyExpiration, if positive, makes Get report keys as missing ahead
+	// of their expiration time, at random, with a probability growing as
+	// they near expiry and with the time their value took to compute. Keys
+	// read by many callers then get reloaded by one of them ahead of time
+	// rather than by all of them at once when they expire, which is the
+	// XFetch algorithm; EarlyExpiration is its beta parameter, for which 1
+	// is a good default, and higher values favor earlier reloads.
+	//
+	// Only the values loaded by the Loader or the BulkLoader, or set with
+	// SetWithRecomputeTime, have a known computation time.
+	EarlyExpiration float64
+
+	// ExpiryTolerance lets setting an existing key keep its current
+	// expiration time if the new one is within ExpiryTolerance of it,
+	// which saves reordering the expiry heap when keys keep being refreshed
+	// with the same TTL. Keys may then expire up to ExpiryTolerance early,
+	// or late.
+	ExpiryTolerance time.Duration
+
+	// TrackAccess makes the cache count the hits of every key, and remember
+	// when each key was last read, as well as count the misses of every key.
+	// See HotKeys and TopMisses.
+	TrackAccess bool
+
+	// SnapshotVersion is the version of the values of the cache, as written
+	// in persisted snapshots. It should be bumped whenever values change in
+	// ways that older snapshots cannot be decoded into, with a migration
+	// from the previous version added to SnapshotMigrations.
+	SnapshotVersion int
+
+	// SnapshotMigrations holds the migrations from every SnapshotVersion to
+	// the next, applied when restoring older snapshots. See Restore.
+	SnapshotMigrations map[int]SnapshotMigration
+
+	// SizeFunc, if set, returns the size in bytes of the memory referenced
+	// by a key and its value, like the contents of strings or slices, beyond
+	// the size of their types. See EstimatedBytes.
+	SizeFunc func(key K, value V) int
+
+	// MaxCost, if positive, is the maximum total cost of the entries of the
+	// cache, as returned by SizeFunc. Setting a key past it evicts keys like
+	// past Capacity, possibly including the key just set, which is always
+	// evicted if it costs more than MaxCost on its own. Costs are only
+	// computed while MaxCost is set: use SetMaxCost to change it once the
+	// cache holds keys.
+	MaxCost int
+
 	cache      map[K]*cacheBucket[K, V]
 	expireList expireList[K, V]
-	mux        sync.RWMutex
+	evictList  evictList[K, V]
+	slab       slab[K, V]
+	backend    Backend[K, V]
+	waiters    map[K][]chan V
+	watchers   map[K][]chan Event[K, V]
+	events     chan Event[K, V]
+	subs       []*subscription[K, V]
+	listeners  []*expireListener[K, V]
+	alarms     []chan struct{}
+	timers     []*ExpiryTimer[K, V]
+	keyIndex   *skiplist[K]
+	natural    bool // keyIndex orders keys with <, as set up by NewOrdered
+	expiries   *skiplist[*cacheBucket[K, V]]
+	indexes    []indexer[K, V]
+	dependents map[K]map[K]struct{}
+	priorities bool
+	stats      Stats
+	misses     missCounter[K]
+	journal    *Journal[K, V]
+	cost       int
+	flushed    instant      // when flush last ran, or zero if it never did
+	view       atomic.Value // *frozenView[K, V]
+	frozen     bool
+	ifaces     uint8 // see valuesMay
+	revision   uint64
+	seq        uint64
+	name       string // see Register
+	closing    bool
+	closed     bool
+	done       chan struct{}
+	shutdown   chan struct{}
+	drainers   []drainer
+	background sync.WaitGroup
+	mux        cacheMutex
 }
 
 func New[K comparable, V any]() *Cache[K, V] {
@@ -30,27 +194,253 @@ func New[K comparable, V any]() *Cache[K, V] {
 	}
 }
 
-// Set assigns the specified value to the specified key in the cache, with
+// NewWithSize returns a cache with room for size keys, which avoids the
+// latency spikes of growing its internal structures as it fills up to that
+// size.
+func NewWithSize[K comparable, V any](size int) *Cache[K, V] {
+	cache := &Cache[K, V]{
+		cache: make(map[K]*cacheBucket[K, V], size),
+	}
+	cache.expireList.elts = make([]*cacheBucket[K, V], 0, size)
+	cache.expireList.exp = make([]instant, 0, size)
+	cache.slab.reserve(size)
+	return cache
+}
+
+// Reserve grows the cache to have room for size keys, if it has less. It
+// takes linear time, but lets the price of growing be paid at a convenient
+// time, like during startup, rather than by whichever Set crosses a growth
+// threshold.
+func (cache *Cache[K, V]) Reserve(size int) {
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	if cache.closed || size <= cap(cache.expireList.elts) {
+		return
+	}
+	m := make(map[K]*cacheBucket[K, V], size)
+	for key, bucket := range cache.cache {
+		m[key] = bucket
+	}
+	cache.cache = m
+
+	elts := make([]*cacheBucket[K, V], len(cache.expireList.elts), size)
+	copy(elts, cache.expireList.elts)
+	cache.expireList.elts = elts
+	if !cache.expireList.coalesced() {
+		exp := make([]instant, len(cache.expireList.exp), size)
+		copy(exp, cache.expireList.exp)
+		cache.expireList.exp = exp
+	}
+}
+
+// NewFromMap returns a cache holding all the key-value pairs of m, each with
 // an expiration of ttl.
+func NewFromMap[K comparable, V any](m map[K]V, ttl time.Duration) *Cache[K, V] {
+	return NewFromMapFunc(m, func(K, V) time.Duration { return ttl })
+}
+
+// NewFromMapFunc returns a cache holding all the key-value pairs of m, each
+// with the expiration returned by ttl for that pair.
+//
+// The cache is built in linear time, which is much faster than setting each
+// pair individually for large maps.
+func NewFromMapFunc[K comparable, V any](m map[K]V, ttl func(key K, value V) time.Duration) *Cache[K, V] {
+	cache := &Cache[K, V]{
+		cache: make(map[K]*cacheBucket[K, V], len(m)),
+	}
+	cache.expireList.elts = make([]*cacheBucket[K, V], 0, len(m))
+	cache.slab.reserve(len(m))
+
+	now := toInstant(time.Now())
+	for key, value := range m {
+		d := ttl(key, value)
+		bucket := cache.slab.alloc()
+		bucket.expiry = now.Add(d)
+		bucket.created = now
+		bucket.idx = len(cache.expireList.elts)
+		bucket.key = key
+		bucket.val = value
+		bucket.softExpiry = bucket.expiry
+		cache.revision++
+		bucket.rev = cache.revision
+		cache.stats.TTLs.observe(d)
+		cache.expireList.elts = append(cache.expireList.elts, bucket)
+		cache.cache[key] = bucket
+	}
+	cache.expireList.Init()
+	return cache
+}
+
+// Set assigns the specified value to the specified key in the cache, with
+// an expiration of ttl. If ttl is DefaultTTL, the expiration is given by
+// TTLFunc.
 func (cache *Cache[K, V]) Set(key K, value V, ttl time.Duration) {
 	cache.mux.Lock()
 	defer cache.mux.Unlock()
 
+	cache.set(key, value, ttl, ttl)
+}
+
+// TrySet is like Set, but gives up instead of waiting if the cache is
+// locked, and reports whether the value was set.
+func (cache *Cache[K, V]) TrySet(key K, value V, ttl time.Duration) bool {
+	if !cache.mux.TryLock() {
+		return false
+	}
+	defer cache.mux.Unlock()
+
+	cache.set(key, value, ttl, ttl)
+	return true
+}
+
+// SetWithSoftTTL assigns the specified value to the specified key in the
+// cache, with two deadlines: past softTTL, the value is considered stale, as
+// reported by GetStale, and past hardTTL, it expires.
+//
+// This lets callers keep serving a value while refreshing it in the
+// background, up to a point. softTTL is capped to hardTTL, and defaults to
+// it if DefaultTTL is passed.
+func (cache *Cache[K, V]) SetWithSoftTTL(key K, value V, softTTL, hardTTL time.Duration) {
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	cache.set(key, value, softTTL, hardTTL)
+}
+
+func (cache *Cache[K, V]) set(key K, value V, softTTL, hardTTL time.Duration) *cacheBucket[K, V] {
+	if cache.closed {
+		return nil
+	}
+
+	hardTTL = cache.resolveTTL(key, value, hardTTL)
+	if softTTL == DefaultTTL || softTTL > hardTTL {
+		softTTL = hardTTL
+	}
+	cache.trace(OpSet, key, hardTTL)
+
+	now := cache.instant()
 	bucket, ok := cache.cache[key]
-	if !ok {
+	if ok {
+		cache.expireDependents(key)
+		// On a dependency cycle, the key itself gets expired along with its
+		// dependents.
+		bucket, ok = cache.cache[key]
+	}
+	if ok {
+		bucket = cache.replace(bucket, value)
+	} else {
 		cache.flush()
-
-		bucket = &cacheBucket[K, V]{
-			key:    key,
-			idx:    cache.expireList.Len(),
+		if cache.Capacity > 0 {
+			for len(cache.cache) >= cache.Capacity && cache.evict() {
+			}
 		}
+
+		bucket = cache.slab.alloc()
+		bucket.key = key
+		bucket.expiry = unset
+		bucket.created = now
 		cache.expireList.Push(bucket)
 		cache.cache[key] = bucket
+		if cache.keyIndex != nil {
+			cache.keyIndex.Insert(key)
+		}
+	}
+
+	cache.store(bucket, value)
+	cache.charge(bucket, value)
+	cache.thaw()
+	bucket.recompute = 0
+	for _, idx := range cache.indexes {
+		idx.update(key, value)
+	}
+	cache.revision++
+	bucket.rev = cache.revision
+	// Only keys that did not expire yet may keep their expiration time.
+	var tolerance time.Duration
+	if bucket.expiry.After(now) {
+		tolerance = cache.ExpiryTolerance
+	}
+	cache.setExpiry(bucket, now.Add(hardTTL), tolerance)
+	bucket.softExpiry = now.Add(softTTL)
+	if bucket.softExpiry.After(bucket.expiry) {
+		bucket.softExpiry = bucket.expiry
 	}
+	cache.stats.TTLs.observe(hardTTL)
+	if cache.journal != nil {
+		cache.journal.record(false, bucket)
+	}
+
+	cache.wake(key, value)
+	cache.notify(EventSet, key, value)
 
-	bucket.val = value
-	bucket.expiry = time.Now().Add(ttl)
-	heap.Fix(&cache.expireList, bucket.idx)
+	if cache.MaxCost > 0 {
+		// Values costing more than MaxCost on their own are not worth
+		// evicting everything else for.
+		if bucket.cost > cache.MaxCost {
+			cache.delete(bucket, EventEvict)
+		}
+		cache.shed()
+		if cache.cache[key] != bucket {
+			return nil
+		}
+	}
+	return bucket
+}
+
+// setExpiry changes the expiration time of bucket, unless it is within
+// tolerance of the current one, and restores the ordering of the expire
+// list.
+func (cache *Cache[K, V]) setExpiry(bucket *cacheBucket[K, V], expiry instant, tolerance time.Duration) {
+	expiry = cache.expireList.round(expiry)
+	fresh := bucket.expiry == unset
+	if !fresh && tolerance > 0 {
+		d := expiry.Sub(bucket.expiry)
+		if d < 0 {
+			d = -d
+		}
+		if d <= tolerance {
+			return
+		}
+	}
+	cache.thaw()
+	cache.reindexExpiry(bucket, false)
+	bucket.expiry = expiry
+	cache.reindexExpiry(bucket, true)
+	cache.expireList.Fix(bucket.idx)
+	if cache.priorities {
+		// New buckets join the evict list once they have an expiration time.
+		if fresh {
+			cache.evictList.push(bucket, bucket.evictKey(), &bucket.eidx)
+		} else {
+			cache.evictList.update(bucket.eidx, bucket.evictKey())
+		}
+	}
+	if cache.expireList.head(bucket) {
+		cache.rearm()
+	}
+}
+
+func (cache *Cache[K, V]) resolveTTL(key K, value V, ttl time.Duration) time.Duration {
+	own, ok := cache.valueTTL(value)
+	if ttl != DefaultTTL {
+		if ok && own < ttl {
+			return own
+		}
+		return ttl
+	}
+	if ok {
+		return own
+	}
+	if adaptive := cache.Adaptive; adaptive != nil {
+		return adaptive.TTL(key)
+	}
+	if ttlFunc := cache.TTLFunc; ttlFunc != nil {
+		var ttl time.Duration
+		cache.guard("TTLFunc", func() { ttl = ttlFunc(key, value) })
+		return ttl
+	}
+	return 0
 }
 
 // Get retrieves the value in the cache for the specified key if it exists,
@@ -59,92 +449,587 @@ func (cache *Cache[K, V]) Get(key K) (value V, found bool) {
 	cache.mux.RLock()
 	defer cache.mux.RUnlock()
 
+	return cache.get(key)
+}
+
+// TryGet is like Get, but gives up and reports a miss instead of waiting if
+// the cache is locked for writing.
+func (cache *Cache[K, V]) TryGet(key K) (value V, found bool) {
+	if !cache.mux.TryRLock() {
+		return value, false
+	}
+	defer cache.mux.RUnlock()
+
+	return cache.get(key)
+}
+
+func (cache *Cache[K, V]) get(key K) (value V, found bool) {
+	cache.trace(OpGet, key, 0)
+
+	bucket, value, found := cache.lookup(key)
+	if found {
+		cache.hit(bucket, value)
+	} else {
+		cache.miss(key)
+	}
+	return value, found
+}
+
+// lookup returns the bucket and value of key, if Get would find it, without
+// recording the access. cache.mux must be held.
+func (cache *Cache[K, V]) lookup(key K) (bucket *cacheBucket[K, V], value V, found bool) {
+	bucket, found = cache.cache[key]
+	if found && cache.EarlyExpiration > 0 {
+		found = !cache.expiresEarly(bucket)
+	}
+	if found {
+		value, found = cache.load(bucket)
+	}
+	return bucket, value, found
+}
+
+func (cache *Cache[K, V]) miss(key K) {
+	if cache.TrackAccess {
+		cache.misses.add(key)
+	}
+	cache.emit(Event[K, V]{Kind: EventMiss, Key: key})
+}
+
+func (cache *Cache[K, V]) hit(bucket *cacheBucket[K, V], value V) {
+	key := bucket.key
+	if cache.TrackAccess {
+		atomic.AddUint64(&bucket.hits, 1)
+		atomic.StoreInt64(&bucket.accessed, cache.now().UnixNano())
+	}
+	if adaptive := cache.Adaptive; adaptive != nil {
+		adaptive.Hit(key)
+	}
+	cache.emit(Event[K, V]{Kind: EventHit, Key: key, Value: value})
+}
+
+// GetStale retrieves the value in the cache for the specified key like Get,
+// but also reports whether the value is stale, that is, whether it is past
+// its soft TTL (see SetWithSoftTTL), or past its expiration time and only
+// still around because it was not flushed yet.
+//
+// This allows callers to knowingly fall back to outdated values, for
+// instance when the source of truth is unavailable.
+func (cache *Cache[K, V]) GetStale(key K) (value V, stale bool, found bool) {
+	cache.mux.RLock()
+	defer cache.mux.RUnlock()
+
+	cache.trace(OpGet, key, 0)
+
 	bucket, found := cache.cache[key]
 	if found {
-		value = bucket.val
+		value, found = cache.load(bucket)
+	}
+	if found {
+		stale = !bucket.softExpiry.After(cache.instant())
+		cache.hit(bucket, value)
+	} else {
+		cache.miss(key)
 	}
+	return value, stale, found
+}
+
+// Contains reports whether Get would find a value for the specified key,
+// without retrieving the value. Unlike Get, it does not count as an access:
+// no hit or miss gets recorded.
+func (cache *Cache[K, V]) Contains(key K) bool {
+	cache.mux.RLock()
+	defer cache.mux.RUnlock()
+
+	_, _, found := cache.lookup(key)
+	return found
+}
+
+// Peek retrieves the value in the cache for the specified key like Get, but
+// without counting as an access: unlike Get, it records no hit or miss,
+// emits no event, and leaves access statistics, adaptive TTLs and eviction
+// order untouched. This makes it suitable for inspecting the cache without
+// perturbing it.
+func (cache *Cache[K, V]) Peek(key K) (value V, found bool) {
+	cache.mux.RLock()
+	defer cache.mux.RUnlock()
+
+	_, value, found = cache.lookup(key)
 	return value, found
 }
 
-// Expire expires the value associated with the specified key, if any.
-func (cache *Cache[K, V]) Expire(key K) {
+// Expire expires the value associated with the specified key, if any, and
+// returns it, as well as whether there was one. This lets callers release
+// resources associated with the value without racing with other writers.
+func (cache *Cache[K, V]) Expire(key K) (value V, found bool) {
 	cache.mux.Lock()
-	defer cache.mux.Unlock()
+	cache.trace(OpExpire, key, 0)
 
 	bucket, found := cache.cache[key]
-	if found {
-		cache.delete(bucket)
+	if !found {
+		cache.mux.Unlock()
+		return value, false
 	}
+	value = cache.delete(bucket, EventDelete)
+	// Buckets keep their guard while WithEntry is modifying their value,
+	// which is only returned once it is done.
+	guard := bucket.guard
+	cache.mux.Unlock()
+	if guard != nil {
+		guard.mux.Lock()
+		value = bucket.val
+		guard.mux.Unlock()
+	}
+	return value, true
 }
 
-// Flush removes all expired keys from the cache.
-func (cache *Cache[K, V]) Flush() {
+// ExpireFunc expires all the values for which fn returns true, and returns
+// how many were expired. fn is called with the cache locked, and must not
+// call methods of the cache.
+func (cache *Cache[K, V]) ExpireFunc(fn func(key K, value V) bool) int {
 	cache.mux.Lock()
 	defer cache.mux.Unlock()
 
-	cache.flush()
+	var matched []*cacheBucket[K, V]
+	for _, bucket := range cache.expireList.elts {
+		if value, _ := cache.load(bucket); fn(bucket.key, value) {
+			matched = append(matched, bucket)
+		}
+	}
+	return cache.deleteAll(matched, EventDelete)
 }
 
-func (cache *Cache[K, V]) flush() {
-	now := time.Now()
+// ExpireMany expires the values associated with the specified keys, if any,
+// and returns how many were expired. It is much cheaper than calling Expire
+// for each key when invalidating many keys at once.
+func (cache *Cache[K, V]) ExpireMany(keys []K) int {
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	matched := make([]*cacheBucket[K, V], 0, len(keys))
+	for _, key := range keys {
+		cache.trace(OpExpire, key, 0)
+
+		if bucket, found := cache.cache[key]; found {
+			matched = append(matched, bucket)
+		}
+	}
+	return cache.deleteAll(matched, EventDelete)
+}
+
+// Retain expires all the values for which fn returns false, keeping only the
+// ones for which it returns true, and returns how many were expired. fn is
+// called with the cache locked, and must not call methods of the cache.
+func (cache *Cache[K, V]) Retain(fn func(key K, value V) bool) int {
+	return cache.ExpireFunc(func(key K, value V) bool {
+		return !fn(key, value)
+	})
+}
+
+// EvictN removes the n keys closest to expiry from the cache, regardless of
+// whether they expired, and returns how many were removed. This lets memory
+// pressure handlers shed entries on demand.
+func (cache *Cache[K, V]) EvictN(n int) int {
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	var evicted int
+	for ; evicted < n; evicted++ {
+		bucket, ok := cache.expireList.Peek()
+		if !ok {
+			break
+		}
+		cache.delete(bucket, EventEvict)
+	}
+	return evicted
+}
+
+// Flush removes all expired keys from the cache, and returns how many were
+// removed. Keys renewed by Renew are not counted. See NextExpiry to find out
+// when the next key is due.
+func (cache *Cache[K, V]) Flush() int {
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	return cache.flush()
+}
+
+// NextExpiry returns the soonest expiration time across all keys in the
+// cache, and false if the cache is empty. It may be in the past if expired
+// keys have not been flushed yet.
+func (cache *Cache[K, V]) NextExpiry() (time.Time, bool) {
+	cache.mux.RLock()
+	defer cache.mux.RUnlock()
+
+	bucket, ok := cache.expireList.Peek()
+	if !ok {
+		return time.Time{}, false
+	}
+	return bucket.expiry.Time(), true
+}
+
+func (cache *Cache[K, V]) flush() (n int) {
+	now := cache.instant()
+	cache.flushed = now
 	for {
 		bucket, ok := cache.expireList.Peek()
 		if !ok || bucket.expiry.After(now) {
 			break
 		}
-		cache.delete(bucket)
+		if cache.renew(bucket, now) {
+			continue
+		}
+		cache.delete(bucket, EventExpire)
+		n++
+	}
+	return n
+}
+
+func (cache *Cache[K, V]) renew(bucket *cacheBucket[K, V], now instant) bool {
+	renew := cache.Renew
+	if renew == nil {
+		return false
+	}
+	value, _ := cache.load(bucket)
+	var ttl time.Duration
+	var ok bool
+	guarded := cache.guard("Renew", func() {
+		value, ttl, ok = renew(context.Background(), bucket.key, value)
+	})
+	if !guarded || !ok || ttl <= 0 {
+		return false
+	}
+
+	bucket = cache.replace(bucket, value)
+	cache.store(bucket, value)
+	cache.charge(bucket, value)
+	cache.thaw()
+	for _, idx := range cache.indexes {
+		idx.update(bucket.key, value)
+	}
+	cache.revision++
+	bucket.rev = cache.revision
+	// Renewed keys are past their expiration time, which must always move.
+	cache.setExpiry(bucket, now.Add(ttl), 0)
+	bucket.softExpiry = bucket.expiry
+	cache.stats.TTLs.observe(ttl)
+	if cache.journal != nil {
+		cache.journal.record(false, bucket)
+	}
+
+	cache.notify(EventSet, bucket.key, value)
+	return true
+}
+
+// deleteAll deletes many buckets at once, and returns how many were
+// deleted; buckets may contain duplicates. Past a certain amount, rebuilding
+// the expire list is cheaper than removing the buckets one by one.
+func (cache *Cache[K, V]) deleteAll(buckets []*cacheBucket[K, V], kind EventKind) (n int) {
+	size := len(cache.expireList.elts)
+	if len(buckets)*bits.Len(uint(size)) < size {
+		for _, bucket := range buckets {
+			if cache.cache[bucket.key] == bucket {
+				cache.delete(bucket, kind)
+				n++
+			}
+		}
+		return n
+	}
+
+	for _, bucket := range buckets {
+		bucket.idx = -1
+	}
+	elts := cache.expireList.elts[:0]
+	for _, bucket := range cache.expireList.elts {
+		if bucket.idx != -1 {
+			bucket.idx = len(elts)
+			elts = append(elts, bucket)
+		}
+	}
+	for i := len(elts); i < size; i++ {
+		cache.expireList.elts[i] = nil // don't keep referencing the items
+	}
+	cache.expireList.elts = elts
+	cache.expireList.Init()
+
+	for _, bucket := range buckets {
+		if cache.cache[bucket.key] == bucket {
+			cache.unlink(bucket, kind)
+			n++
+		}
+	}
+	return n
+}
+
+func (cache *Cache[K, V]) delete(bucket *cacheBucket[K, V], kind EventKind) V {
+	// Buckets being deleted by deleteAll are already out of the expire list.
+	if bucket.idx >= 0 {
+		cache.expireList.Remove(bucket.idx)
 	}
+	return cache.unlink(bucket, kind)
 }
 
-func (cache *Cache[K, V]) delete(bucket *cacheBucket[K, V]) {
+// unlink removes a bucket that is no longer in the expire list from the rest
+// of the cache, and notifies about its removal.
+func (cache *Cache[K, V]) unlink(bucket *cacheBucket[K, V], kind EventKind) V {
+	busy := bucket.lockOut()
+	var value V
+	if !busy {
+		value, _ = cache.load(bucket)
+	}
 	delete(cache.cache, bucket.key)
-	heap.Remove(&cache.expireList, bucket.idx)
+	cache.cost -= bucket.cost
+	cache.thaw()
+	if cache.priorities {
+		cache.evictList.remove(bucket.eidx)
+	}
+	if cache.keyIndex != nil {
+		cache.keyIndex.Remove(bucket.key)
+	}
+	cache.reindexExpiry(bucket, false)
+	for _, idx := range cache.indexes {
+		idx.remove(bucket.key)
+	}
+	if t, ok := cache.backend.(backendTaker[K, V]); ok {
+		// The value loaded above may not outlive the deletion.
+		value, _ = t.take(bucket.key)
+	} else if cache.backend != nil {
+		cache.backend.Delete(bucket.key)
+	}
+	if cache.journal != nil {
+		cache.journal.record(true, bucket)
+	}
+	if bucket.deps != nil || cache.dependents != nil {
+		cache.unlinkDependencies(bucket)
+	}
+	cache.stats.Lifetimes.observe(cache.instant().Sub(bucket.created))
+	reason := cache.evictReason(kind)
+	if busy {
+		// WithEntry is still modifying the value, which gets notified about
+		// once it is done. The bucket is left to it rather than recycled.
+		bucket.postpone(func() { cache.removed(bucket, bucket.val, kind, reason) })
+		return value
+	}
+	cache.removed(bucket, value, kind, reason)
+	cache.slab.release(bucket, len(cache.cache))
+	return value
+}
+
+// removed notifies about the removal of bucket, holding value, from the
+// cache. cache.mux must be held for writing.
+func (cache *Cache[K, V]) removed(bucket *cacheBucket[K, V], value V, kind EventKind, reason EvictReason) {
+	cache.notify(kind, bucket.key, value)
+	if bucket.pinned() {
+		key := bucket.key
+		bucket.ref.cleanup = func() { cache.expired(key, value, reason) }
+	} else {
+		cache.expired(bucket.key, value, reason)
+	}
+}
+
+// expired calls the expiry callbacks for a key that left the cache.
+// cache.mux must be held for writing.
+func (cache *Cache[K, V]) expired(key K, value V, reason EvictReason) {
 	if onExpire := cache.OnExpire; onExpire != nil {
-		onExpire(bucket.key, bucket.val)
+		cache.guard("OnExpire", func() { onExpire(key, value) })
+	}
+	for _, l := range cache.listeners {
+		cache.guard("OnExpireFunc", func() {
+			if l.match(key) {
+				l.fn(key, value)
+			}
+		})
 	}
+	cache.evicted(value, reason)
 }
 
 type cacheBucket[K, V any] struct {
-	expiry time.Time
-	idx    int // cache buckets know their position in the expire list
-	key    K
-	val    V
+	// accessed atomically; kept first to be 64-bit aligned on 32-bit
+	// platforms, as long as the bucket itself is (see slab)
+	hits     uint64
+	accessed int64
+
+	expiry     instant
+	softExpiry instant
+	created    instant
+	rev        uint64
+	idx        int // cache buckets know their position in the expire list
+	eidx       int // and in the evict list, when maintained
+	priority   int
+	cost       int           // as returned by SizeFunc, while MaxCost is set
+	seq        uint64        // tiebreaks equal expiries in the expiry index
+	recompute  time.Duration // how long the value took to compute, if known
+	key        K
+	val        V
+	deps       []K // keys this bucket depends on
+
+	// References to the value handed out by Acquire, and the lock held by
+	// WithEntry, if any.
+	ref   *entryRef
+	guard *entryGuard
+
+	// When the expire list is coalesced, buckets know their group and their
+	// position in it.
+	group *expiryGroup[K, V]
+	gidx  int
 }
 
+// expireListArity is the number of children of each node of the expire
+// list. A 4-ary heap is half as deep as a binary heap, which makes fixing
+// the position of a bucket cheaper, and the children of a node sit next to
+// each other in memory.
+const expireListArity = 4
+
+// expireList is a d-ary min-heap of buckets ordered by expiration time, or
+// a heap of groups of buckets when coalescing. See SetExpiryResolution.
+//
+// The heap is laid out as parallel slices: exp holds the expiration time of
+// the bucket at the same index of elts, so that sifting only reads
+// contiguous memory rather than chasing a pointer per comparison.
 type expireList[K, V any] struct {
-	elts []*cacheBucket[K, V]
+	elts       []*cacheBucket[K, V]
+	exp        []instant
+	resolution time.Duration
+	groups     minHeap[*expiryGroup[K, V]]
+	byExpiry   map[instant]*expiryGroup[K, V]
 }
 
-func (c *expireList[K, V]) Peek() (*cacheBucket[K, V], bool) {
-	if len(c.elts) > 0 {
-		return c.elts[0], true
+func (l *expireList[K, V]) Peek() (*cacheBucket[K, V], bool) {
+	if l.coalesced() {
+		if len(l.groups.elts) == 0 {
+			return nil, false
+		}
+		g := l.groups.elts[0]
+		return g.buckets[len(g.buckets)-1], true
+	}
+	if len(l.elts) > 0 {
+		return l.elts[0], true
 	}
 	return nil, false
 }
 
-// expireList must implement sort.Interface and container/heap.Interface
-
 func (l *expireList[K, V]) Len() int {
 	return len(l.elts)
 }
 
-func (l *expireList[K, V]) Less(i, j int) bool {
-	return l.elts[i].expiry.Before(l.elts[j].expiry)
+// Init establishes the heap ordering of the whole list, in linear time.
+func (l *expireList[K, V]) Init() {
+	for i, bucket := range l.elts {
+		bucket.idx = i
+	}
+	if l.coalesced() {
+		l.exp = nil
+		l.groups = minHeap[*expiryGroup[K, V]]{}
+		l.byExpiry = nil
+		for _, bucket := range l.elts {
+			l.attach(bucket)
+		}
+		return
+	}
+	l.exp = l.exp[:0]
+	for _, bucket := range l.elts {
+		l.exp = append(l.exp, bucket.expiry)
+	}
+	if len(l.elts) < 2 {
+		return
+	}
+	for i := (len(l.elts) - 2) / expireListArity; i >= 0; i-- {
+		l.down(i)
+	}
+}
+
+// Push appends bucket to the list. The heap ordering must then be restored
+// with Fix.
+func (l *expireList[K, V]) Push(bucket *cacheBucket[K, V]) {
+	bucket.idx = len(l.elts)
+	l.elts = append(l.elts, bucket)
+	if l.coalesced() {
+		l.attach(bucket)
+	} else {
+		l.exp = append(l.exp, bucket.expiry)
+	}
+}
+
+// Fix restores the heap ordering after the expiration time of the bucket at
+// index i changed.
+func (l *expireList[K, V]) Fix(i int) {
+	if l.coalesced() {
+		bucket := l.elts[i]
+		if bucket.group.expiry != bucket.expiry {
+			l.detach(bucket)
+			l.attach(bucket)
+		}
+		return
+	}
+	l.exp[i] = l.elts[i].expiry
+	if !l.down(i) {
+		l.up(i)
+	}
 }
 
-func (l *expireList[K, V]) Swap(i, j int) {
-	l.elts[i], l.elts[j] = l.elts[j], l.elts[i]
-	// Fix the expire list indices
-	l.elts[i].idx, l.elts[j].idx = i, j
+// Remove removes the bucket at index i from the list.
+func (l *expireList[K, V]) Remove(i int) *cacheBucket[K, V] {
+	bucket := l.elts[i]
+	if l.coalesced() {
+		l.detach(bucket)
+	}
+	last := len(l.elts) - 1
+	if i != last {
+		l.elts[i] = l.elts[last]
+		l.elts[i].idx = i
+	}
+	l.elts[last] = nil // don't keep referencing the item
+	l.elts = l.elts[:last]
+	if !l.coalesced() {
+		l.exp = l.exp[:last]
+		if i != last {
+			l.Fix(i)
+		}
+	}
+	bucket.idx = -1
+	return bucket
 }
 
-func (l *expireList[K, V]) Push(x any) {
-	l.elts = append(l.elts, x.(*cacheBucket[K, V]))
+func (l *expireList[K, V]) up(i int) {
+	bucket, expiry := l.elts[i], l.exp[i]
+	for i > 0 {
+		parent := (i - 1) / expireListArity
+		if expiry >= l.exp[parent] {
+			break
+		}
+		l.elts[i], l.exp[i] = l.elts[parent], l.exp[parent]
+		l.elts[i].idx = i
+		i = parent
+	}
+	l.elts[i], l.exp[i] = bucket, expiry
+	bucket.idx = i
 }
 
-func (l *expireList[K, V]) Pop() (val any) {
-	val = l.elts[len(l.elts)-1]
-	l.elts[len(l.elts)-1] = nil // don't keep referencing the item
-	l.elts = l.elts[:len(l.elts)-1]
-	return val
+func (l *expireList[K, V]) down(i int) bool {
+	start := i
+	bucket, expiry := l.elts[i], l.exp[i]
+	for {
+		first := expireListArity*i + 1
+		if first >= len(l.exp) {
+			break
+		}
+		end := first + expireListArity
+		if end > len(l.exp) {
+			end = len(l.exp)
+		}
+		min := first
+		for j := first + 1; j < end; j++ {
+			if l.exp[j] < l.exp[min] {
+				min = j
+			}
+		}
+		if l.exp[min] >= expiry {
+			break
+		}
+		l.elts[i], l.exp[i] = l.elts[min], l.exp[min]
+		l.elts[i].idx = i
+		i = min
+	}
+	l.elts[i], l.exp[i] = bucket, expiry
+	bucket.idx = i
+	return i > start
 }
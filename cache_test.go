@@ -7,9 +7,12 @@
 package ttlcache
 
 import (
+	"context"
+	"strings"
 	"testing"
 	"time"
 	"math/rand"
+	"unsafe"
 )
 
 func TestCache(t *testing.T) {
@@ -31,11 +34,439 @@ func TestCache(t *testing.T) {
 		t.Fatal("expected key bar to have expired, but it was still present")
 	}
 
-	stringCache.Expire("foo")
+	foo, ok = stringCache.Expire("foo")
+	if !ok || foo != "1" {
+		t.Fatalf("expected expiring key foo to return value 1, but got %v (found: %v)", foo, ok)
+	}
 	_, ok = stringCache.Get("foo")
 	if ok {
 		t.Fatal("expected key foo to have expired, but it was still present")
 	}
+	if _, ok = stringCache.Expire("foo"); ok {
+		t.Fatal("expected expiring key foo twice to find nothing")
+	}
+}
+
+func TestTTLFunc(t *testing.T) {
+	c := New[string, string]()
+	c.Set("foo", "1", DefaultTTL)
+	if e := c.ExpiringSoon(1)[0]; e.Expiry.After(time.Now()) {
+		t.Fatal("expected foo to expire immediately without a TTLFunc")
+	}
+
+	c.TTLFunc = func(key, value string) time.Duration {
+		if strings.HasPrefix(key, "session:") {
+			return time.Minute
+		}
+		return time.Hour
+	}
+	c.Set("session:1", "2", DefaultTTL)
+	c.Set("user:1", "3", DefaultTTL)
+	c.SetWithSoftTTL("user:2", "4", DefaultTTL, DefaultTTL)
+
+	for key, ttl := range map[string]time.Duration{"session:1": time.Minute, "user:1": time.Hour, "user:2": time.Hour} {
+		var expiry time.Time
+		for _, e := range c.ExpiringSoon(4) {
+			if e.Key == key {
+				expiry = e.Expiry
+			}
+		}
+		if remaining := time.Until(expiry); remaining > ttl || remaining < ttl-time.Second {
+			t.Fatalf("expected %v to expire in %v, got %v", key, ttl, remaining)
+		}
+	}
+	if _, stale, _ := c.GetStale("user:2"); stale {
+		t.Fatal("expected soft TTL of user:2 to default to its TTL")
+	}
+}
+
+func TestTry(t *testing.T) {
+	c := New[string, string]()
+	if !c.TrySet("foo", "1", time.Hour) {
+		t.Fatal("expected TrySet on an idle cache to succeed")
+	}
+	if v, ok := c.TryGet("foo"); !ok || v != "1" {
+		t.Fatalf("expected TryGet on an idle cache to find foo, got %v (found: %v)", v, ok)
+	}
+
+	c.mux.RLock()
+	if c.TrySet("foo", "2", time.Hour) {
+		t.Fatal("expected TrySet to give up on a locked cache")
+	}
+	if _, ok := c.TryGet("foo"); !ok {
+		t.Fatal("expected TryGet to succeed alongside other readers")
+	}
+	c.mux.RUnlock()
+
+	c.mux.Lock()
+	if _, ok := c.TryGet("foo"); ok {
+		t.Fatal("expected TryGet to give up on a cache locked for writing")
+	}
+	c.mux.Unlock()
+}
+
+func TestReserve(t *testing.T) {
+	c := NewWithSize[int, int](10)
+	if cap(c.expireList.elts) != 10 {
+		t.Fatalf("expected room for 10 keys, got %d", cap(c.expireList.elts))
+	}
+	for i := 0; i < 5; i++ {
+		c.Set(i, i, time.Hour)
+	}
+
+	c.Reserve(100)
+	if cap(c.expireList.elts) != 100 {
+		t.Fatalf("expected room for 100 keys, got %d", cap(c.expireList.elts))
+	}
+	c.Reserve(50)
+	if cap(c.expireList.elts) != 100 {
+		t.Fatal("expected Reserve not to shrink the cache")
+	}
+	for i := 0; i < 5; i++ {
+		if v, ok := c.Get(i); !ok || v != i {
+			t.Fatalf("expected key %d to be kept, got %v, %v", i, v, ok)
+		}
+	}
+}
+
+func TestKeysShared(t *testing.T) {
+	c := New[string, int]()
+	c.Set(strings.Repeat("x", 1<<10), 0, time.Hour)
+
+	data := func(s string) uintptr {
+		return *(*uintptr)(unsafe.Pointer(&s))
+	}
+	for key, bucket := range c.cache {
+		if data(key) != data(bucket.key) {
+			t.Fatal("expected the map and the bucket to share the key contents")
+		}
+	}
+}
+
+func TestNewFromMap(t *testing.T) {
+	m := make(map[int]int)
+	for i := 0; i < 100; i++ {
+		m[i] = i
+	}
+
+	c := NewFromMap(m, time.Hour)
+	for i := 0; i < 100; i++ {
+		if v, ok := c.Get(i); !ok || v != i {
+			t.Fatalf("expected key %d to be %d, got %v (found: %v)", i, i, v, ok)
+		}
+	}
+
+	c = NewFromMapFunc(m, func(key, value int) time.Duration {
+		return time.Duration(100-key) * time.Hour
+	})
+	for i, e := range c.ExpiringSoon(100) {
+		if e.Key != 99-i {
+			t.Fatalf("expected entry %d to be key %d, got %v", i, 99-i, e)
+		}
+	}
+}
+
+func TestGetStale(t *testing.T) {
+	c := New[string, string]()
+	c.Set("foo", "1", time.Hour)
+	c.Set("bar", "2", time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	v, stale, ok := c.GetStale("foo")
+	if !ok || stale || v != "1" {
+		t.Fatalf("expected fresh value 1 for foo, got %v (stale: %v, found: %v)", v, stale, ok)
+	}
+	v, stale, ok = c.GetStale("bar")
+	if !ok || !stale || v != "2" {
+		t.Fatalf("expected stale value 2 for bar, got %v (stale: %v, found: %v)", v, stale, ok)
+	}
+
+	c.Flush()
+	if _, _, ok := c.GetStale("bar"); ok {
+		t.Fatal("expected bar to be gone after a flush")
+	}
+
+	c.SetWithSoftTTL("baz", "3", time.Nanosecond, time.Hour)
+	time.Sleep(time.Millisecond)
+	c.Flush()
+	v, stale, ok = c.GetStale("baz")
+	if !ok || !stale || v != "3" {
+		t.Fatalf("expected stale value 3 for baz, got %v (stale: %v, found: %v)", v, stale, ok)
+	}
+}
+
+func TestRenew(t *testing.T) {
+	c := New[string, int]()
+	c.Renew = func(ctx context.Context, key string, value int) (int, time.Duration, bool) {
+		return value + 1, time.Hour, key == "lease"
+	}
+
+	c.Set("lease", 1, time.Nanosecond)
+	c.Set("other", 1, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if n := c.Flush(); n != 1 {
+		t.Fatalf("expected 1 key to be flushed, got %v", n)
+	}
+
+	if v, ok := c.Get("lease"); !ok || v != 2 {
+		t.Fatalf("expected lease to have been renewed to 2, got %v (found: %v)", v, ok)
+	}
+	if _, ok := c.Get("other"); ok {
+		t.Fatal("expected other to have expired")
+	}
+}
+
+func TestContains(t *testing.T) {
+	c := New[string, int]()
+	c.Set("foo", 1, time.Hour)
+	c.Set("bar", 2, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	events := c.Events(10)
+	if !c.Contains("foo") {
+		t.Fatal("expected foo to be in the cache")
+	}
+	if c.Contains("baz") {
+		t.Fatal("expected missing keys not to be in the cache")
+	}
+	contains := c.Contains("bar")
+	select {
+	case ev := <-events:
+		t.Fatalf("expected Contains not to emit events, got %v", ev)
+	default:
+	}
+	if _, ok := c.Get("bar"); contains != ok {
+		t.Fatal("expected Contains to agree with Get on expired keys that were not flushed yet")
+	}
+}
+
+func TestPeek(t *testing.T) {
+	c := New[string, int]()
+	c.TrackAccess = true
+	c.Adaptive = NewAdaptiveTTL[string](time.Minute, time.Hour)
+	c.Set("foo", 1, DefaultTTL)
+	c.Set("bar", 2, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	events := c.Events(10)
+	if v, ok := c.Peek("foo"); !ok || v != 1 {
+		t.Fatalf("expected to peek 1, got %v, %v", v, ok)
+	}
+	if v, ok := c.Peek("bar"); !ok || v != 2 {
+		t.Fatalf("expected expired keys that were not flushed yet to be peeked like Get, got %v, %v", v, ok)
+	}
+	if _, ok := c.Peek("baz"); ok {
+		t.Fatal("expected missing keys not to be peeked")
+	}
+	select {
+	case ev := <-events:
+		t.Fatalf("expected Peek not to emit events, got %v", ev)
+	default:
+	}
+	if b := c.cache["foo"]; b.hits != 0 || b.accessed != 0 {
+		t.Fatal("expected Peek not to count as an access")
+	}
+	if st, _ := c.Adaptive.state.Peek("foo"); st.hits != 0 {
+		t.Fatal("expected Peek not to count towards adaptive TTLs")
+	}
+}
+
+func TestExpireFunc(t *testing.T) {
+	c := New[int, int]()
+	for i := 0; i < 10; i++ {
+		c.Set(i, i, time.Hour)
+	}
+
+	n := c.ExpireFunc(func(key, value int) bool {
+		return key%2 == 0
+	})
+	if n != 5 {
+		t.Fatalf("expected 5 keys to be expired, got %v", n)
+	}
+	for i := 0; i < 10; i++ {
+		if _, ok := c.Get(i); ok != (i%2 == 1) {
+			t.Fatalf("unexpected presence of key %d: %v", i, ok)
+		}
+	}
+}
+
+func TestExpireMany(t *testing.T) {
+	c := New[int, int]()
+	for i := 0; i < 100; i++ {
+		c.Set(i, i, time.Duration(100-i)*time.Hour)
+	}
+
+	var expired int
+	c.OnExpire = func(key, value int) {
+		expired++
+	}
+
+	// A handful of keys are removed one by one, while many keys get the
+	// expire list rebuilt; both must leave a valid heap behind.
+	for _, tc := range []struct {
+		keys []int
+		want int
+	}{
+		{[]int{1, 1, 200}, 1},
+		{[]int{10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23, 24, 25, 26, 27, 28, 29, 30, 30}, 21},
+	} {
+		keys, want := tc.keys, tc.want
+		expired = 0
+		if n := c.ExpireMany(keys); n != want || expired != want {
+			t.Fatalf("expected %d keys to be expired, got %v (callbacks: %v)", want, n, expired)
+		}
+		for _, key := range keys {
+			if _, ok := c.Get(key); ok {
+				t.Fatalf("expected key %d to be expired", key)
+			}
+		}
+	}
+
+	var order []int
+	c.OnExpire = func(key, value int) {
+		order = append(order, key)
+	}
+	c.EvictN(100)
+	if len(order) != 78 {
+		t.Fatalf("expected 78 keys to remain, got %d", len(order))
+	}
+	for i := 1; i < len(order); i++ {
+		if order[i] > order[i-1] {
+			t.Fatalf("expected keys to be evicted by ascending expiry, got %v", order)
+		}
+	}
+}
+
+func TestRetain(t *testing.T) {
+	c := New[int, int]()
+	for i := 0; i < 10; i++ {
+		c.Set(i, i, time.Hour)
+	}
+
+	var expired []int
+	c.OnExpire = func(key, value int) {
+		expired = append(expired, key)
+	}
+	n := c.Retain(func(key, value int) bool {
+		return key < 3
+	})
+	if n != 7 || len(expired) != 7 {
+		t.Fatalf("expected 7 keys to be expired, got %v (callbacks: %v)", n, expired)
+	}
+	for i := 0; i < 10; i++ {
+		if _, ok := c.Get(i); ok != (i < 3) {
+			t.Fatalf("unexpected presence of key %d: %v", i, ok)
+		}
+	}
+}
+
+func TestEvictN(t *testing.T) {
+	c := New[int, int]()
+	for i := 0; i < 10; i++ {
+		c.Set(i, i, time.Duration(i+1)*time.Hour)
+	}
+
+	events := c.Events(10)
+	if n := c.EvictN(3); n != 3 {
+		t.Fatalf("expected 3 keys to be evicted, got %v", n)
+	}
+	for i := 0; i < 3; i++ {
+		if ev := <-events; ev.Kind != EventEvict || ev.Key != i {
+			t.Fatalf("expected key %d to be evicted, got %v", i, ev)
+		}
+	}
+	if n := c.EvictN(100); n != 7 {
+		t.Fatalf("expected the remaining 7 keys to be evicted, got %v", n)
+	}
+}
+
+func TestNextExpiry(t *testing.T) {
+	c := New[string, string]()
+	if _, ok := c.NextExpiry(); ok {
+		t.Fatal("expected empty cache to have no next expiry")
+	}
+
+	before := time.Now()
+	c.Set("foo", "1", time.Hour)
+	c.Set("bar", "2", time.Minute)
+	next, ok := c.NextExpiry()
+	if !ok {
+		t.Fatal("expected cache to have a next expiry")
+	}
+	if next.Before(before.Add(time.Minute)) || next.After(time.Now().Add(time.Minute)) {
+		t.Fatalf("expected next expiry to be the one of bar, got %v", next)
+	}
+}
+
+func TestExpireList(t *testing.T) {
+	c := New[int, int]()
+	for i := 0; i < 1000; i++ {
+		key := rand.Intn(200)
+		if rand.Intn(4) == 0 {
+			c.Expire(key)
+		} else {
+			c.Set(key, key, time.Duration(rand.Intn(1000)+1)*time.Minute)
+		}
+
+		elts := c.expireList.elts
+		for j, bucket := range elts {
+			if bucket.idx != j {
+				t.Fatalf("expected bucket at %d to know its index, got %d", j, bucket.idx)
+			}
+			if parent := (j - 1) / expireListArity; j > 0 && bucket.expiry.Before(elts[parent].expiry) {
+				t.Fatalf("bucket at %d expires before its parent at %d", j, parent)
+			}
+		}
+	}
+
+	prev := unset
+	for c.expireList.Len() > 0 {
+		bucket := c.expireList.Remove(0)
+		if bucket.expiry.Before(prev) {
+			t.Fatal("expected buckets to be removed by ascending expiry")
+		}
+		prev = bucket.expiry
+	}
+}
+
+func TestExpiryTolerance(t *testing.T) {
+	now := time.Now()
+	clock := &now
+	c := New[int, int]()
+	c.Clock = clockFunc(func() time.Time { return *clock })
+	c.ExpiryTolerance = time.Second
+
+	c.Set(1, 1, time.Minute)
+	expiry := c.cache[1].expiry
+
+	now = now.Add(500 * time.Millisecond)
+	c.Set(1, 2, time.Minute)
+	if v, _ := c.Get(1); v != 2 || c.cache[1].expiry != expiry {
+		t.Fatalf("expected the value to change but not the expiry, got %v, %v", v, c.cache[1].expiry)
+	}
+
+	now = now.Add(time.Second)
+	c.Set(1, 3, time.Minute)
+	if want := toInstant(now.Add(time.Minute)); c.cache[1].expiry != want {
+		t.Fatalf("expected the expiry to move past the tolerance, got %v instead of %v", c.cache[1].expiry, want)
+	}
+
+	// Keys past their expiration time always get a new one, whether set
+	// again or renewed.
+	now = now.Add(time.Minute + 500*time.Millisecond)
+	c.Set(1, 4, time.Second)
+	if want := toInstant(now.Add(time.Second)); c.cache[1].expiry != want {
+		t.Fatalf("expected an expired key to get a new expiry, got %v instead of %v", c.cache[1].expiry, want)
+	}
+	c.Set(2, 1, 10*time.Millisecond)
+	c.Renew = func(ctx context.Context, key, value int) (int, time.Duration, bool) {
+		return value, 100 * time.Millisecond, true
+	}
+	now = now.Add(20 * time.Millisecond)
+	c.Flush()
+	if want := toInstant(now.Add(100 * time.Millisecond)); c.cache[2].expiry != want {
+		t.Fatalf("expected a renewed key to get a new expiry, got %v instead of %v", c.cache[2].expiry, want)
+	}
 }
 
 func BenchmarkCache(b *testing.B) {
@@ -87,4 +518,49 @@ func BenchmarkCache(b *testing.B) {
 			c.Expire(indices[i])
 		}
 	})
+
+	b.Run("priority", func (b *testing.B) {
+		c := New[int, int]()
+		for i := 0; i < 1<<16; i++ {
+			c.SetWithPriority(i, i, time.Hour, i%8)
+		}
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			c.SetWithPriority(rand.Intn(1<<16), i, time.Hour, i%8)
+		}
+	})
+
+	b.Run("expiring-soon", func (b *testing.B) {
+		c := New[int, int]()
+		for i := 0; i < 1<<16; i++ {
+			c.Set(i, i, time.Duration(rand.Int63n(int64(time.Hour))))
+		}
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			c.ExpiringSoon(1000)
+		}
+	})
+}
+
+func BenchmarkExpireList(b *testing.B) {
+	const size = 1 << 20
+	var l expireList[int, int]
+	for i := 0; i < size; i++ {
+		l.Push(&cacheBucket[int, int]{key: i, expiry: instant(rand.Int63())})
+	}
+	l.Init()
+	fix := make([]*cacheBucket[int, int], size)
+	copy(fix, l.elts)
+	rand.Shuffle(len(fix), func(i, j int) {
+		fix[i], fix[j] = fix[j], fix[i]
+	})
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		bucket := fix[i%size]
+		bucket.expiry = instant(rand.Int63())
+		l.Fix(bucket.idx)
+	}
 }
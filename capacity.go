@@ -0,0 +1,85 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+// SetCapacity changes the Capacity of the cache, and immediately evicts keys
+// past it, returning how many were evicted. Unlike setting Capacity
+// directly, it is safe to call concurrently with other operations, which
+// lets autoscaling logic grow and shrink the cache at runtime.
+func (cache *Cache[K, V]) SetCapacity(n int) int {
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	cache.Capacity = n
+	if n <= 0 || len(cache.cache) <= n {
+		return 0
+	}
+	cache.flush()
+	var evicted int
+	for len(cache.cache) > n && cache.evict() {
+		evicted++
+	}
+	return evicted
+}
+
+// SetMaxCost changes the MaxCost of the cache, and immediately evicts keys
+// past it, returning how many were evicted. The costs of all the keys are
+// computed anew with SizeFunc, which takes linear time.
+func (cache *Cache[K, V]) SetMaxCost(c int) int {
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	cache.MaxCost = c
+	if c <= 0 {
+		// Costs are no longer tracked, and would be stale by the time MaxCost
+		// gets set again.
+		for _, bucket := range cache.expireList.elts {
+			bucket.cost = 0
+		}
+		cache.cost = 0
+		return 0
+	}
+	for _, bucket := range cache.expireList.elts {
+		value, _ := cache.load(bucket)
+		cache.charge(bucket, value)
+	}
+	n := len(cache.cache)
+	cache.shed()
+	return n - len(cache.cache)
+}
+
+// Cost returns the total cost of the entries of the cache, as tracked while
+// MaxCost is set.
+func (cache *Cache[K, V]) Cost() int {
+	cache.mux.RLock()
+	defer cache.mux.RUnlock()
+
+	return cache.cost
+}
+
+// charge updates the cost of bucket for its new value. cache.mux must be held
+// for writing.
+func (cache *Cache[K, V]) charge(bucket *cacheBucket[K, V], value V) {
+	sizeFunc := cache.SizeFunc
+	if cache.MaxCost <= 0 || sizeFunc == nil {
+		return
+	}
+	var cost int
+	cache.guard("SizeFunc", func() { cost = sizeFunc(bucket.key, value) })
+	if cost < 0 {
+		cost = 0
+	}
+	cache.cost += cost - bucket.cost
+	bucket.cost = cost
+}
+
+// shed evicts keys until the total cost of the cache is within MaxCost.
+// cache.mux must be held for writing.
+func (cache *Cache[K, V]) shed() {
+	for cache.MaxCost > 0 && cache.cost > cache.MaxCost && cache.evict() {
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetCapacity(t *testing.T) {
+	c := New[int, int]()
+	for i := 0; i < 10; i++ {
+		c.Set(i, i, time.Duration(i+1)*time.Minute)
+	}
+	if n := c.SetCapacity(4); n != 6 {
+		t.Fatalf("expected 6 keys to be evicted, got %d", n)
+	}
+	if n := c.Snapshot().Len(); n != 4 {
+		t.Fatalf("expected 4 keys to be left, got %d", n)
+	}
+	for i := 6; i < 10; i++ {
+		if _, ok := c.Get(i); !ok {
+			t.Fatalf("expected the latest expiring keys to be kept, %d is missing", i)
+		}
+	}
+	if n := c.SetCapacity(8); n != 0 {
+		t.Fatalf("expected growing the cache not to evict, got %d", n)
+	}
+	c.Set(10, 10, time.Hour)
+	if n := c.Snapshot().Len(); n != 5 {
+		t.Fatalf("expected the grown cache to take new keys, got %d keys", n)
+	}
+}
+
+func TestMaxCost(t *testing.T) {
+	c := New[string, string]()
+	c.SizeFunc = func(key, value string) int { return len(value) }
+	c.MaxCost = 10
+
+	c.Set("a", "xxxx", time.Minute)
+	c.Set("b", "xxxx", 2*time.Minute)
+	if cost := c.Cost(); cost != 8 {
+		t.Fatalf("expected a cost of 8, got %d", cost)
+	}
+	c.Set("c", "xxxx", 3*time.Minute)
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("expected the soonest expiring key to be evicted past MaxCost")
+	}
+	if cost := c.Cost(); cost != 8 {
+		t.Fatalf("expected a cost of 8, got %d", cost)
+	}
+
+	c.Set("b", "x", 2*time.Minute)
+	if cost := c.Cost(); cost != 5 {
+		t.Fatalf("expected overwrites to update the cost, got %d", cost)
+	}
+	if c.SetIfNewer("d", "xxxxxxxxxxxx", time.Hour, nil) {
+		t.Fatal("expected keys costing more than MaxCost not to be kept")
+	}
+
+	if n := c.SetMaxCost(4); n != 1 {
+		t.Fatalf("expected 1 key to be evicted, got %d", n)
+	}
+	if _, ok := c.Get("c"); !ok {
+		t.Fatal("expected keys within MaxCost to be kept")
+	}
+	if n := c.SetMaxCost(0); n != 0 || c.Cost() != 0 {
+		t.Fatalf("expected costs to stop being tracked, evicted %d, cost %d", n, c.Cost())
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if err := c.checkInvariants(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"math"
+	"time"
+)
+
+// Clock tells the current time. Package clocktest implements a fake clock
+// for tests.
+type Clock interface {
+	Now() time.Time
+}
+
+func (cache *Cache[K, V]) now() time.Time {
+	if clock := cache.Clock; clock != nil {
+		return clock.Now()
+	}
+	return time.Now()
+}
+
+// epoch is the origin of instants. Measuring time relative to it keeps the
+// monotonic clock reading of time.Now, making expiry immune to changes of
+// the wall clock.
+var epoch = time.Now()
+
+// instant is a point in time, as nanoseconds since epoch. It is a third of
+// the size of time.Time, and cheaper to compare.
+type instant int64
+
+// unset is the expiration time of buckets that were not given one yet.
+const unset instant = math.MinInt64
+
+func toInstant(t time.Time) instant {
+	return instant(t.Sub(epoch))
+}
+
+func (t instant) Time() time.Time {
+	return epoch.Add(time.Duration(t))
+}
+
+func (t instant) After(u instant) bool {
+	return t > u
+}
+
+func (t instant) Before(u instant) bool {
+	return t < u
+}
+
+// Sub returns t-u, saturating instead of overflowing.
+func (t instant) Sub(u instant) time.Duration {
+	switch {
+	case u < 0 && t > math.MaxInt64+u:
+		return math.MaxInt64
+	case u > 0 && t < math.MinInt64+u:
+		return math.MinInt64
+	}
+	return time.Duration(t - u)
+}
+
+// Add returns t+d, saturating instead of overflowing.
+func (t instant) Add(d time.Duration) instant {
+	switch {
+	case d > 0 && t > math.MaxInt64-instant(d):
+		return math.MaxInt64
+	case d < 0 && t < math.MinInt64-instant(d):
+		return math.MinInt64
+	}
+	return t + instant(d)
+}
+
+// instant returns the current time as an instant.
+func (cache *Cache[K, V]) instant() instant {
+	return toInstant(cache.now())
+}
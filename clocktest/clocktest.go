@@ -0,0 +1,138 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package clocktest implements a fake clock for testing code built on
+// ttlcache without sleeping.
+//
+// Set a Clock as the Clock of a cache, then move time forward with Advance
+// to make keys expire. Code waiting on the clock through After or Sleep
+// wakes up as time passes; BlockUntil lets tests wait for that code to be
+// blocked before advancing time, rather than guessing with real sleeps.
+//
+// Background work of the cache itself, like janitors, waits on the system
+// clock regardless: flush the cache explicitly instead.
+package clocktest
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock is a fake clock, only moving when told to. It is safe for
+// concurrent use.
+type Clock struct {
+	now     time.Time
+	timers  []*timer // waiting to fire, by ascending deadline
+	changed chan struct{}
+	mux     sync.Mutex
+}
+
+type timer struct {
+	at time.Time
+	ch chan time.Time
+}
+
+// New returns a clock stopped at the specified time.
+func New(now time.Time) *Clock {
+	return &Clock{now: now, changed: make(chan struct{})}
+}
+
+// Now returns the current time of the clock.
+func (c *Clock) Now() time.Time {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	return c.now
+}
+
+// Advance moves the clock forward by d, firing the timers that come due.
+func (c *Clock) Advance(d time.Duration) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	c.set(c.now.Add(d))
+}
+
+// Set moves the clock to t, firing the timers that come due. Setting the
+// clock back in time leaves timers waiting for it to catch up again.
+func (c *Clock) Set(t time.Time) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	c.set(t)
+}
+
+func (c *Clock) set(t time.Time) {
+	c.now = t
+	n := 0
+	for n < len(c.timers) && !c.timers[n].at.After(t) {
+		c.timers[n].ch <- t
+		c.timers[n] = nil // don't keep referencing the timers
+		n++
+	}
+	if n > 0 {
+		c.timers = c.timers[n:]
+		c.notify()
+	}
+}
+
+// After returns a channel receiving the time of the clock once it reaches
+// d past the current time, like time.After.
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	t := &timer{at: c.now.Add(d), ch: ch}
+	i := sort.Search(len(c.timers), func(i int) bool {
+		return c.timers[i].at.After(t.at)
+	})
+	c.timers = append(c.timers, nil)
+	copy(c.timers[i+1:], c.timers[i:])
+	c.timers[i] = t
+	c.notify()
+	return ch
+}
+
+// Sleep blocks until the clock reaches d past the current time, like
+// time.Sleep.
+func (c *Clock) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+// Timers returns the number of timers created by After and Sleep that are
+// waiting to fire.
+func (c *Clock) Timers() int {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	return len(c.timers)
+}
+
+// BlockUntil waits until exactly n timers are waiting to fire, so that tests
+// can advance the clock once the code under test went to sleep.
+func (c *Clock) BlockUntil(n int) {
+	for {
+		c.mux.Lock()
+		timers, changed := len(c.timers), c.changed
+		c.mux.Unlock()
+		if timers == n {
+			return
+		}
+		<-changed
+	}
+}
+
+// notify wakes up callers of BlockUntil. c.mux must be held.
+func (c *Clock) notify() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
@@ -0,0 +1,70 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package clocktest
+
+import (
+	"testing"
+	"time"
+
+	"snai.pe/go-ttlcache"
+)
+
+func TestClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := New(start)
+
+	c := ttlcache.New[string, int]()
+	c.Clock = clock
+	c.Set("foo", 1, time.Minute)
+
+	clock.Advance(30 * time.Second)
+	c.Flush()
+	if _, ok := c.Get("foo"); !ok {
+		t.Fatal("expected foo to still be cached")
+	}
+	clock.Advance(30 * time.Second)
+	c.Flush()
+	if _, ok := c.Get("foo"); ok {
+		t.Fatal("expected foo to expire once the clock advanced past its TTL")
+	}
+	if got := clock.Now(); !got.Equal(start.Add(time.Minute)) {
+		t.Fatalf("expected the clock to read %v, got %v", start.Add(time.Minute), got)
+	}
+}
+
+func TestClockTimers(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := New(start)
+
+	woke := make(chan time.Time)
+	for _, d := range []time.Duration{2 * time.Second, time.Second} {
+		d := d
+		go func() {
+			clock.Sleep(d)
+			woke <- clock.Now()
+		}()
+	}
+	clock.BlockUntil(2)
+
+	clock.Advance(time.Second)
+	<-woke
+	if n := clock.Timers(); n != 1 {
+		t.Fatalf("expected 1 timer left, got %d", n)
+	}
+
+	clock.Set(start.Add(time.Hour))
+	if got := <-woke; !got.Equal(start.Add(time.Hour)) {
+		t.Fatalf("expected the last sleeper to wake up at the time it was set to, got %v", got)
+	}
+	clock.BlockUntil(0)
+
+	select {
+	case <-clock.After(0):
+	default:
+		t.Fatal("expected After to fire right away for non-positive durations")
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrClosed is returned by operations on a closed cache.
+var ErrClosed = errors.New("ttlcache: cache is closed")
+
+// DrainError is returned by Close when background work tied to the cache
+// did not finish draining before the context passed to Close was done.
+type DrainError struct {
+	// Dropped is the number of queued writes, like those of running
+	// WriteBuffers, that were dropped instead of being applied.
+	Dropped int
+
+	// Err is the context error.
+	Err error
+}
+
+func (err *DrainError) Error() string {
+	return fmt.Sprintf("ttlcache: closed before draining, dropping %d queued writes: %v", err.Dropped, err.Err)
+}
+
+func (err *DrainError) Unwrap() error {
+	return err.Err
+}
+
+// Close shuts down the cache. It first tells background work tied to the
+// cache, like running Refreshers and WriteBuffers, to stop, and waits for it
+// to drain: in-flight loads get to finish and queued writes get applied.
+// If ctx is done before that, the work left is cancelled or dropped, and a
+// *DrainError wrapping the context error is returned.
+//
+// Keys that are past their expiration time are then expired, firing
+// OnExpire as usual. All the other keys remaining in the cache are dropped,
+// firing OnExpire for each of them if ExpireOnClose is set, and watch,
+// subscription and event stream channels are closed. Values stored in a
+// custom backend are left alone unless ExpireOnClose is set; the backend is
+// then closed if it implements io.Closer, and the error it returns, if any,
+// is returned by Close unless draining failed. The Journal of
+// the cache, if any, stops logging changes, and is left as is for the cache
+// to be recovered later.
+//
+// Closing the cache also unregisters it, if it was registered.
+//
+// Once closed, the cache stays empty: Set and the like do nothing, and
+// operations that can fail return ErrClosed, including subsequent calls to
+// Close.
+func (cache *Cache[K, V]) Close(ctx context.Context) error {
+	cache.mux.Lock()
+	if cache.closing {
+		cache.mux.Unlock()
+		return ErrClosed
+	}
+	cache.closing = true
+	close(cache.doneChan())
+	name := cache.name
+	cache.mux.Unlock()
+
+	if name != "" {
+		cache.unregister(name)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		cache.background.Wait()
+		close(stopped)
+	}()
+	var err error
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	cache.flush()
+	cache.closed = true
+	cache.thaw()
+	close(cache.shutdownChan())
+	// Shutting down is not a change worth logging: the journal must survive
+	// for the cache to be recovered.
+	cache.journal = nil
+
+	dropped := 0
+	for _, d := range cache.drainers {
+		dropped += d.drop()
+	}
+	cache.drainers = nil
+
+	if cache.ExpireOnClose {
+		for len(cache.expireList.elts) > 0 {
+			cache.delete(cache.expireList.elts[0], EventDelete)
+		}
+	} else if cache.backend == nil && cache.valuesMay(valueIfacesEvictee) {
+		for _, bucket := range cache.expireList.elts {
+			bucket := bucket
+			closed := func() {
+				if value := bucket.val; bucket.pinned() {
+					bucket.ref.cleanup = func() { cache.evicted(value, ReasonClosed) }
+				} else {
+					cache.evicted(value, ReasonClosed)
+				}
+			}
+			// WithEntry may still be modifying the value.
+			if bucket.lockOut() {
+				bucket.postpone(closed)
+			} else {
+				closed()
+			}
+		}
+	}
+	var cerr error
+	if c, ok := cache.backend.(io.Closer); ok {
+		cerr = c.Close()
+	}
+	cache.cache = nil
+	cache.cost = 0
+	cache.expireList.elts = nil
+	cache.expireList.Init()
+	cache.evictList = evictList[K, V]{}
+	cache.slab = slab[K, V]{}
+	if cache.keyIndex != nil {
+		cache.keyIndex = newSkiplist(cache.keyIndex.less)
+	}
+	if cache.expiries != nil {
+		cache.setExpiryIndex(true)
+	}
+	cache.dependents = nil
+	for _, idx := range cache.indexes {
+		idx.reset()
+	}
+	cache.waiters = nil
+	for _, t := range cache.timers {
+		t.timer.Stop()
+	}
+	cache.timers = nil
+
+	for _, watchers := range cache.watchers {
+		for _, ch := range watchers {
+			close(ch)
+		}
+	}
+	cache.watchers = nil
+	for _, sub := range cache.subs {
+		close(sub.ch)
+	}
+	cache.subs = nil
+	if cache.events != nil {
+		close(cache.events)
+		cache.events = nil
+	}
+
+	if err != nil {
+		return &DrainError{Dropped: dropped, Err: err}
+	}
+	return cerr
+}
+
+// doneChan returns a channel closed when the cache starts closing. cache.mux
+// must be held for writing.
+func (cache *Cache[K, V]) doneChan() chan struct{} {
+	if cache.done == nil {
+		cache.done = make(chan struct{})
+	}
+	return cache.done
+}
+
+// shutdownChan returns a channel closed once the cache is closed, after
+// background work was given the chance to drain. cache.mux must be held for
+// writing.
+func (cache *Cache[K, V]) shutdownChan() chan struct{} {
+	if cache.shutdown == nil {
+		cache.shutdown = make(chan struct{})
+	}
+	return cache.shutdown
+}
+
+// startBackground registers background work tied to the cache, which must
+// call cache.background.Done once it stops. It returns a channel closed when
+// the work should stop, and another closed when the work left must be
+// abandoned rather than drained.
+func (cache *Cache[K, V]) startBackground() (done, shutdown <-chan struct{}, err error) {
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	if cache.closing {
+		return nil, nil, ErrClosed
+	}
+	cache.background.Add(1)
+	return cache.doneChan(), cache.shutdownChan(), nil
+}
+
+// drainer is background work holding queued work, which Close drops if it
+// could not be drained in time.
+type drainer interface {
+	// Pending returns how much work is queued.
+	Pending() int
+
+	// drop discards the queued work, and returns how much was discarded.
+	// It is called with cache.mux held for writing.
+	drop() int
+}
+
+func (cache *Cache[K, V]) addDrainer(d drainer) {
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	cache.drainers = append(cache.drainers, d)
+}
+
+func (cache *Cache[K, V]) removeDrainer(d drainer) {
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	for i, other := range cache.drainers {
+		if other == d {
+			cache.drainers = append(cache.drainers[:i], cache.drainers[i+1:]...)
+			break
+		}
+	}
+}
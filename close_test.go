@@ -0,0 +1,145 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestClose(t *testing.T) {
+	c := New[string, int]()
+	c.ExpireOnClose = true
+	var expired []string
+	c.OnExpire = func(key string, value int) {
+		expired = append(expired, key)
+	}
+	c.Set("foo", 1, time.Hour)
+	c.Set("bar", 2, time.Hour)
+
+	events, _ := c.Watch("foo")
+	stream := c.Events(10)
+
+	c.Loader = func(ctx context.Context, key string) (int, time.Duration, error) {
+		<-ctx.Done()
+		return 0, 0, ctx.Err()
+	}
+	r := c.NewRefresher(1)
+	r.Register("foo", time.Hour)
+	stopped := make(chan error)
+	go func() { stopped <- r.Run(context.Background()) }()
+
+	waited := make(chan error)
+	go func() {
+		_, err := c.WaitFor(context.Background(), "baz")
+		waited <- err
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	// The in-flight load only returns once cancelled, so the refresher
+	// cannot drain.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	var drainErr *DrainError
+	if err := c.Close(ctx); !errors.As(err, &drainErr) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected Close to fail with a DrainError, got %v", err)
+	}
+	if err := <-stopped; err != ErrClosed {
+		t.Fatalf("expected refresher to stop with ErrClosed, got %v", err)
+	}
+	if err := <-waited; err != ErrClosed {
+		t.Fatalf("expected WaitFor to fail with ErrClosed, got %v", err)
+	}
+	if len(expired) != 2 {
+		t.Fatalf("expected all keys to expire on close, got %v", expired)
+	}
+	for range events {
+	}
+	for range stream {
+	}
+
+	c.Set("foo", 1, time.Hour)
+	if _, ok := c.Get("foo"); ok {
+		t.Fatal("expected Set on a closed cache to do nothing")
+	}
+	if err := c.Close(context.Background()); err != ErrClosed {
+		t.Fatalf("expected closing twice to fail with ErrClosed, got %v", err)
+	}
+	if err := r.Run(context.Background()); err != ErrClosed {
+		t.Fatalf("expected running a refresher on a closed cache to fail, got %v", err)
+	}
+	if _, ok := <-c.Events(1); ok {
+		t.Fatal("expected event stream of a closed cache to be closed")
+	}
+}
+
+func TestCloseDrain(t *testing.T) {
+	c := New[string, int]()
+	c.ExpireOnClose = true
+	var expired []string
+	c.OnExpire = func(key string, value int) {
+		expired = append(expired, key)
+	}
+	c.Set("stale", 0, time.Millisecond)
+
+	b := c.NewWriteBuffer(10)
+	stopped := make(chan error)
+	go func() { stopped <- b.Run(context.Background()) }()
+
+	c.Loader = func(ctx context.Context, key string) (int, time.Duration, error) {
+		time.Sleep(10 * time.Millisecond)
+		return 1, time.Hour, nil
+	}
+	r := c.NewRefresher(1)
+	r.Register("loaded", time.Hour)
+	go r.Run(context.Background())
+	time.Sleep(5 * time.Millisecond)
+
+	b.Set("queued", 1, time.Hour)
+	if err := c.Close(context.Background()); err != nil {
+		t.Fatalf("expected Close to drain, got %v", err)
+	}
+	if err := <-stopped; err != ErrClosed {
+		t.Fatalf("expected the write buffer to stop with ErrClosed, got %v", err)
+	}
+	want := []string{"stale", "loaded", "queued"}
+	if len(expired) != len(want) || expired[0] != want[0] {
+		t.Fatalf("expected %v to expire, got %v", want, expired)
+	}
+}
+
+func TestCloseDrainDropped(t *testing.T) {
+	c := New[string, int]()
+	c.Loader = func(ctx context.Context, key string) (int, time.Duration, error) {
+		<-ctx.Done()
+		return 0, 0, ctx.Err()
+	}
+	r := c.NewRefresher(1)
+	r.Register("foo", time.Hour)
+	go r.Run(context.Background())
+	time.Sleep(5 * time.Millisecond)
+
+	// Registered without running, so the writes are still queued once
+	// Close gives up.
+	b := c.NewWriteBuffer(10)
+	c.addDrainer(b)
+	b.Set("foo", 1, time.Hour)
+	b.Set("foo", 2, time.Hour)
+	b.Set("bar", 1, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var err *DrainError
+	if !errors.As(c.Close(ctx), &err) || err.Dropped != 2 {
+		t.Fatalf("expected Close to report 2 dropped writes, got %v", err)
+	}
+	if b.Pending() != 0 {
+		t.Fatal("expected dropped writes to be discarded")
+	}
+}
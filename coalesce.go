@@ -0,0 +1,111 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import "time"
+
+// SetExpiryResolution makes the cache round expiration times up to the next
+// multiple of resolution, and coalesce keys expiring at the same time into a
+// single node of the expiry heap. When many keys share coarse deadlines,
+// this shrinks the heap down to one node per deadline, and setting a key
+// again within the same window no longer reorders the heap at all.
+//
+// Keys live up to resolution longer than their TTL. A non-positive
+// resolution stops coalescing, but leaves expiration times rounded.
+// Changing the resolution takes linear time.
+func (cache *Cache[K, V]) SetExpiryResolution(resolution time.Duration) {
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	cache.thaw()
+	l := &cache.expireList
+	l.resolution = resolution
+	l.groups = minHeap[*expiryGroup[K, V]]{}
+	l.byExpiry = nil
+	if resolution > 0 {
+		for _, bucket := range l.elts {
+			cache.reindexExpiry(bucket, false)
+			bucket.expiry = l.round(bucket.expiry)
+			cache.reindexExpiry(bucket, true)
+		}
+		if cache.priorities {
+			cache.prioritize()
+		}
+	}
+	l.Init()
+	cache.rearm()
+}
+
+// expiryGroup is a node of the expiry heap of a coalescing expire list,
+// holding all the buckets with the same expiration time.
+type expiryGroup[K, V any] struct {
+	expiry  instant
+	buckets []*cacheBucket[K, V]
+	idx     int // position in the heap of groups
+}
+
+// coalesced reports whether the expire list groups buckets by expiration
+// time. When it does, elts holds the buckets in no particular order, and the
+// heap is made of groups instead.
+func (l *expireList[K, V]) coalesced() bool {
+	return l.resolution > 0
+}
+
+// round rounds t up to the resolution of the list, on the wall clock.
+func (l *expireList[K, V]) round(t instant) instant {
+	if l.resolution <= 0 {
+		return t
+	}
+	wall := t.Time()
+	r := wall.Truncate(l.resolution)
+	if r.Before(wall) {
+		r = r.Add(l.resolution)
+	}
+	return t.Add(r.Sub(wall))
+}
+
+// head reports whether bucket is among the soonest to expire.
+func (l *expireList[K, V]) head(bucket *cacheBucket[K, V]) bool {
+	if l.coalesced() {
+		return bucket.group.idx == 0
+	}
+	return bucket.idx == 0
+}
+
+// attach adds bucket to the group of its expiration time.
+func (l *expireList[K, V]) attach(bucket *cacheBucket[K, V]) {
+	if l.byExpiry == nil {
+		l.byExpiry = make(map[instant]*expiryGroup[K, V])
+	}
+	g, ok := l.byExpiry[bucket.expiry]
+	if !ok {
+		g = &expiryGroup[K, V]{expiry: bucket.expiry}
+		l.byExpiry[bucket.expiry] = g
+		l.groups.push(g, heapKey{at: g.expiry}, &g.idx)
+	}
+	bucket.group = g
+	bucket.gidx = len(g.buckets)
+	g.buckets = append(g.buckets, bucket)
+}
+
+// detach removes bucket from its group, and the group from the heap if it
+// becomes empty.
+func (l *expireList[K, V]) detach(bucket *cacheBucket[K, V]) {
+	g := bucket.group
+	last := len(g.buckets) - 1
+	if bucket.gidx != last {
+		g.buckets[bucket.gidx] = g.buckets[last]
+		g.buckets[bucket.gidx].gidx = bucket.gidx
+	}
+	g.buckets[last] = nil // don't keep referencing the item
+	g.buckets = g.buckets[:last]
+	bucket.group = nil
+	if len(g.buckets) == 0 {
+		delete(l.byExpiry, g.expiry)
+		l.groups.remove(g.idx)
+	}
+}
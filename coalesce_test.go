@@ -0,0 +1,93 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSetExpiryResolution(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := New[int, int]()
+	c.Clock = clockFunc(func() time.Time { return now })
+	c.Set(-1, -1, 90*time.Second)
+
+	c.SetExpiryResolution(time.Minute)
+	for i := 0; i < 100; i++ {
+		c.Set(i, i, time.Duration(i+1)*time.Second)
+	}
+	if n := len(c.expireList.groups.elts); n != 2 {
+		t.Fatalf("expected keys to be coalesced into 2 groups, got %d", n)
+	}
+	if next, _ := c.NextExpiry(); !next.Equal(now.Add(time.Minute)) {
+		t.Fatalf("expected expiries to be rounded up to the minute, got %v", next)
+	}
+
+	var last time.Time
+	var n int
+	c.RangeByExpiry(func(e Entry[int, int]) bool {
+		if e.Expiry.Before(last) {
+			t.Fatalf("expected entries by ascending expiry, got %v after %v", e.Expiry, last)
+		}
+		last = e.Expiry
+		n++
+		return true
+	})
+	if n != 101 {
+		t.Fatalf("expected to range over 101 entries, got %d", n)
+	}
+
+	clone := c.Clone()
+	now = now.Add(time.Minute)
+	if n := c.Flush(); n != 60 {
+		t.Fatalf("expected 60 keys to be flushed, got %d", n)
+	}
+	if n := clone.Flush(); n != 60 {
+		t.Fatalf("expected clones to stay coalesced, got %d keys flushed", n)
+	}
+
+	c.SetExpiryResolution(0)
+	c.Set(1000, 0, time.Second)
+	if next, _ := c.NextExpiry(); !next.Equal(now.Add(time.Second)) {
+		t.Fatalf("expected expiries to be exact again, got %v", next)
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if err := c.checkInvariants(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCoalescedInvariants(t *testing.T) {
+	c := New[string, int]()
+	c.SetExpiryIndex(true)
+	c.SetExpiryResolution(10 * time.Minute)
+	c.Capacity = 50
+
+	for i := 0; i < 2000; i++ {
+		key := strconv.Itoa(rand.Intn(100))
+		ttl := time.Duration(rand.Intn(100)+1) * time.Minute
+		switch rand.Intn(7) {
+		case 0:
+			c.Expire(key)
+		case 1:
+			c.ExpireFunc(func(k string, _ int) bool { return k == key || rand.Intn(10) == 0 })
+		case 2:
+			c.SetWithPriority(key, i, ttl, rand.Intn(3))
+		case 3:
+			c.EvictN(1)
+		default:
+			c.Set(key, i, ttl)
+		}
+		if err := c.checkInvariants(); err != nil {
+			t.Fatalf("after %d operations: %v", i+1, err)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"sync"
+	"time"
+)
+
+// Compact is a minimal TTL cache storing its entries by value rather than
+// behind a pointer each, for small keys and values. Entries live in a single
+// slice, reused as keys come and go, and are found through a map of slot
+// numbers, so setting keys does not allocate once the cache has grown.
+//
+// When neither K nor V contain pointers, none of the memory of the cache
+// holds pointers either, and the garbage collector does not need to scan it
+// at all, where a Cache costs it a bucket per entry.
+//
+// Like Cache, Compact only expires keys on write. It supports none of the
+// other features of Cache.
+type Compact[K comparable, V any] struct {
+	// OnExpire gets called whenever a key expires from the cache.
+	OnExpire func(key K, value V)
+
+	// Clock, if set, tells the time to the cache instead of the system clock.
+	Clock Clock
+
+	index map[K]int32
+	slots []compactSlot[K, V]
+	free  []int32
+	heap  []int32 // slot numbers, ordered by expiration time
+	mux   sync.RWMutex
+}
+
+type compactSlot[K, V any] struct {
+	expiry instant
+	pos    int32 // position in the heap
+	key    K
+	val    V
+}
+
+// NewCompact returns an empty compact cache.
+func NewCompact[K comparable, V any]() *Compact[K, V] {
+	return &Compact[K, V]{index: make(map[K]int32)}
+}
+
+func (c *Compact[K, V]) now() instant {
+	if clock := c.Clock; clock != nil {
+		return toInstant(clock.Now())
+	}
+	return toInstant(time.Now())
+}
+
+// Set assigns the specified value to the specified key, expiring after ttl.
+// Expired keys get flushed first.
+func (c *Compact[K, V]) Set(key K, value V, ttl time.Duration) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	now := c.now()
+	c.flush(now)
+	s, ok := c.index[key]
+	if !ok {
+		if n := len(c.free); n > 0 {
+			s = c.free[n-1]
+			c.free = c.free[:n-1]
+		} else {
+			s = int32(len(c.slots))
+			c.slots = append(c.slots, compactSlot[K, V]{})
+		}
+		c.slots[s] = compactSlot[K, V]{pos: int32(len(c.heap)), key: key}
+		c.heap = append(c.heap, s)
+		c.index[key] = s
+	}
+	slot := &c.slots[s]
+	slot.val = value
+	slot.expiry = now.Add(ttl)
+	c.fix(int(slot.pos))
+}
+
+// Get retrieves the value in the cache for the specified key if it exists,
+// as well as whether the value was found.
+func (c *Compact[K, V]) Get(key K) (value V, found bool) {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+
+	s, ok := c.index[key]
+	if !ok || !c.slots[s].expiry.After(c.now()) {
+		return value, false
+	}
+	return c.slots[s].val, true
+}
+
+// Expire removes the specified key from the cache, calling OnExpire, and
+// returns its value if it was found.
+func (c *Compact[K, V]) Expire(key K) (value V, found bool) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	s, ok := c.index[key]
+	if !ok {
+		return value, false
+	}
+	return c.remove(s), true
+}
+
+// Flush removes all expired keys from the cache, and returns how many were
+// removed.
+func (c *Compact[K, V]) Flush() int {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	return c.flush(c.now())
+}
+
+// Len returns the number of keys in the cache, including those that expired
+// but were not flushed yet.
+func (c *Compact[K, V]) Len() int {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+
+	return len(c.index)
+}
+
+func (c *Compact[K, V]) flush(now instant) (n int) {
+	for len(c.heap) > 0 && !c.slots[c.heap[0]].expiry.After(now) {
+		c.remove(c.heap[0])
+		n++
+	}
+	return n
+}
+
+func (c *Compact[K, V]) remove(s int32) V {
+	slot := c.slots[s]
+	last := len(c.heap) - 1
+	if i := int(slot.pos); i != last {
+		c.heap[i] = c.heap[last]
+		c.slots[c.heap[i]].pos = int32(i)
+		c.heap = c.heap[:last]
+		c.fix(i)
+	} else {
+		c.heap = c.heap[:last]
+	}
+	delete(c.index, slot.key)
+	c.slots[s] = compactSlot[K, V]{} // don't keep referencing the contents
+	c.free = append(c.free, s)
+
+	if onExpire := c.OnExpire; onExpire != nil {
+		onExpire(slot.key, slot.val)
+	}
+	return slot.val
+}
+
+// fix restores the heap ordering after the expiration time of the slot at
+// position i of the heap changed.
+func (c *Compact[K, V]) fix(i int) {
+	start := i
+	s := c.heap[i]
+	expiry := c.slots[s].expiry
+	for {
+		child := 2*i + 1
+		if child >= len(c.heap) {
+			break
+		}
+		if right := child + 1; right < len(c.heap) && c.slots[c.heap[right]].expiry < c.slots[c.heap[child]].expiry {
+			child = right
+		}
+		if c.slots[c.heap[child]].expiry >= expiry {
+			break
+		}
+		c.heap[i] = c.heap[child]
+		c.slots[c.heap[i]].pos = int32(i)
+		i = child
+	}
+	if i == start {
+		for i > 0 {
+			parent := (i - 1) / 2
+			if expiry >= c.slots[c.heap[parent]].expiry {
+				break
+			}
+			c.heap[i] = c.heap[parent]
+			c.slots[c.heap[i]].pos = int32(i)
+			i = parent
+		}
+	}
+	c.heap[i] = s
+	c.slots[s].pos = int32(i)
+}
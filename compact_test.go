@@ -0,0 +1,69 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestCompact(t *testing.T) {
+	now := time.Now()
+	c := NewCompact[int, int]()
+	c.Clock = clockFunc(func() time.Time { return now })
+	var expired []int
+	c.OnExpire = func(key, _ int) { expired = append(expired, key) }
+
+	for i := 0; i < 1000; i++ {
+		c.Set(i, i*2, time.Duration(rand.Intn(1000)+1)*time.Second)
+	}
+	for i := 0; i < 1000; i += 3 {
+		c.Set(i, i*3, time.Hour)
+	}
+	if v, ok := c.Get(3); !ok || v != 9 {
+		t.Fatalf("expected 3 to be overwritten, got %v, %v", v, ok)
+	}
+	if v, ok := c.Expire(4); !ok || v != 8 {
+		t.Fatalf("expected 4 to be expired, got %v, %v", v, ok)
+	}
+
+	now = now.Add(30 * time.Minute)
+	if n := c.Flush(); n != 665 {
+		t.Fatalf("expected 665 keys to be flushed, got %d", n)
+	}
+	if len(expired) != 666 {
+		t.Fatalf("expected OnExpire to be called for 666 keys, got %d", len(expired))
+	}
+	if n := c.Len(); n != 334 {
+		t.Fatalf("expected 334 keys to be left, got %d", n)
+	}
+	for i := 0; i < 1000; i++ {
+		if _, ok := c.Get(i); ok != (i%3 == 0) {
+			t.Fatalf("expected only keys set for an hour to be left, %d present: %v", i, ok)
+		}
+	}
+}
+
+func TestCompactAllocs(t *testing.T) {
+	c := NewCompact[int, int]()
+	for i := 0; i < 100; i++ {
+		c.Set(i, i, time.Hour)
+	}
+	for i := 0; i < 100; i++ {
+		c.Expire(i)
+	}
+
+	// Keys reuse the slots freed by earlier keys.
+	var i int
+	if allocs := testing.AllocsPerRun(100, func() {
+		c.Set(i, i, time.Hour)
+		i++
+	}); allocs != 0 {
+		t.Fatalf("expected setting keys not to allocate, got %v allocations", allocs)
+	}
+}
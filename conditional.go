@@ -0,0 +1,57 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"time"
+)
+
+// SetIfNewer assigns the specified value to the specified key like Set, but
+// only if the key is missing or newer reports that value is newer than the
+// current one, and reports whether it did. Expired values that were not
+// flushed yet are always replaced.
+//
+// This protects against out-of-order updates, for instance when several
+// producers write to the same keys. newer is called with the cache locked,
+// and must not call methods of the cache.
+func (cache *Cache[K, V]) SetIfNewer(key K, value V, ttl time.Duration, newer func(current, value V) bool) bool {
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	if bucket, found := cache.cache[key]; found && bucket.expiry.After(cache.instant()) {
+		if current, found := cache.load(bucket); found && !newer(current, value) {
+			return false
+		}
+	}
+	return cache.set(key, value, ttl, ttl) != nil
+}
+
+// SetAllIfAbsent assigns all the specified values to their keys with an
+// expiration of ttl, but only if none of the keys is already in the cache,
+// and reports whether it did. Either all the keys are set, or none is.
+//
+// This allows claiming compound resources spanning several keys without
+// ever leaving partial claims behind. Expired values that were not flushed
+// yet count as absent.
+func (cache *Cache[K, V]) SetAllIfAbsent(values map[K]V, ttl time.Duration) bool {
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	if cache.closed {
+		return false
+	}
+	now := cache.instant()
+	for key := range values {
+		if bucket, found := cache.cache[key]; found && bucket.expiry.After(now) {
+			return false
+		}
+	}
+	for key, value := range values {
+		cache.set(key, value, ttl, ttl)
+	}
+	return true
+}
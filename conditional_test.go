@@ -0,0 +1,64 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetIfNewer(t *testing.T) {
+	type versioned struct {
+		version int
+		data    string
+	}
+	newer := func(current, value versioned) bool {
+		return value.version > current.version
+	}
+
+	c := New[string, versioned]()
+	if !c.SetIfNewer("foo", versioned{2, "b"}, time.Hour, newer) {
+		t.Fatal("expected setting a missing key to succeed")
+	}
+	if c.SetIfNewer("foo", versioned{1, "a"}, time.Hour, newer) {
+		t.Fatal("expected setting an older version to fail")
+	}
+	if !c.SetIfNewer("foo", versioned{3, "c"}, time.Hour, newer) {
+		t.Fatal("expected setting a newer version to succeed")
+	}
+	if v, _ := c.Get("foo"); v.data != "c" {
+		t.Fatalf("expected foo to be at version 3, got %v", v)
+	}
+
+	c.Set("bar", versioned{5, "e"}, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if !c.SetIfNewer("bar", versioned{4, "d"}, time.Hour, newer) {
+		t.Fatal("expected replacing an expired value to succeed")
+	}
+}
+
+func TestSetAllIfAbsent(t *testing.T) {
+	c := New[string, int]()
+	if !c.SetAllIfAbsent(map[string]int{"a": 1, "b": 2}, time.Hour) {
+		t.Fatal("expected claiming a and b to succeed")
+	}
+	if c.SetAllIfAbsent(map[string]int{"b": 3, "c": 4}, time.Hour) {
+		t.Fatal("expected claiming b and c to fail")
+	}
+	if _, ok := c.Get("c"); ok {
+		t.Fatal("expected c not to be set by a failed claim")
+	}
+	if v, _ := c.Get("b"); v != 2 {
+		t.Fatalf("expected b to be left alone, got %v", v)
+	}
+
+	c.Set("d", 5, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if !c.SetAllIfAbsent(map[string]int{"c": 6, "d": 7}, time.Hour) {
+		t.Fatal("expected claiming c and expired d to succeed")
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// ErrDecrypt is returned when reading an encrypted stream that was tampered
+// with, truncated, or encrypted with another key.
+var ErrDecrypt = errors.New("ttlcache: cannot decrypt stream")
+
+const (
+	// cryptChunk is the maximum size of the plaintext of the chunks of
+	// encrypted streams.
+	cryptChunk = 64 << 10
+
+	cryptIDSize = 16
+)
+
+// EncryptWriter returns a writer encrypting everything written to it with
+// aead before writing it to w, like persisted snapshots or spilled values,
+// which keeps sensitive values off the disk in plaintext. The key can come
+// from anywhere, including a key management service. Close must be called
+// once done writing, to mark the end of the stream; it does not close w.
+//
+// The stream is made of chunks, each sealed with its own random nonce and
+// authenticated along with its position, so that reordering, truncating or
+// splicing streams gets detected by DecryptReader.
+func EncryptWriter(w io.Writer, aead cipher.AEAD) io.WriteCloser {
+	return &encryptWriter{w: w, aead: aead}
+}
+
+// DecryptReader returns a reader decrypting a stream written through
+// EncryptWriter with the same key. Reads fail with ErrDecrypt if the stream
+// is not authentic.
+func DecryptReader(r io.Reader, aead cipher.AEAD) io.Reader {
+	return &decryptReader{r: r, aead: aead}
+}
+
+type encryptWriter struct {
+	w     io.Writer
+	aead  cipher.AEAD
+	id    []byte
+	index uint64
+	buf   []byte
+	err   error
+}
+
+func (w *encryptWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	var n int
+	for len(p) > 0 {
+		m := cryptChunk - len(w.buf)
+		if m > len(p) {
+			m = len(p)
+		}
+		w.buf = append(w.buf, p[:m]...)
+		p = p[m:]
+		n += m
+		if len(w.buf) == cryptChunk {
+			if w.err = w.seal(false); w.err != nil {
+				return n, w.err
+			}
+		}
+	}
+	return n, nil
+}
+
+// flush seals the data written so far into a chunk of its own, so that it
+// can be read back even if the stream never gets closed.
+func (w *encryptWriter) flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	if len(w.buf) > 0 {
+		w.err = w.seal(false)
+	}
+	return w.err
+}
+
+func (w *encryptWriter) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	w.err = w.seal(true)
+	if w.err == nil {
+		w.err = errors.New("ttlcache: write to closed encrypted stream")
+		return nil
+	}
+	return w.err
+}
+
+func (w *encryptWriter) seal(final bool) error {
+	if w.id == nil {
+		w.id = make([]byte, cryptIDSize)
+		if _, err := rand.Read(w.id); err != nil {
+			return err
+		}
+		if _, err := w.w.Write(w.id); err != nil {
+			return err
+		}
+	}
+
+	ns := w.aead.NonceSize()
+	chunk := make([]byte, 4+ns, 4+ns+len(w.buf)+w.aead.Overhead())
+	nonce := chunk[4:]
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	chunk = w.aead.Seal(chunk, nonce, w.buf, cryptAD(w.id, w.index, final))
+	binary.BigEndian.PutUint32(chunk, uint32(len(chunk)-4))
+
+	w.index++
+	w.buf = w.buf[:0]
+	_, err := w.w.Write(chunk)
+	return err
+}
+
+type decryptReader struct {
+	r     io.Reader
+	aead  cipher.AEAD
+	id    []byte
+	index uint64
+	buf   []byte
+	final bool
+	err   error
+
+	// partial makes streams cut short, like logs being written when the
+	// process died, fail with io.ErrUnexpectedEOF rather than ErrDecrypt.
+	partial bool
+}
+
+func (r *decryptReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.final {
+			return 0, io.EOF
+		}
+		r.err = r.open()
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *decryptReader) open() error {
+	if r.id == nil {
+		r.id = make([]byte, cryptIDSize)
+		if _, err := io.ReadFull(r.r, r.id); err != nil {
+			return r.truncated()
+		}
+	}
+
+	var size [4]byte
+	if _, err := io.ReadFull(r.r, size[:]); err != nil {
+		return r.truncated()
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n < uint32(r.aead.NonceSize()+r.aead.Overhead()) || n > uint32(r.aead.NonceSize()+cryptChunk+r.aead.Overhead()) {
+		return ErrDecrypt
+	}
+	chunk := make([]byte, n)
+	if _, err := io.ReadFull(r.r, chunk); err != nil {
+		return r.truncated()
+	}
+	nonce, sealed := chunk[:r.aead.NonceSize()], chunk[r.aead.NonceSize():]
+
+	// The final chunk is authenticated as such, so try both.
+	for _, final := range [2]bool{false, true} {
+		if plain, err := r.aead.Open(nil, nonce, sealed, cryptAD(r.id, r.index, final)); err == nil {
+			r.buf = plain
+			r.final = final
+			r.index++
+			return nil
+		}
+	}
+	return ErrDecrypt
+}
+
+// truncated returns the error for a stream ending before its final chunk.
+func (r *decryptReader) truncated() error {
+	if r.partial {
+		return io.ErrUnexpectedEOF
+	}
+	return ErrDecrypt
+}
+
+// cryptAD returns the additional data authenticated along with a chunk.
+func cryptAD(id []byte, index uint64, final bool) []byte {
+	ad := make([]byte, len(id)+9)
+	copy(ad, id)
+	binary.BigEndian.PutUint64(ad[len(id):], index)
+	if final {
+		ad[len(ad)-1] = 1
+	}
+	return ad
+}
@@ -0,0 +1,88 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"io"
+	"testing"
+	"time"
+)
+
+func newTestAEAD(t *testing.T) cipher.AEAD {
+	key := make([]byte, 32)
+	rand.Read(key)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return aead
+}
+
+func TestEncryptedStream(t *testing.T) {
+	aead := newTestAEAD(t)
+
+	plain := make([]byte, 3*cryptChunk+123)
+	rand.Read(plain)
+
+	var buf bytes.Buffer
+	w := EncryptWriter(&buf, aead)
+	if _, err := w.Write(plain); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	sealed := buf.Bytes()
+	if bytes.Contains(sealed, plain[:64]) {
+		t.Fatal("expected the stream to be encrypted")
+	}
+
+	got, err := io.ReadAll(DecryptReader(bytes.NewReader(sealed), aead))
+	if err != nil || !bytes.Equal(got, plain) {
+		t.Fatalf("expected the stream to decrypt to the plaintext, got %d bytes, %v", len(got), err)
+	}
+
+	truncated := sealed[:len(sealed)-len(sealed)/8]
+	if _, err := io.ReadAll(DecryptReader(bytes.NewReader(truncated), aead)); err != ErrDecrypt {
+		t.Fatalf("expected truncation to be detected, got %v", err)
+	}
+	if _, err := io.ReadAll(DecryptReader(bytes.NewReader(sealed), newTestAEAD(t))); err != ErrDecrypt {
+		t.Fatalf("expected another key to fail, got %v", err)
+	}
+}
+
+func TestEncryptedSnapshot(t *testing.T) {
+	aead := newTestAEAD(t)
+	c := New[string, string]()
+	c.Set("ssn", "123-45-6789", time.Hour)
+
+	var buf bytes.Buffer
+	w := EncryptWriter(&buf, aead)
+	if _, err := c.Snapshot().WriteTo(w); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	if bytes.Contains(buf.Bytes(), []byte("123-45-6789")) {
+		t.Fatal("expected the snapshot to be encrypted")
+	}
+
+	restored := New[string, string]()
+	if err := restored.Restore(DecryptReader(&buf, aead)); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := restored.Get("ssn"); v != "123-45-6789" {
+		t.Fatalf("expected the value to be restored, got %q", v)
+	}
+}
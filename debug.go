@@ -0,0 +1,25 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+//go:build ttlcache_debug
+
+package ttlcache
+
+// CheckInvariants verifies that the internal structures of the cache are
+// consistent: that the expiry heap is ordered, that every entry knows its
+// position in it, and that the map, the heap and the optional indexes agree
+// on the keys in the cache. It returns an error describing the first
+// inconsistency found, if any.
+//
+// It takes linear time, and is only available when building with the
+// ttlcache_debug tag, to catch corruption early when extending the cache,
+// for instance with custom backends.
+func (cache *Cache[K, V]) CheckInvariants() error {
+	cache.mux.RLock()
+	defer cache.mux.RUnlock()
+
+	return cache.checkInvariants()
+}
@@ -0,0 +1,139 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package dedup implements per-key event deduplication and debouncing.
+package dedup
+
+import (
+	"sync"
+	"time"
+
+	"snai.pe/go-ttlcache"
+)
+
+// Deduper suppresses duplicate events for the same key happening within a
+// time window.
+type Deduper[K comparable] struct {
+	window time.Duration
+	seen   *ttlcache.Cache[K, struct{}]
+	mux    sync.Mutex
+}
+
+// NewDeduper returns a deduper that suppresses duplicates of an event for the
+// specified window after it was first seen.
+func NewDeduper[K comparable](window time.Duration) *Deduper[K] {
+	return &Deduper[K]{
+		window: window,
+		seen:   ttlcache.New[K, struct{}](),
+	}
+}
+
+// Seen reports whether an event for the specified key was already seen in
+// the current window. If it was not, a new window starts for that key.
+func (d *Deduper[K]) Seen(key K) bool {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	// The cache only expires entries on write; flush first so that windows
+	// that are over do not count.
+	d.seen.Flush()
+	if _, ok := d.seen.Get(key); ok {
+		return true
+	}
+	d.seen.Set(key, struct{}{}, d.window)
+	return false
+}
+
+// Forget ends the current window for the specified key, if any.
+func (d *Deduper[K]) Forget(key K) {
+	d.seen.Expire(key)
+}
+
+// Debouncer coalesces bursts of events for the same key into a single
+// callback, fired once no new event for that key happened for a while.
+//
+// Like Deduper, pending keys are kept in a cache, where every event pushes
+// back the expiration time of its key; the callback is called as the key
+// expires. A single timer flushes the cache when the next key is due.
+type Debouncer[K comparable] struct {
+	wait    time.Duration
+	fn      func(key K)
+	pending *ttlcache.Cache[K, struct{}]
+	timer   *time.Timer
+	armed   bool
+	fired   []K  // keys expired since the last flush
+	cancel  bool // set while canceling, which is not firing
+	mux     sync.Mutex
+}
+
+// NewDebouncer returns a debouncer calling fn for a key once wait has elapsed
+// since the last event for that key.
+//
+// fn is called from its own goroutine.
+func NewDebouncer[K comparable](wait time.Duration, fn func(key K)) *Debouncer[K] {
+	d := &Debouncer[K]{
+		wait:    wait,
+		fn:      fn,
+		pending: ttlcache.New[K, struct{}](),
+	}
+	// Called with d.mux held, by whichever call to the cache expired key.
+	d.pending.OnExpire = func(key K, _ struct{}) {
+		if !d.cancel {
+			d.fired = append(d.fired, key)
+		}
+	}
+	return d
+}
+
+// Trigger records an event for the specified key, postponing its callback.
+func (d *Debouncer[K]) Trigger(key K) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	d.pending.Set(key, struct{}{}, d.wait)
+	// Keys expire in the order they were last triggered: an armed timer is
+	// already due no later than this one.
+	if !d.armed {
+		d.arm(d.wait)
+	}
+}
+
+// Cancel drops the pending callback for the specified key, if any.
+func (d *Debouncer[K]) Cancel(key K) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	d.cancel = true
+	d.pending.Expire(key)
+	d.cancel = false
+}
+
+// arm sets the timer to flush the pending keys after delay. d.mux must be
+// held.
+func (d *Debouncer[K]) arm(delay time.Duration) {
+	if d.timer == nil {
+		d.timer = time.AfterFunc(delay, d.flush)
+	} else {
+		d.timer.Reset(delay)
+	}
+	d.armed = true
+}
+
+func (d *Debouncer[K]) flush() {
+	d.mux.Lock()
+	d.pending.Flush()
+	fired := d.fired
+	d.fired = nil
+	d.armed = false
+	if next, ok := d.pending.NextExpiry(); ok {
+		d.arm(time.Until(next))
+	}
+	d.mux.Unlock()
+
+	for _, key := range fired {
+		d.fn(key)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package dedup
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeduper(t *testing.T) {
+	d := NewDeduper[string](time.Hour)
+
+	if d.Seen("foo") {
+		t.Fatal("expected foo not to have been seen yet")
+	}
+	if !d.Seen("foo") {
+		t.Fatal("expected foo to have been seen")
+	}
+	if d.Seen("bar") {
+		t.Fatal("expected bar not to have been seen yet")
+	}
+
+	d.Forget("foo")
+	if d.Seen("foo") {
+		t.Fatal("expected foo to have been forgotten")
+	}
+
+	short := NewDeduper[string](time.Millisecond)
+	short.Seen("foo")
+	time.Sleep(2 * time.Millisecond)
+	if short.Seen("foo") {
+		t.Fatal("expected the window for foo to be over")
+	}
+}
+
+func TestDebouncer(t *testing.T) {
+	fired := make(chan string, 10)
+	d := NewDebouncer(10*time.Millisecond, func(key string) {
+		fired <- key
+	})
+
+	for i := 0; i < 5; i++ {
+		d.Trigger("foo")
+		time.Sleep(time.Millisecond)
+	}
+	d.Trigger("bar")
+	d.Cancel("bar")
+
+	select {
+	case key := <-fired:
+		if key != "foo" {
+			t.Fatalf("expected callback for foo, got %v", key)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected callback for foo to fire")
+	}
+
+	select {
+	case key := <-fired:
+		t.Fatalf("expected a single callback, got another one for %v", key)
+	case <-time.After(30 * time.Millisecond):
+	}
+}
+
+func TestDebouncerStaggered(t *testing.T) {
+	fired := make(chan string, 10)
+	d := NewDebouncer(10*time.Millisecond, func(key string) {
+		fired <- key
+	})
+
+	d.Trigger("foo")
+	time.Sleep(5 * time.Millisecond)
+	d.Trigger("bar")
+	for _, want := range []string{"foo", "bar"} {
+		select {
+		case key := <-fired:
+			if key != want {
+				t.Fatalf("expected callback for %v, got %v", want, key)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected callback for %v to fire", want)
+		}
+	}
+
+	// The timer must be armed again once idle.
+	d.Trigger("baz")
+	select {
+	case key := <-fired:
+		if key != "baz" {
+			t.Fatalf("expected callback for baz, got %v", key)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected callback for baz to fire")
+	}
+}
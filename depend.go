@@ -0,0 +1,77 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+// DependOn declares that key depends on dependency: whenever dependency is
+// removed from the cache or set to a new value, key gets expired too, and so
+// do the keys depending on key, transitively. It reports whether both keys
+// were found in the cache.
+//
+// This is meant for caching values derived from other cached values, which
+// would otherwise outlive their inputs. The link is dropped when either key
+// is removed.
+func (cache *Cache[K, V]) DependOn(key, dependency K) bool {
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	bucket, ok := cache.cache[key]
+	if !ok {
+		return false
+	}
+	if _, ok := cache.cache[dependency]; !ok {
+		return false
+	}
+	if cache.dependents == nil {
+		cache.dependents = make(map[K]map[K]struct{})
+	}
+	cache.link(bucket, dependency)
+	return true
+}
+
+func (cache *Cache[K, V]) link(bucket *cacheBucket[K, V], dependency K) {
+	dependents, ok := cache.dependents[dependency]
+	if !ok {
+		dependents = make(map[K]struct{})
+		cache.dependents[dependency] = dependents
+	}
+	if _, ok := dependents[bucket.key]; ok {
+		return
+	}
+	dependents[bucket.key] = struct{}{}
+	bucket.deps = append(bucket.deps, dependency)
+}
+
+// unlinkDependencies drops the links between bucket and the keys it depends
+// on, then expires the keys depending on it. cache.mux must be held for
+// writing.
+func (cache *Cache[K, V]) unlinkDependencies(bucket *cacheBucket[K, V]) {
+	for _, dep := range bucket.deps {
+		dependents := cache.dependents[dep]
+		delete(dependents, bucket.key)
+		if len(dependents) == 0 {
+			delete(cache.dependents, dep)
+		}
+	}
+	bucket.deps = nil
+	cache.expireDependents(bucket.key)
+}
+
+// expireDependents expires the keys depending on key. cache.mux must be held
+// for writing.
+func (cache *Cache[K, V]) expireDependents(key K) {
+	dependents, ok := cache.dependents[key]
+	if !ok {
+		return
+	}
+	// Dropping the links first stops the cascade on dependency cycles.
+	delete(cache.dependents, key)
+	for dependent := range dependents {
+		if bucket, ok := cache.cache[dependent]; ok {
+			cache.delete(bucket, EventDelete)
+		}
+	}
+}
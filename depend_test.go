@@ -0,0 +1,101 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDependOn(t *testing.T) {
+	c := New[string, int]()
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		c.Set(key, 0, time.Hour)
+	}
+
+	// c depends on b which depends on a, and on d; a and c form a cycle.
+	if !c.DependOn("b", "a") || !c.DependOn("c", "b") || !c.DependOn("c", "d") || !c.DependOn("a", "c") {
+		t.Fatal("expected dependencies to be declared")
+	}
+	if c.DependOn("b", "missing") || c.DependOn("missing", "b") {
+		t.Fatal("expected dependencies on missing keys to be refused")
+	}
+
+	c.Expire("a")
+	for _, key := range []string{"a", "b", "c"} {
+		if _, ok := c.Get(key); ok {
+			t.Fatalf("expected %q to be expired along with its dependency", key)
+		}
+	}
+	for _, key := range []string{"d", "e"} {
+		if _, ok := c.Get(key); !ok {
+			t.Fatalf("expected %q to be kept", key)
+		}
+	}
+	if len(c.dependents) != 0 {
+		t.Fatalf("expected all links to be dropped, got %v", c.dependents)
+	}
+
+	c.Set("f", 0, time.Hour)
+	c.DependOn("f", "e")
+	c.Set("e", 1, time.Hour)
+	if _, ok := c.Get("f"); ok {
+		t.Fatal("expected f to be expired when its dependency was set")
+	}
+
+	c.Set("g", 0, time.Hour)
+	c.DependOn("g", "d")
+	c.ExpireMany([]string{"d", "e", "g"})
+	if len(c.cache) != 0 || len(c.expireList.elts) != 0 {
+		t.Fatalf("expected the cache to be empty, got %v", c.cache)
+	}
+}
+
+func TestSetDependencyCycle(t *testing.T) {
+	c := New[string, int]()
+	c.Set("a", 1, time.Hour)
+	c.Set("b", 2, time.Hour)
+	c.DependOn("a", "b")
+	c.DependOn("b", "a")
+
+	// Overwriting a expires b, which expires a in turn; a must be set anew.
+	c.Set("a", 3, time.Hour)
+	if v, ok := c.Get("a"); !ok || v != 3 {
+		t.Fatalf("expected a to be set, got %v, %v", v, ok)
+	}
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("expected b to be expired")
+	}
+	c.mux.Lock()
+	err := c.checkInvariants()
+	c.mux.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWarmDependencyCycle(t *testing.T) {
+	c := New[string, int]()
+	c.Set("a", 1, time.Hour)
+	c.Set("b", 2, time.Hour)
+	c.DependOn("a", "b")
+	c.DependOn("b", "a")
+
+	c.Warm([]Entry[string, int]{{Key: "a", Value: 3, Expiry: time.Now().Add(time.Hour)}})
+	if v, ok := c.Get("a"); !ok || v != 3 {
+		t.Fatalf("expected a to be set, got %v, %v", v, ok)
+	}
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("expected b to be expired")
+	}
+	c.mux.Lock()
+	err := c.checkInvariants()
+	c.mux.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
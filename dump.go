@@ -0,0 +1,76 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Dump writes a human-readable listing of the entries in the cache to w,
+// for debugging. Every entry gets a line with its key, its value, its
+// remaining TTL, its size if SizeFunc is set, and flags among:
+//
+//	expired   the entry expired, but was not flushed yet
+//	stale     the entry is past its soft TTL
+//	priority  the entry has a non-zero priority, which follows
+//
+// Entries are sorted by the printed form of their keys, which keeps the
+// output stable across calls.
+func (cache *Cache[K, V]) Dump(w io.Writer) error {
+	cache.mux.RLock()
+	defer cache.mux.RUnlock()
+
+	type line struct {
+		key  string
+		rest string
+	}
+	now := cache.instant()
+	lines := make([]line, 0, len(cache.expireList.elts))
+	for _, bucket := range cache.expireList.elts {
+		value := cache.settled(bucket)
+
+		var rest strings.Builder
+		fmt.Fprintf(&rest, "%v ttl=%v", value, bucket.expiry.Sub(now).Round(time.Millisecond))
+		if sizeFunc := cache.SizeFunc; sizeFunc != nil {
+			var size int
+			cache.guard("SizeFunc", func() { size = sizeFunc(bucket.key, value) })
+			fmt.Fprintf(&rest, " size=%d", size)
+		}
+		switch {
+		case !bucket.expiry.After(now):
+			rest.WriteString(" expired")
+		case !bucket.softExpiry.After(now):
+			rest.WriteString(" stale")
+		}
+		if bucket.priority != 0 {
+			fmt.Fprintf(&rest, " priority=%d", bucket.priority)
+		}
+		lines = append(lines, line{key: fmt.Sprintf("%#v", bucket.key), rest: rest.String()})
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		return lines[i].key < lines[j].key
+	})
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "ttlcache.Cache (%d entries)\n", len(lines))
+	for _, l := range lines {
+		fmt.Fprintf(bw, "\t%s: %s\n", l.key, l.rest)
+	}
+	return bw.Flush()
+}
+
+// String returns the listing of the cache written by Dump.
+func (cache *Cache[K, V]) String() string {
+	var sb strings.Builder
+	cache.Dump(&sb)
+	return sb.String()
+}
@@ -0,0 +1,31 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDump(t *testing.T) {
+	now := time.Now()
+	c := New[string, int]()
+	c.Clock = fixedClock(now)
+	c.Set("b", 2, time.Hour)
+	c.SetWithSoftTTL("a", 1, 0, time.Minute)
+	c.SetWithPriority("c", 3, 0, 5)
+	c.SizeFunc = func(key string, value int) int { return value * 10 }
+
+	want := `ttlcache.Cache (3 entries)
+	"a": 1 ttl=1m0s size=10 stale
+	"b": 2 ttl=1h0m0s size=20
+	"c": 3 ttl=0s size=30 expired priority=5
+`
+	if got := c.String(); got != want {
+		t.Fatalf("unexpected dump:\n%s\nexpected:\n%s", got, want)
+	}
+}
@@ -0,0 +1,347 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Entry is a copy of a key-value pair stored in the cache, along with its
+// expiration time.
+type Entry[K, V any] struct {
+	Key    K
+	Value  V
+	Expiry time.Time
+}
+
+func (cache *Cache[K, V]) entry(bucket *cacheBucket[K, V]) Entry[K, V] {
+	value := cache.settled(bucket)
+	return Entry[K, V]{Key: bucket.key, Value: value, Expiry: bucket.expiry.Time()}
+}
+
+// Merge imports the live entries of other into the cache, with their
+// remaining TTLs. When a key is present in both caches, conflict gets called
+// with the current entry and the imported one, and the entry it returns is
+// kept; if conflict is nil, imported entries always win.
+//
+// conflict is called with the cache locked, and must not call methods of
+// either cache.
+func (cache *Cache[K, V]) Merge(other *Cache[K, V], conflict func(current, imported Entry[K, V]) Entry[K, V]) {
+	if other == cache {
+		return
+	}
+
+	// Copy the entries first rather than locking both caches at once, which
+	// could deadlock with a concurrent merge in the other direction.
+	imported := other.liveEntries()
+
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	now := cache.instant()
+	for _, e := range imported {
+		if bucket, ok := cache.cache[e.Key]; ok && conflict != nil && bucket.expiry.After(now) {
+			e = conflict(cache.entry(bucket), e)
+		}
+		ttl := toInstant(e.Expiry).Sub(now)
+		if ttl <= 0 {
+			continue
+		}
+		cache.set(e.Key, e.Value, ttl, ttl)
+	}
+}
+
+// Warm sets many entries at once, each expiring at its Expiry, which is much
+// faster than setting them one by one when loading large datasets: the
+// expiry heap is rebuilt once in linear time instead of being updated for
+// every entry. Entries that already expired are skipped, and later entries
+// win over earlier ones with the same key.
+func (cache *Cache[K, V]) Warm(entries []Entry[K, V]) {
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	if cache.closed {
+		return
+	}
+	cache.flush()
+
+	now := cache.instant()
+	for _, e := range entries {
+		expiry := toInstant(e.Expiry)
+		if !expiry.After(now) {
+			continue
+		}
+		bucket, ok := cache.cache[e.Key]
+		if ok {
+			cache.expireDependents(e.Key)
+			bucket, ok = cache.cache[e.Key]
+		}
+		if ok {
+			bucket = cache.replace(bucket, e.Value)
+		} else {
+			bucket = cache.slab.alloc()
+			bucket.key = e.Key
+			bucket.created = now
+			cache.expireList.elts = append(cache.expireList.elts, bucket)
+			cache.cache[e.Key] = bucket
+			if cache.keyIndex != nil {
+				cache.keyIndex.Insert(e.Key)
+			}
+		}
+
+		cache.store(bucket, e.Value)
+		cache.charge(bucket, e.Value)
+		cache.thaw()
+		bucket.recompute = 0
+		for _, idx := range cache.indexes {
+			idx.update(e.Key, e.Value)
+		}
+		cache.revision++
+		bucket.rev = cache.revision
+		cache.reindexExpiry(bucket, false)
+		bucket.expiry = cache.expireList.round(expiry)
+		bucket.softExpiry = expiry
+		cache.reindexExpiry(bucket, true)
+		cache.stats.TTLs.observe(expiry.Sub(now))
+		if cache.journal != nil {
+			cache.journal.record(false, bucket)
+		}
+
+		cache.wake(e.Key, e.Value)
+		cache.notify(EventSet, e.Key, e.Value)
+	}
+	cache.expireList.Init()
+	cache.rearm()
+	if cache.priorities {
+		cache.prioritize()
+	}
+	if cache.Capacity > 0 {
+		for len(cache.cache) > cache.Capacity && cache.evict() {
+		}
+	}
+	cache.shed()
+}
+
+// Clone returns an independent copy of the cache, holding the same entries
+// with the same expiration times, and the same callbacks and loader.
+// Values are copied by assignment. Watchers, subscriptions, expiry
+// listeners, secondary indexes, the event stream and statistics are not
+// carried over.
+//
+// Clones always keep their values in memory: the values of a cache with a
+// custom backend are loaded into the clone rather than shared through the
+// backend.
+func (cache *Cache[K, V]) Clone() *Cache[K, V] {
+	// Locked for writing, since buckets get copied whole, including their
+	// access statistics, which readers update.
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	clone := &Cache[K, V]{
+		OnExpire:           cache.OnExpire,
+		OnError:            cache.OnError,
+		ExpireOnClose:      cache.ExpireOnClose,
+		Renew:              cache.Renew,
+		TTLFunc:            cache.TTLFunc,
+		Adaptive:           cache.Adaptive,
+		Trace:              cache.Trace,
+		Clock:              cache.Clock,
+		Loader:             cache.Loader,
+		BulkLoader:         cache.BulkLoader,
+		Capacity:           cache.Capacity,
+		EvictionSamples:    cache.EvictionSamples,
+		EarlyExpiration:    cache.EarlyExpiration,
+		SizeFunc:           cache.SizeFunc,
+		TrackAccess:        cache.TrackAccess,
+		SnapshotVersion:    cache.SnapshotVersion,
+		SnapshotMigrations: cache.SnapshotMigrations,
+		ExpiryTolerance:    cache.ExpiryTolerance,
+		MaxCost:            cache.MaxCost,
+		cost:               cache.cost,
+		revision:           cache.revision,
+		seq:                cache.seq,
+		cache:              make(map[K]*cacheBucket[K, V], len(cache.cache)),
+	}
+	// Copying the expire list as-is keeps it a valid heap, and every bucket
+	// at the same index.
+	clone.expireList.elts = make([]*cacheBucket[K, V], len(cache.expireList.elts))
+	clone.slab.reserve(len(cache.expireList.elts))
+	for i, bucket := range cache.expireList.elts {
+		copied := clone.slab.alloc()
+		val := cache.settled(bucket)
+		*copied = *bucket
+		copied.val = val
+		copied.ref = nil
+		copied.guard = nil
+		clone.expireList.elts[i] = copied
+		clone.cache[copied.key] = copied
+	}
+	if cache.expireList.coalesced() {
+		clone.expireList.resolution = cache.expireList.resolution
+		clone.expireList.Init()
+	} else {
+		clone.expireList.exp = append([]instant(nil), cache.expireList.exp...)
+	}
+	if cache.dependents != nil {
+		clone.dependents = make(map[K]map[K]struct{}, len(cache.dependents))
+		for _, bucket := range clone.expireList.elts {
+			deps := bucket.deps
+			bucket.deps = nil
+			for _, dep := range deps {
+				clone.link(bucket, dep)
+			}
+		}
+	}
+	if cache.priorities {
+		clone.prioritize()
+	}
+	if cache.expiries != nil {
+		clone.setExpiryIndex(true)
+	}
+	if cache.keyIndex != nil {
+		clone.setKeyOrder(cache.keyIndex.less)
+		clone.natural = cache.natural
+	}
+	return clone
+}
+
+func (cache *Cache[K, V]) liveEntries() []Entry[K, V] {
+	cache.mux.RLock()
+	defer cache.mux.RUnlock()
+
+	now := cache.instant()
+	entries := make([]Entry[K, V], 0, len(cache.expireList.elts))
+	for _, bucket := range cache.expireList.elts {
+		if bucket.expiry.After(now) {
+			entries = append(entries, cache.entry(bucket))
+		}
+	}
+	return entries
+}
+
+// ExpiringSoon returns the n entries closest to expiry, sorted by ascending
+// expiration time. Entries that expired but were not flushed yet are
+// included.
+func (cache *Cache[K, V]) ExpiringSoon(n int) []Entry[K, V] {
+	cache.mux.RLock()
+	defer cache.mux.RUnlock()
+
+	if n > cache.expireList.Len() {
+		n = cache.expireList.Len()
+	}
+	if n <= 0 {
+		return nil
+	}
+
+	entries := make([]Entry[K, V], 0, n)
+	cache.walkByExpiry(func(bucket *cacheBucket[K, V]) bool {
+		entries = append(entries, cache.entry(bucket))
+		return len(entries) < n
+	})
+	return entries
+}
+
+// RangeByExpiry calls fn for each entry in the cache, by ascending expiration
+// time, until fn returns false. Entries that expired but were not flushed
+// yet are included.
+//
+// Stopping early is cheap: visiting the first n entries takes O(n log n)
+// time regardless of the size of the cache. fn is called with the cache
+// locked, and must not call methods of the cache.
+func (cache *Cache[K, V]) RangeByExpiry(fn func(e Entry[K, V]) bool) {
+	cache.mux.RLock()
+	defer cache.mux.RUnlock()
+
+	cache.walkByExpiry(func(bucket *cacheBucket[K, V]) bool {
+		return fn(cache.entry(bucket))
+	})
+}
+
+// walkByExpiry calls fn for each bucket by ascending expiration time, until
+// fn returns false. cache.mux must be held.
+func (cache *Cache[K, V]) walkByExpiry(fn func(bucket *cacheBucket[K, V]) bool) {
+	if cache.expireList.coalesced() {
+		groups := cache.expireList.groups.elts
+		walkHeap(len(groups), 2, func(i int) instant {
+			return groups[i].expiry
+		}, func(i int) bool {
+			for _, bucket := range groups[i].buckets {
+				if !fn(bucket) {
+					return false
+				}
+			}
+			return true
+		})
+		return
+	}
+
+	elts, exp := cache.expireList.elts, cache.expireList.exp
+	walkHeap(len(elts), expireListArity, func(i int) instant {
+		return exp[i]
+	}, func(i int) bool {
+		return fn(elts[i])
+	})
+}
+
+// walkHeap calls fn for each position of a heap of size n with the specified
+// arity, by ascending expiration time at each position, until fn returns
+// false.
+func walkHeap(n, arity int, expiry func(i int) instant, fn func(i int) bool) {
+	if n == 0 {
+		return
+	}
+
+	// The n soonest entries of a heap form a subtree rooted at its head, so
+	// walk down from the head, always visiting the soonest node seen so far.
+	// The frontier holds positions in the heap, and never touches the heap
+	// itself.
+	var frontier minHeap[int]
+	frontier.push(0, heapKey{at: expiry(0)}, nil)
+	for len(frontier.elts) > 0 {
+		i := frontier.remove(0)
+		if !fn(i) {
+			return
+		}
+		for child := arity*i + 1; child <= arity*(i+1) && child < n; child++ {
+			frontier.push(child, heapKey{at: expiry(child)}, nil)
+		}
+	}
+}
+
+// Sample returns a uniform random sample of up to n distinct keys from the
+// cache, picked without copying the whole keyspace. Sampled keys that turn
+// out to have expired are left out, so fewer than n keys may be returned
+// even if the cache holds more.
+func (cache *Cache[K, V]) Sample(n int) []K {
+	cache.mux.RLock()
+	defer cache.mux.RUnlock()
+
+	elts := cache.expireList.elts
+	if n > len(elts) {
+		n = len(elts)
+	}
+	if n <= 0 {
+		return nil
+	}
+
+	// Floyd's algorithm: picks n distinct positions in O(n).
+	now := cache.instant()
+	keys := make([]K, 0, n)
+	picked := make(map[int]struct{}, n)
+	for j := len(elts) - n; j < len(elts); j++ {
+		i := rand.Intn(j + 1)
+		if _, dup := picked[i]; dup {
+			i = j
+		}
+		picked[i] = struct{}{}
+		if bucket := elts[i]; bucket.expiry.After(now) {
+			keys = append(keys, bucket.key)
+		}
+	}
+	return keys
+}
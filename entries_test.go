@@ -0,0 +1,189 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestExpiringSoon(t *testing.T) {
+	c := New[int, int]()
+	for _, i := range rand.Perm(100) {
+		c.Set(i, i, time.Duration(i+1)*time.Hour)
+	}
+
+	if entries := c.ExpiringSoon(0); len(entries) != 0 {
+		t.Fatalf("expected no entries, got %v", entries)
+	}
+	entries := c.ExpiringSoon(10)
+	if len(entries) != 10 {
+		t.Fatalf("expected 10 entries, got %v", len(entries))
+	}
+	for i, e := range entries {
+		if e.Key != i || e.Value != i {
+			t.Fatalf("expected entry %d to be key %d, got %v", i, i, e)
+		}
+	}
+	if entries := c.ExpiringSoon(1000); len(entries) != 100 {
+		t.Fatalf("expected all 100 entries, got %v", len(entries))
+	}
+}
+
+func TestRangeByExpiry(t *testing.T) {
+	c := New[int, int]()
+	for _, i := range rand.Perm(100) {
+		c.Set(i, i, time.Duration(i+1)*time.Minute)
+	}
+
+	next := 0
+	c.RangeByExpiry(func(e Entry[int, int]) bool {
+		if e.Key != next {
+			t.Fatalf("expected entry %d to be key %d, got %v", next, next, e)
+		}
+		next++
+		return true
+	})
+	if next != 100 {
+		t.Fatalf("expected 100 entries, got %v", next)
+	}
+
+	next = 0
+	c.RangeByExpiry(func(e Entry[int, int]) bool {
+		next++
+		return next < 5
+	})
+	if next != 5 {
+		t.Fatalf("expected iteration to stop after 5 entries, got %v", next)
+	}
+}
+
+func TestSample(t *testing.T) {
+	c := New[int, int]()
+	for i := 0; i < 100; i++ {
+		c.Set(i, i, time.Hour)
+	}
+
+	keys := c.Sample(10)
+	if len(keys) != 10 {
+		t.Fatalf("expected 10 keys, got %v", keys)
+	}
+	seen := make(map[int]bool)
+	for _, k := range keys {
+		if seen[k] {
+			t.Fatalf("expected distinct keys, got %v", keys)
+		}
+		seen[k] = true
+	}
+	if keys := c.Sample(1000); len(keys) != 100 {
+		t.Fatalf("expected all 100 keys, got %v", len(keys))
+	}
+
+	c.Set(100, 100, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	for _, k := range c.Sample(101) {
+		if k == 100 {
+			t.Fatal("expected expired key not to be sampled")
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	a := New[string, int]()
+	a.Set("foo", 1, time.Hour)
+	a.Set("bar", 2, time.Hour)
+
+	b := New[string, int]()
+	b.Set("bar", 3, time.Hour)
+	b.Set("baz", 4, time.Minute)
+	b.Set("expired", 5, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	a.Merge(b, func(current, imported Entry[string, int]) Entry[string, int] {
+		if current.Value > imported.Value {
+			return current
+		}
+		return imported
+	})
+	a.Merge(a, nil)
+
+	expected := map[string]int{"foo": 1, "bar": 3, "baz": 4}
+	for k, v := range expected {
+		if got, ok := a.Get(k); !ok || got != v {
+			t.Fatalf("expected %v to be %v, got %v (found: %v)", k, v, got, ok)
+		}
+	}
+	if _, ok := a.Get("expired"); ok {
+		t.Fatal("expected expired entries not to be merged")
+	}
+	if e := a.ExpiringSoon(1); e[0].Key != "baz" || time.Until(e[0].Expiry) > time.Minute {
+		t.Fatalf("expected baz to keep its remaining TTL, got %v", e)
+	}
+}
+
+func TestClone(t *testing.T) {
+	c := New[string, int]()
+	c.Set("foo", 1, time.Hour)
+	c.Set("bar", 2, time.Minute)
+
+	clone := c.Clone()
+	c.Set("foo", 3, time.Hour)
+	clone.Expire("bar")
+	clone.Set("baz", 4, time.Hour)
+
+	if v, ok := clone.Get("foo"); !ok || v != 1 {
+		t.Fatalf("expected clone to keep foo at 1, got %v", v)
+	}
+	if _, ok := c.Get("bar"); !ok {
+		t.Fatal("expected expiring bar in the clone not to affect the original")
+	}
+	if _, ok := c.Get("baz"); ok {
+		t.Fatal("expected setting baz in the clone not to affect the original")
+	}
+
+	a, b := c.ExpiringSoon(1)[0], c.Clone().ExpiringSoon(1)[0]
+	if a != b {
+		t.Fatalf("expected clone to keep expiration times, got %v and %v", a, b)
+	}
+
+	c.EarlyExpiration = 1
+	if clone := c.Clone(); clone.EarlyExpiration != 1 {
+		t.Fatalf("expected clone to keep EarlyExpiration, got %v", clone.EarlyExpiration)
+	}
+}
+
+func TestWarm(t *testing.T) {
+	c := New[int, int]()
+	c.Set(0, -1, time.Hour)
+
+	now := time.Now()
+	entries := make([]Entry[int, int], 0, 101)
+	for _, i := range rand.Perm(100) {
+		entries = append(entries, Entry[int, int]{Key: i, Value: i, Expiry: now.Add(time.Duration(i+1) * time.Minute)})
+	}
+	entries = append(entries, Entry[int, int]{Key: 100, Value: 100, Expiry: now.Add(-time.Minute)})
+	c.Warm(entries)
+
+	if len(c.cache) != 100 {
+		t.Fatalf("expected 100 keys, got %d", len(c.cache))
+	}
+	if v, _ := c.Get(0); v != 0 {
+		t.Fatalf("expected warmed entries to replace existing ones, got %v", v)
+	}
+	if _, ok := c.Get(100); ok {
+		t.Fatal("expected expired entries to be skipped")
+	}
+	var i int
+	c.RangeByExpiry(func(e Entry[int, int]) bool {
+		if e.Key != i {
+			t.Fatalf("expected key %d to expire next, got %d", i, e.Key)
+		}
+		i++
+		return true
+	})
+}
@@ -0,0 +1,291 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"path"
+	"strings"
+	"sync/atomic"
+)
+
+// EventKind describes what happened to a cache entry.
+type EventKind int
+
+const (
+	// EventSet is emitted whenever a value is assigned to a key.
+	EventSet EventKind = iota
+	// EventExpire is emitted when a key is flushed after its expiration time.
+	EventExpire
+	// EventDelete is emitted when a key is explicitly expired with Expire
+	// before its expiration time.
+	EventDelete
+	// EventHit is emitted when Get finds a value for a key. It is only
+	// delivered to the event stream.
+	EventHit
+	// EventMiss is emitted when Get finds no value for a key. It is only
+	// delivered to the event stream.
+	EventMiss
+	// EventEvict is emitted when a key is removed before its expiration
+	// time to make room, for instance by EvictN.
+	EventEvict
+)
+
+func (kind EventKind) String() string {
+	switch kind {
+	case EventSet:
+		return "set"
+	case EventExpire:
+		return "expire"
+	case EventDelete:
+		return "delete"
+	case EventHit:
+		return "hit"
+	case EventMiss:
+		return "miss"
+	case EventEvict:
+		return "evict"
+	default:
+		return "unknown"
+	}
+}
+
+// Event describes a change to a cache entry.
+type Event[K, V any] struct {
+	Kind  EventKind
+	Key   K
+	Value V
+}
+
+// watchBuffer is the number of events a watcher can lag behind before
+// events start being dropped.
+const watchBuffer = 16
+
+// Watch returns a channel receiving events for the specified key, as well as
+// a function to stop watching, which closes the channel. The channel is also
+// closed when the cache is closed.
+//
+// Events are delivered without blocking the cache: if the receiver falls
+// more than a few events behind, further events are dropped until it
+// catches up.
+func (cache *Cache[K, V]) Watch(key K) (events <-chan Event[K, V], cancel func()) {
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	if cache.closed {
+		return closedEvents[K, V](), func() {}
+	}
+	if cache.watchers == nil {
+		cache.watchers = make(map[K][]chan Event[K, V])
+	}
+	ch := make(chan Event[K, V], watchBuffer)
+	cache.watchers[key] = append(cache.watchers[key], ch)
+
+	var cancelled bool
+	cancel = func() {
+		cache.mux.Lock()
+		defer cache.mux.Unlock()
+
+		if cancelled || cache.closed {
+			return
+		}
+		cancelled = true
+
+		watchers := cache.watchers[key]
+		for i, w := range watchers {
+			if w == ch {
+				watchers = append(watchers[:i], watchers[i+1:]...)
+				break
+			}
+		}
+		if len(watchers) == 0 {
+			delete(cache.watchers, key)
+		} else {
+			cache.watchers[key] = watchers
+		}
+		close(ch)
+	}
+	return ch, cancel
+}
+
+type subscription[K, V any] struct {
+	match func(key K) bool
+	kinds uint
+	ch    chan Event[K, V]
+}
+
+// Subscribe returns a channel receiving the events of the specified kinds
+// for all keys matching the specified predicate, as well as a function to
+// unsubscribe, which closes the channel, as does closing the cache. If no
+// kinds are specified, events of all kinds are delivered, save for hits and
+// misses.
+//
+// The predicate is called with the cache locked for every change happening
+// in the cache, and should be cheap. Events are delivered like for Watch.
+func (cache *Cache[K, V]) Subscribe(match func(key K) bool, kinds ...EventKind) (events <-chan Event[K, V], cancel func()) {
+	sub := &subscription[K, V]{
+		match: match,
+		ch:    make(chan Event[K, V], watchBuffer),
+	}
+	for _, kind := range kinds {
+		sub.kinds |= 1 << kind
+	}
+	if sub.kinds == 0 {
+		sub.kinds = ^uint(0)
+	}
+
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	if cache.closed {
+		return closedEvents[K, V](), func() {}
+	}
+	cache.subs = append(cache.subs, sub)
+
+	var cancelled bool
+	cancel = func() {
+		cache.mux.Lock()
+		defer cache.mux.Unlock()
+
+		if cancelled || cache.closed {
+			return
+		}
+		cancelled = true
+
+		for i, s := range cache.subs {
+			if s == sub {
+				cache.subs = append(cache.subs[:i], cache.subs[i+1:]...)
+				break
+			}
+		}
+		close(sub.ch)
+	}
+	return sub.ch, cancel
+}
+
+type expireListener[K, V any] struct {
+	match func(key K) bool
+	fn    func(key K, value V)
+}
+
+// OnExpireFunc registers fn to be called like OnExpire, but only for the keys
+// matching the specified predicate, and returns a function unregistering
+// it. Any number of listeners can be registered, letting different parts of
+// a program each handle their own keys.
+//
+// Both match and fn are called with the cache locked, like OnExpire.
+func (cache *Cache[K, V]) OnExpireFunc(match func(key K) bool, fn func(key K, value V)) (remove func()) {
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	l := &expireListener[K, V]{match: match, fn: fn}
+	cache.listeners = append(cache.listeners, l)
+
+	return func() {
+		cache.mux.Lock()
+		defer cache.mux.Unlock()
+
+		for i, other := range cache.listeners {
+			if other == l {
+				cache.listeners = append(cache.listeners[:i], cache.listeners[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// MatchPrefix returns a predicate matching string keys starting with the
+// specified prefix, for use with Subscribe or OnExpireFunc.
+func MatchPrefix(prefix string) func(key string) bool {
+	return func(key string) bool {
+		return strings.HasPrefix(key, prefix)
+	}
+}
+
+// MatchGlob returns a predicate matching string keys against the specified
+// shell pattern, as implemented by path.Match, for use with Subscribe or
+// OnExpireFunc.
+// Malformed patterns match nothing.
+func MatchGlob(pattern string) func(key string) bool {
+	return func(key string) bool {
+		ok, _ := path.Match(pattern, key)
+		return ok
+	}
+}
+
+// Events returns a channel receiving every event happening in the cache,
+// enabling the event stream on first use with room for buffer events.
+// Subsequent calls return the same channel and ignore buffer.
+//
+// Events are delivered without blocking the cache: whenever the buffer is
+// full, new events are dropped and counted in DroppedEvents. Consumers that
+// cannot afford to lose events should size the buffer for their bursts and
+// monitor that counter.
+func (cache *Cache[K, V]) Events(buffer int) <-chan Event[K, V] {
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	if cache.closed {
+		return closedEvents[K, V]()
+	}
+	if cache.events == nil {
+		cache.events = make(chan Event[K, V], buffer)
+	}
+	return cache.events
+}
+
+// DroppedEvents returns the number of events that were dropped so far
+// because the event stream was full.
+func (cache *Cache[K, V]) DroppedEvents() uint64 {
+	return atomic.LoadUint64(&cache.droppedEvents)
+}
+
+func closedEvents[K, V any]() chan Event[K, V] {
+	ch := make(chan Event[K, V])
+	close(ch)
+	return ch
+}
+
+// notify delivers an event to the event stream, the watchers of key, and
+// matching subscriptions. cache.mux must be held for writing.
+func (cache *Cache[K, V]) notify(kind EventKind, key K, value V) {
+	ev := Event[K, V]{Kind: kind, Key: key, Value: value}
+	cache.emit(ev)
+
+	for _, ch := range cache.watchers[key] {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+	for _, sub := range cache.subs {
+		if sub.kinds&(1<<kind) == 0 {
+			continue
+		}
+		var match bool
+		cache.guard("Subscribe", func() { match = sub.match(key) })
+		if !match {
+			continue
+		}
+		select {
+		case sub.ch <- ev:
+		default:
+		}
+	}
+}
+
+// emit delivers an event to the event stream, if enabled. cache.mux must be
+// held, but may be held for reading only.
+func (cache *Cache[K, V]) emit(ev Event[K, V]) {
+	if cache.events == nil {
+		return
+	}
+	select {
+	case cache.events <- ev:
+	default:
+		atomic.AddUint64(&cache.droppedEvents, 1)
+	}
+}
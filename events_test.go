@@ -0,0 +1,137 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	c := New[string, int]()
+	events, cancel := c.Watch("foo")
+
+	c.Set("foo", 1, time.Nanosecond)
+	c.Set("bar", 2, time.Hour) // flushes foo
+	c.Set("foo", 3, time.Hour)
+	c.Expire("foo")
+	cancel()
+
+	expected := []Event[string, int]{
+		{Kind: EventSet, Key: "foo", Value: 1},
+		{Kind: EventExpire, Key: "foo", Value: 1},
+		{Kind: EventSet, Key: "foo", Value: 3},
+		{Kind: EventDelete, Key: "foo", Value: 3},
+	}
+	var got []Event[string, int]
+	for ev := range events {
+		got = append(got, ev)
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("expected events %v, got %v", expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Fatalf("expected events %v, got %v", expected, got)
+		}
+	}
+
+	cancel() // must be idempotent
+	if len(c.watchers) != 0 {
+		t.Fatalf("expected watcher on foo to be removed, got %v", c.watchers)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	c := New[string, int]()
+	events := c.Events(4)
+	if c.Events(100) != events {
+		t.Fatal("expected Events to always return the same channel")
+	}
+
+	c.Set("foo", 1, time.Hour)
+	c.Get("foo")
+	c.Get("bar")
+	c.Expire("foo")
+	c.Get("foo") // dropped
+
+	expected := []EventKind{EventSet, EventHit, EventMiss, EventDelete}
+	for _, kind := range expected {
+		ev := <-events
+		if ev.Kind != kind {
+			t.Fatalf("expected %v event, got %v", kind, ev)
+		}
+	}
+	if dropped := c.DroppedEvents(); dropped != 1 {
+		t.Fatalf("expected 1 dropped event, got %v", dropped)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	c := New[string, int]()
+	events, cancel := c.Subscribe(MatchGlob("user:*"), EventDelete, EventExpire)
+
+	c.Set("user:1", 1, time.Nanosecond)
+	c.Set("user:2", 2, time.Hour) // flushes user:1
+	c.Set("group:1", 3, time.Hour)
+	c.Expire("group:1")
+	c.Expire("user:2")
+	cancel()
+	cancel()
+
+	expected := []Event[string, int]{
+		{Kind: EventExpire, Key: "user:1", Value: 1},
+		{Kind: EventDelete, Key: "user:2", Value: 2},
+	}
+	var got []Event[string, int]
+	for ev := range events {
+		got = append(got, ev)
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("expected events %v, got %v", expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Fatalf("expected events %v, got %v", expected, got)
+		}
+	}
+	if len(c.subs) != 0 {
+		t.Fatalf("expected subscription to be removed, got %v", c.subs)
+	}
+}
+
+func TestOnExpireFunc(t *testing.T) {
+	c := New[string, int]()
+	var users, groups []string
+	removeUsers := c.OnExpireFunc(MatchPrefix("user:"), func(key string, value int) {
+		users = append(users, key)
+	})
+	c.OnExpireFunc(MatchPrefix("group:"), func(key string, value int) {
+		groups = append(groups, key)
+	})
+
+	c.Set("user:1", 1, time.Hour)
+	c.Set("group:1", 2, time.Hour)
+	c.Set("other", 3, time.Hour)
+	c.Expire("user:1")
+	c.Expire("group:1")
+	c.Expire("other")
+
+	if len(users) != 1 || users[0] != "user:1" {
+		t.Fatalf("expected user listener to only get user:1, got %v", users)
+	}
+	if len(groups) != 1 || groups[0] != "group:1" {
+		t.Fatalf("expected group listener to only get group:1, got %v", groups)
+	}
+
+	removeUsers()
+	c.Set("user:2", 4, time.Hour)
+	c.Expire("user:2")
+	if len(users) != 1 {
+		t.Fatalf("expected removed listener not to be called, got %v", users)
+	}
+}
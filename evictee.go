@@ -0,0 +1,138 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import "reflect"
+
+// EvictReason tells why a value left the cache.
+type EvictReason int
+
+const (
+	// ReasonExpired is given when a key is flushed after its expiration
+	// time.
+	ReasonExpired EvictReason = iota
+	// ReasonDeleted is given when a key is explicitly expired.
+	ReasonDeleted
+	// ReasonEvicted is given when a key is removed to make room.
+	ReasonEvicted
+	// ReasonReplaced is given when a key is set to a different value.
+	ReasonReplaced
+	// ReasonClosed is given when the cache is closed.
+	ReasonClosed
+)
+
+func (reason EvictReason) String() string {
+	switch reason {
+	case ReasonExpired:
+		return "expired"
+	case ReasonDeleted:
+		return "deleted"
+	case ReasonEvicted:
+		return "evicted"
+	case ReasonReplaced:
+		return "replaced"
+	case ReasonClosed:
+		return "closed"
+	default:
+		return "unknown"
+	}
+}
+
+// Evictee is implemented by values owning resources that must be released
+// once they leave the cache. OnEvicted gets called with the cache locked
+// every time such a value leaves it, whether OnExpire is set or not: when
+// its key expires, is expired, evicted, or set to another value, and when
+// the cache is closed, unless the value is kept in a custom backend.
+//
+// Values shared by several caches, like after Clone or Merge, are notified
+// by each of them.
+type Evictee interface {
+	OnEvicted(reason EvictReason)
+}
+
+// evicted notifies value, if it is an Evictee, that it left the cache.
+// cache.mux must be held for writing.
+func (cache *Cache[K, V]) evicted(value V, reason EvictReason) {
+	if !cache.valuesMay(valueIfacesEvictee) {
+		return
+	}
+	if e, ok := any(value).(Evictee); ok {
+		cache.guard("OnEvicted", func() { e.OnEvicted(reason) })
+	}
+}
+
+// replace notifies the current value of bucket, if it is an Evictee, that
+// value replaces it, once it is no longer referenced. It must be called
+// before storing a new value in any bucket, and returns the bucket to store
+// it in. cache.mux must be held for writing.
+func (cache *Cache[K, V]) replace(bucket *cacheBucket[K, V], value V) *cacheBucket[K, V] {
+	if bucket.lockOut() {
+		// WithEntry is still modifying the old value.
+		fresh := cache.takeOver(bucket)
+		bucket.postpone(func() { cache.replaced(bucket, value) })
+		return fresh
+	}
+	cache.replaced(bucket, value)
+	return bucket
+}
+
+// replaced notifies the value of bucket, if it is an Evictee, that value
+// replaces it. cache.mux must be held for writing.
+func (cache *Cache[K, V]) replaced(bucket *cacheBucket[K, V], value V) {
+	ref := bucket.ref
+	if ref == nil && !cache.valuesMay(valueIfacesEvictee) {
+		return
+	}
+	old, ok := cache.load(bucket)
+	if !ok || sameValue(old, value) {
+		return
+	}
+	// References are to the old value, not to the key.
+	bucket.ref = nil
+	if ref == nil || ref.count == 0 {
+		cache.evicted(old, ReasonReplaced)
+	} else if cache.valuesMay(valueIfacesEvictee) {
+		ref.cleanup = func() { cache.evicted(old, ReasonReplaced) }
+	}
+}
+
+// evictReason returns the reason to give to Evictee values for a removal
+// of the specified kind.
+func (cache *Cache[K, V]) evictReason(kind EventKind) EvictReason {
+	switch {
+	case cache.closed:
+		return ReasonClosed
+	case kind == EventExpire:
+		return ReasonExpired
+	case kind == EventEvict:
+		return ReasonEvicted
+	default:
+		return ReasonDeleted
+	}
+}
+
+// sameValue reports whether a and b are known to be the same value, like
+// the same pointer set again.
+func sameValue(a, b any) (same bool) {
+	t := reflect.TypeOf(a)
+	if t != reflect.TypeOf(b) {
+		return false
+	}
+	if t == nil {
+		return true
+	}
+	if !t.Comparable() {
+		return false
+	}
+	// Comparable structs may still hold incomparable values in interfaces.
+	defer func() {
+		if recover() != nil {
+			same = false
+		}
+	}()
+	return a == b
+}
@@ -0,0 +1,84 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testResource struct {
+	reasons []EvictReason
+}
+
+func (r *testResource) OnEvicted(reason EvictReason) {
+	r.reasons = append(r.reasons, reason)
+}
+
+func expectReasons(t *testing.T, name string, r *testResource, want ...EvictReason) {
+	t.Helper()
+	if !reflect.DeepEqual(r.reasons, want) {
+		t.Fatalf("expected %s to be notified with %v, got %v", name, want, r.reasons)
+	}
+}
+
+func TestEvictee(t *testing.T) {
+	now := time.Now()
+	c := New[string, *testResource]()
+	c.Clock = clockFunc(func() time.Time { return now })
+	c.Capacity = 2
+
+	expired, deleted, evicted, kept := &testResource{}, &testResource{}, &testResource{}, &testResource{}
+	c.Set("expired", expired, time.Second)
+	c.Set("deleted", deleted, time.Hour)
+	now = now.Add(time.Minute)
+	c.Get("expired")
+	c.Expire("deleted")
+
+	c.Set("evicted", evicted, time.Minute)
+	c.Set("kept", kept, time.Hour)
+	c.Set("other", &testResource{}, time.Hour)
+
+	expectReasons(t, "expired", expired, ReasonExpired)
+	expectReasons(t, "deleted", deleted, ReasonDeleted)
+	expectReasons(t, "evicted", evicted, ReasonEvicted)
+	expectReasons(t, "kept", kept)
+
+	replacement := &testResource{}
+	c.Set("kept", kept, time.Hour)
+	expectReasons(t, "kept", kept)
+	c.Set("kept", replacement, time.Hour)
+	expectReasons(t, "kept", kept, ReasonReplaced)
+
+	c.Close(context.Background())
+	expectReasons(t, "replacement", replacement, ReasonClosed)
+}
+
+func TestEvicteeInterface(t *testing.T) {
+	c := New[string, any]()
+	r := &testResource{}
+	c.Set("resource", r, time.Hour)
+	c.Set("resource", 42, time.Hour)
+	c.Set("resource", []int{1}, time.Hour)
+	c.Set("resource", []int{1}, time.Hour)
+	expectReasons(t, "resource", r, ReasonReplaced)
+
+	c.ExpireOnClose = true
+	c.Set("resource", r, time.Hour)
+	c.Close(context.Background())
+	expectReasons(t, "resource", r, ReasonReplaced, ReasonClosed)
+}
+
+func TestEvicteeAllocs(t *testing.T) {
+	c := New[string, string]()
+	c.Set("foo", "bar", time.Hour)
+	if allocs := testing.AllocsPerRun(100, func() { c.Set("foo", "baz", time.Hour) }); allocs != 0 {
+		t.Fatalf("expected values that are not evictees not to be boxed, got %v allocations", allocs)
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"time"
+)
+
+// SetExpiryIndex makes the cache maintain an index of its entries ordered by
+// expiration time, alongside its expiry heap, or drops it. The index makes
+// RangeExpiry and ExpireRange take time proportional to the entries in the
+// range rather than to the entries expiring before it, at the price of
+// slower insertions and removals.
+func (cache *Cache[K, V]) SetExpiryIndex(enabled bool) {
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	cache.setExpiryIndex(enabled)
+}
+
+func (cache *Cache[K, V]) setExpiryIndex(enabled bool) {
+	if !enabled {
+		cache.expiries = nil
+		return
+	}
+	cache.expiries = newSkiplist(func(a, b *cacheBucket[K, V]) bool {
+		if a.expiry != b.expiry {
+			return a.expiry < b.expiry
+		}
+		return a.seq < b.seq
+	})
+	for _, bucket := range cache.expireList.elts {
+		cache.seq++
+		bucket.seq = cache.seq
+		cache.expiries.Insert(bucket)
+	}
+}
+
+// RangeExpiry calls fn for each entry in the cache expiring within [from,
+// to), by ascending expiration time, until fn returns false. Entries that
+// expired but were not flushed yet are included if they are in the range.
+//
+// For instance, the entries expiring in the next five minutes are the ones
+// within [now, now+5m). fn is called with the cache locked, and must not
+// call methods of the cache.
+func (cache *Cache[K, V]) RangeExpiry(from, to time.Time, fn func(e Entry[K, V]) bool) {
+	cache.mux.RLock()
+	defer cache.mux.RUnlock()
+
+	cache.rangeExpiry(from, to, func(bucket *cacheBucket[K, V]) bool {
+		return fn(cache.entry(bucket))
+	})
+}
+
+// ExpireRange expires all the entries expiring within [from, to), and
+// returns how many were expired.
+func (cache *Cache[K, V]) ExpireRange(from, to time.Time) int {
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	var matched []*cacheBucket[K, V]
+	cache.rangeExpiry(from, to, func(bucket *cacheBucket[K, V]) bool {
+		matched = append(matched, bucket)
+		return true
+	})
+	return cache.deleteAll(matched, EventDelete)
+}
+
+func (cache *Cache[K, V]) rangeExpiry(fromTime, toTime time.Time, fn func(bucket *cacheBucket[K, V]) bool) {
+	from, to := toInstant(fromTime), toInstant(toTime)
+	if cache.expiries == nil {
+		cache.walkByExpiry(func(bucket *cacheBucket[K, V]) bool {
+			if !bucket.expiry.Before(to) {
+				return false
+			}
+			return bucket.expiry.Before(from) || fn(bucket)
+		})
+		return
+	}
+
+	for n := cache.expiries.Seek(&cacheBucket[K, V]{expiry: from}); n != nil; n = n.Next() {
+		if !n.val.expiry.Before(to) || !fn(n.val) {
+			return
+		}
+	}
+}
+
+// reindexExpiry must be called around changes to the expiration time of
+// bucket: once before with insert false, and once after with insert true.
+// cache.mux must be held for writing.
+func (cache *Cache[K, V]) reindexExpiry(bucket *cacheBucket[K, V], insert bool) {
+	if cache.expiries == nil {
+		return
+	}
+	if !insert {
+		cache.expiries.Remove(bucket)
+		return
+	}
+	cache.seq++
+	bucket.seq = cache.seq
+	cache.expiries.Insert(bucket)
+}
@@ -0,0 +1,65 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRangeExpiry(t *testing.T) {
+	for _, indexed := range []bool{false, true} {
+		now := time.Now()
+		c := New[int, int]()
+		c.Clock = fixedClock(now)
+		c.SetExpiryIndex(indexed)
+		for i := 0; i < 20; i++ {
+			c.Set(i, i, time.Duration(i%10+1)*time.Minute)
+		}
+		// Moving a key around must keep the index up to date.
+		c.Set(19, 19, time.Hour)
+
+		var keys []int
+		c.RangeExpiry(now, now.Add(5*time.Minute), func(e Entry[int, int]) bool {
+			keys = append(keys, e.Key)
+			return true
+		})
+		if len(keys) != 8 {
+			t.Fatalf("indexed=%v: expected 8 keys expiring within 5 minutes, got %v", indexed, keys)
+		}
+		for i := 1; i < len(keys); i++ {
+			if keys[i]%10 < keys[i-1]%10 {
+				t.Fatalf("indexed=%v: expected keys by ascending expiry, got %v", indexed, keys)
+			}
+		}
+
+		if n := c.ExpireRange(now.Add(3*time.Minute), now.Add(8*time.Minute)); n != 10 {
+			t.Fatalf("indexed=%v: expected 10 keys to be expired, got %d", indexed, n)
+		}
+		for i := 0; i < 20; i++ {
+			d := i%10 + 1
+			if _, ok := c.Get(i); ok != (d < 3 || d >= 8 || i == 19) {
+				t.Fatalf("indexed=%v: unexpected presence of key %d: %v", indexed, i, ok)
+			}
+		}
+		if indexed && c.expiries.Len() != len(c.cache) {
+			t.Fatalf("expected the index to hold %d entries, got %d", len(c.cache), c.expiries.Len())
+		}
+	}
+}
+
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time {
+	return time.Time(c)
+}
+
+type clockFunc func() time.Time
+
+func (f clockFunc) Now() time.Time {
+	return f()
+}
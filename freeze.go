@@ -0,0 +1,83 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import "math"
+
+// frozenView is the view published by Freeze, valid until the first of its
+// entries expires.
+type frozenView[K comparable, V any] struct {
+	snapshot *Snapshot[K, V]
+	until    instant
+}
+
+// Freeze returns an immutable view of the live entries of the cache, like
+// Snapshot. Unlike snapshots, the view gets published: until the cache
+// changes or one of the entries in the view expires, Freeze keeps returning
+// the same view without copying the cache again nor taking any lock. This
+// makes it cheap for every request of a server to work on a consistent state
+// of the cache, doing any number of lookups without contending with writers.
+//
+// Values are shared with the cache by assignment, like in snapshots; values
+// holding pointers must not be modified through the view.
+func (cache *Cache[K, V]) Freeze() *Snapshot[K, V] {
+	if view := cache.frozenView(); view != nil {
+		return view.snapshot
+	}
+
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	// Another call may have published a view in the meantime.
+	if view := cache.frozenView(); view != nil {
+		return view.snapshot
+	}
+
+	t := cache.now()
+	now := toInstant(t)
+	until := instant(math.MaxInt64)
+	entries := make([]Entry[K, V], 0, len(cache.expireList.elts))
+	index := make(map[K]int, len(cache.expireList.elts))
+	for _, bucket := range cache.expireList.elts {
+		if !bucket.expiry.After(now) {
+			continue
+		}
+		if bucket.expiry.Before(until) {
+			until = bucket.expiry
+		}
+		index[bucket.key] = len(entries)
+		entries = append(entries, cache.entry(bucket))
+	}
+
+	view := &frozenView[K, V]{
+		snapshot: &Snapshot[K, V]{time: t, version: cache.SnapshotVersion, entries: entries, index: index},
+		until:    until,
+	}
+	if !cache.closed {
+		cache.view.Store(view)
+		cache.frozen = true
+	}
+	return view.snapshot
+}
+
+// frozenView returns the view published by Freeze, if it is still valid.
+func (cache *Cache[K, V]) frozenView() *frozenView[K, V] {
+	view, _ := cache.view.Load().(*frozenView[K, V])
+	if view == nil || !cache.instant().Before(view.until) {
+		return nil
+	}
+	return view
+}
+
+// thaw withdraws the view published by Freeze, if any, after a change to the
+// cache. cache.mux must be held for writing.
+func (cache *Cache[K, V]) thaw() {
+	if cache.frozen {
+		cache.frozen = false
+		cache.view.Store((*frozenView[K, V])(nil))
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFreeze(t *testing.T) {
+	now := time.Now()
+	c := New[string, int]()
+	c.Clock = clockFunc(func() time.Time { return now })
+	c.Set("foo", 1, time.Hour)
+	c.Set("bar", 2, time.Minute)
+
+	view := c.Freeze()
+	if v, ok := view.Get("foo"); !ok || v != 1 {
+		t.Fatalf("expected foo to be frozen at 1, got %v, %v", v, ok)
+	}
+	if c.Freeze() != view {
+		t.Fatal("expected the view to be reused while the cache is unchanged")
+	}
+
+	c.Set("foo", 3, time.Hour)
+	if v, _ := view.Get("foo"); v != 1 {
+		t.Fatalf("expected the view to stay unchanged, got %v", v)
+	}
+	view = c.Freeze()
+	if v, _ := view.Get("foo"); v != 3 {
+		t.Fatalf("expected a new view after a change, got %v", v)
+	}
+
+	now = now.Add(time.Minute)
+	if next := c.Freeze(); next == view || next.Len() != 1 {
+		t.Fatalf("expected a new view without bar once it expired, got %d entries", next.Len())
+	}
+
+	c.Expire("foo")
+	if n := c.Freeze().Len(); n != 0 {
+		t.Fatalf("expected an empty view after expiring foo, got %d entries", n)
+	}
+}
+
+func TestFreezeClosed(t *testing.T) {
+	c := New[string, int]()
+	c.Set("foo", 1, time.Hour)
+	c.Freeze()
+	c.Close(context.Background())
+	if n := c.Freeze().Len(); n != 0 {
+		t.Fatalf("expected a closed cache to freeze empty, got %d entries", n)
+	}
+}
+
+func TestFreezeConcurrent(t *testing.T) {
+	c := New[int, int]()
+	for i := 0; i < 100; i++ {
+		c.Set(i, i, time.Hour)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				view := c.Freeze()
+				if v, ok := view.Get(j % 100); ok && v < j%100 {
+					t.Errorf("expected %d to never go back, got %d", j%100, v)
+				}
+			}
+		}()
+	}
+	for j := 0; j < 1000; j++ {
+		c.Set(j%100, j, time.Hour)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,104 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+)
+
+// ErrHandover is returned when the other side of a handover hangs up before
+// it completes.
+var ErrHandover = errors.New("ttlcache: handover interrupted")
+
+// HandOver waits for a successor process to connect to l, typically a unix
+// socket, and sends it a snapshot of the live entries of the cache, so that
+// restarts don't begin with a cold cache. It returns once the successor
+// confirms having restored the snapshot with TakeOver, and closes l, which
+// also happens if ctx is done first.
+//
+// l may be inherited from a service manager, as with systemd socket
+// activation, through net.FileListener. Entries set after the snapshot is
+// taken are not handed over, so the process should stop serving traffic,
+// or at least writes, before calling HandOver.
+func (cache *Cache[K, V]) HandOver(ctx context.Context, l net.Listener) error {
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-stop:
+		}
+		l.Close()
+	}()
+
+	conn, err := l.Accept()
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	defer conn.Close()
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-stop:
+		}
+	}()
+
+	if _, err := cache.Snapshot().WriteTo(conn); err != nil {
+		return handoverErr(ctx, err)
+	}
+	var ack [1]byte
+	if _, err := io.ReadFull(conn, ack[:]); err != nil {
+		return handoverErr(ctx, err)
+	}
+	return nil
+}
+
+// TakeOver connects to the predecessor process at the specified address,
+// where it is expected to be calling HandOver, and restores the snapshot it
+// sends. See Restore.
+func (cache *Cache[K, V]) TakeOver(ctx context.Context, network, address string) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-stop:
+		}
+	}()
+
+	if err := cache.Restore(conn); err != nil {
+		return handoverErr(ctx, err)
+	}
+	if _, err := conn.Write([]byte{0}); err != nil {
+		return handoverErr(ctx, err)
+	}
+	return nil
+}
+
+func handoverErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return ErrHandover
+	}
+	return err
+}
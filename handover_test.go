@@ -0,0 +1,88 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func listenHandover(t *testing.T) (net.Listener, string) {
+	// Unix socket paths are short-lived and limited in length, which rules
+	// out t.TempDir on some systems.
+	dir, err := os.MkdirTemp("", "ttlcache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "handover.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	return l, path
+}
+
+func TestHandOver(t *testing.T) {
+	l, path := listenHandover(t)
+
+	old := New[string, int]()
+	old.Set("foo", 1, time.Hour)
+	old.Set("bar", 2, time.Minute)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- old.HandOver(ctx, l)
+	}()
+
+	successor := New[string, int]()
+	if err := successor.TakeOver(ctx, "unix", path); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]int{"foo": 1, "bar": 2} {
+		if v, ok := successor.Get(key); !ok || v != want {
+			t.Fatalf("expected %q to be handed over, got %v, %v", key, v, ok)
+		}
+	}
+	if soon := successor.ExpiringSoon(1); len(soon) != 1 || soon[0].Key != "bar" {
+		t.Fatalf("expected expiration times to be handed over, got %v", soon)
+	}
+}
+
+func TestHandOverCancel(t *testing.T) {
+	l, _ := listenHandover(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := New[string, int]().HandOver(ctx, l); err != context.Canceled {
+		t.Fatalf("expected HandOver to stop with ctx, got %v", err)
+	}
+}
+
+func TestTakeOverInterrupted(t *testing.T) {
+	l, path := listenHandover(t)
+	defer l.Close()
+	go func() {
+		if conn, err := l.Accept(); err == nil {
+			conn.Write([]byte(snapshotMagic + "\x01\x00"))
+			conn.Close()
+		}
+	}()
+
+	if err := New[string, int]().TakeOver(context.Background(), "unix", path); err != ErrHandover {
+		t.Fatalf("expected ErrHandover, got %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import "time"
+
+// Health is a report on how well the cache keeps up with expiring keys and
+// delivering its work, meant for readiness probes.
+type Health struct {
+	// Closed tells whether the cache is closed or closing.
+	Closed bool
+
+	// Janitors is the number of janitors running for the cache. Without
+	// any, expired keys are only flushed by writes.
+	Janitors int
+
+	// PendingWrites is the number of keys with writes queued in running
+	// WriteBuffers, waiting to be applied.
+	PendingWrites int
+
+	// PendingEvents is the number of events waiting in event stream and
+	// subscription channels to be received.
+	PendingEvents int
+
+	// Keys is the number of keys in the cache, and Expired the number of
+	// those that are past their expiration time but were not flushed yet.
+	Keys    int
+	Expired int
+
+	// LastFlush is when expired keys were last flushed, which every write
+	// does, or the zero time if they never were. SinceFlush is the time
+	// elapsed since then.
+	LastFlush  time.Time
+	SinceFlush time.Duration
+}
+
+// ExpiredRatio returns the ratio of keys that are past their expiration time
+// but were not flushed yet, or 0 if the cache is empty.
+func (h Health) ExpiredRatio() float64 {
+	if h.Keys == 0 {
+		return 0
+	}
+	return float64(h.Expired) / float64(h.Keys)
+}
+
+// Health returns a report on the state of the cache. Counting expired keys
+// takes O(n log n) time for n expired keys, regardless of the size of the
+// cache.
+func (cache *Cache[K, V]) Health() Health {
+	cache.mux.RLock()
+	defer cache.mux.RUnlock()
+
+	h := Health{
+		Closed:   cache.closing,
+		Janitors: len(cache.alarms),
+		Keys:     len(cache.cache),
+	}
+	for _, d := range cache.drainers {
+		h.PendingWrites += d.Pending()
+	}
+	h.PendingEvents = len(cache.events)
+	for _, sub := range cache.subs {
+		h.PendingEvents += len(sub.ch)
+	}
+
+	now := cache.instant()
+	cache.walkByExpiry(func(bucket *cacheBucket[K, V]) bool {
+		if bucket.expiry.After(now) {
+			return false
+		}
+		h.Expired++
+		return true
+	})
+	if cache.flushed != 0 {
+		h.LastFlush = cache.flushed.Time()
+		h.SinceFlush = now.Sub(cache.flushed)
+	}
+	return h
+}
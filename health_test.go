@@ -0,0 +1,67 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestHealth(t *testing.T) {
+	now := time.Now()
+	c := New[string, int]()
+	c.Clock = clockFunc(func() time.Time { return now })
+
+	if h := c.Health(); h.Keys != 0 || h.ExpiredRatio() != 0 || !h.LastFlush.IsZero() {
+		t.Fatalf("expected an empty cache to be healthy, got %+v", h)
+	}
+
+	c.Set("foo", 1, time.Second)
+	c.Set("bar", 2, time.Second)
+	c.Set("baz", 3, time.Hour)
+	c.Set("qux", 4, time.Hour)
+	flushed := now
+	now = now.Add(time.Minute)
+
+	_ = c.Events(10)
+	c.Get("baz")
+	b := c.NewWriteBuffer(10)
+	c.addDrainer(b)
+	b.Set("quux", 5, time.Hour)
+
+	h := c.Health()
+	if h.Keys != 4 || h.Expired != 2 || h.ExpiredRatio() != 0.5 {
+		t.Fatalf("expected 2 out of 4 keys to be expired, got %+v", h)
+	}
+	if h.PendingWrites != 1 || h.PendingEvents != 1 {
+		t.Fatalf("expected 1 pending write and event, got %+v", h)
+	}
+	if !h.LastFlush.Equal(flushed) || h.SinceFlush != time.Minute {
+		t.Fatalf("expected the last flush to be a minute ago, got %+v", h)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		c.NewJanitor().Run(ctx)
+		close(stopped)
+	}()
+	for h := c.Health(); h.Janitors != 1 || h.Expired != 0; h = c.Health() {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-stopped
+	if h := c.Health(); h.Janitors != 0 || h.SinceFlush != 0 {
+		t.Fatalf("expected the janitor to have flushed the cache and stopped, got %+v", h)
+	}
+
+	c.Close(context.Background())
+	if !c.Health().Closed {
+		t.Fatal("expected a closed cache to be reported as such")
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+// heapKey orders the elements of a minHeap, by rank first, then by time.
+type heapKey struct {
+	rank int
+	at   instant
+}
+
+func (k heapKey) before(other heapKey) bool {
+	if k.rank != other.rank {
+		return k.rank < other.rank
+	}
+	return k.at < other.at
+}
+
+// minHeap is a binary min-heap. Unlike container/heap, it is typed, and never
+// calls into its elements: like the expire list, it keeps the key of each
+// element next to it, and the position of each element gets written through
+// a pointer into the element, if any. Sifting thus neither boxes elements
+// into interfaces nor goes through dynamic dispatch.
+type minHeap[T any] struct {
+	elts []T
+	keys []heapKey
+	pos  []*int // nil for heaps that do not track positions
+}
+
+// init establishes the heap ordering of elts and keys, and sets up the
+// positions of elements to be tracked through pos, if not nil. It takes
+// linear time.
+func (h *minHeap[T]) init(pos []*int) {
+	h.pos = pos
+	for i, p := range pos {
+		*p = i
+	}
+	for i := len(h.elts)/2 - 1; i >= 0; i-- {
+		h.down(i, len(h.elts))
+	}
+}
+
+// push adds x to the heap. Its position gets written to pos, if not nil,
+// which must be so for all or none of the elements.
+func (h *minHeap[T]) push(x T, key heapKey, pos *int) {
+	h.elts = append(h.elts, x)
+	h.keys = append(h.keys, key)
+	if pos != nil {
+		*pos = len(h.pos)
+		h.pos = append(h.pos, pos)
+	}
+	h.up(len(h.elts) - 1)
+}
+
+// remove removes and returns the element at position i.
+func (h *minHeap[T]) remove(i int) T {
+	n := len(h.elts) - 1
+	if n != i {
+		h.swap(i, n)
+		if !h.down(i, n) {
+			h.up(i)
+		}
+	}
+	x := h.elts[n]
+	var zero T
+	h.elts[n] = zero // don't keep referencing the item
+	h.elts = h.elts[:n]
+	h.keys = h.keys[:n]
+	if h.pos != nil {
+		h.pos[n] = nil
+		h.pos = h.pos[:n]
+	}
+	return x
+}
+
+// update changes the key of the element at position i, and restores the
+// ordering of the heap.
+func (h *minHeap[T]) update(i int, key heapKey) {
+	h.keys[i] = key
+	if !h.down(i, len(h.elts)) {
+		h.up(i)
+	}
+}
+
+func (h *minHeap[T]) swap(i, j int) {
+	h.elts[i], h.elts[j] = h.elts[j], h.elts[i]
+	h.keys[i], h.keys[j] = h.keys[j], h.keys[i]
+	if h.pos != nil {
+		h.pos[i], h.pos[j] = h.pos[j], h.pos[i]
+		*h.pos[i], *h.pos[j] = i, j
+	}
+}
+
+func (h *minHeap[T]) up(j int) {
+	for j > 0 {
+		i := (j - 1) / 2
+		if !h.keys[j].before(h.keys[i]) {
+			break
+		}
+		h.swap(i, j)
+		j = i
+	}
+}
+
+func (h *minHeap[T]) down(i0, n int) bool {
+	i := i0
+	for {
+		j := 2*i + 1
+		if j >= n || j < 0 {
+			break
+		}
+		if j2 := j + 1; j2 < n && h.keys[j2].before(h.keys[j]) {
+			j = j2
+		}
+		if !h.keys[j].before(h.keys[i]) {
+			break
+		}
+		h.swap(i, j)
+		i = j
+	}
+	return i > i0
+}
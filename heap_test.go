@@ -0,0 +1,76 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+type testNode struct {
+	val int
+	idx int
+}
+
+func TestMinHeap(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	var h minHeap[*testNode]
+	for i := 0; i < 100; i++ {
+		n := &testNode{val: rng.Intn(1000)}
+		h.push(n, heapKey{at: instant(n.val)}, &n.idx)
+	}
+	for i := 0; i < 20; i++ {
+		n := h.elts[rng.Intn(len(h.elts))]
+		n.val = rng.Intn(1000)
+		h.update(n.idx, heapKey{at: instant(n.val)})
+	}
+	for i := 0; i < 20; i++ {
+		h.remove(rng.Intn(len(h.elts)))
+	}
+	for i, n := range h.elts {
+		if n.idx != i {
+			t.Fatalf("expected node at %d to know its index, got %d", i, n.idx)
+		}
+	}
+
+	want := make([]int, len(h.elts))
+	for i, n := range h.elts {
+		want[i] = n.val
+	}
+	sort.Ints(want)
+	for _, v := range want {
+		if got := h.remove(0).val; got != v {
+			t.Fatalf("expected %d to be popped, got %d", v, got)
+		}
+	}
+	if len(h.elts) != 0 || len(h.keys) != 0 || len(h.pos) != 0 {
+		t.Fatalf("expected the heap to be empty, got %d nodes", len(h.elts))
+	}
+}
+
+func TestMinHeapInit(t *testing.T) {
+	h := minHeap[string]{
+		elts: []string{"c", "e", "a", "d", "b"},
+		keys: []heapKey{{rank: 1, at: 0}, {rank: 1, at: 2}, {rank: 0, at: 5}, {rank: 1, at: 1}, {rank: 0, at: 9}},
+	}
+	h.init(nil)
+	for i := 1; i < len(h.keys); i++ {
+		if h.keys[i].before(h.keys[(i-1)/2]) {
+			t.Fatalf("expected %q not to come before its parent", h.elts[i])
+		}
+	}
+
+	var order string
+	for len(h.elts) > 0 {
+		order += h.remove(0)
+	}
+	if order != "abcde" {
+		t.Fatalf("expected elements to be ordered by rank then time, got %q", order)
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+// indexer is the part of secondary indexes the cache maintains.
+type indexer[K comparable, V any] interface {
+	update(key K, value V)
+	remove(key K)
+	reset()
+}
+
+// Index is a secondary index of a cache, mapping values of type I extracted
+// from the values in the cache to the keys holding them.
+type Index[K comparable, V any, I comparable] struct {
+	cache   *Cache[K, V]
+	extract func(value V) I
+	keys    map[I]map[K]struct{}
+	byKey   map[K]I
+}
+
+// AddIndex registers a secondary index over the values of the cache, kept
+// up to date as keys are set and removed, and returns it. extract gets
+// called with the cache locked whenever a value is set, and must not call
+// methods of the cache.
+//
+// For instance, a cache of sessions keyed by session ID can be indexed by
+// user ID, to find all the sessions of a user without scanning the cache.
+func AddIndex[K comparable, V any, I comparable](cache *Cache[K, V], extract func(value V) I) *Index[K, V, I] {
+	idx := &Index[K, V, I]{
+		cache:   cache,
+		extract: extract,
+		keys:    make(map[I]map[K]struct{}),
+		byKey:   make(map[K]I),
+	}
+
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	for key, bucket := range cache.cache {
+		if value, ok := cache.load(bucket); ok {
+			idx.update(key, value)
+		}
+	}
+	cache.indexes = append(cache.indexes, idx)
+	return idx
+}
+
+// Keys returns the live keys whose values map to i, in no particular order.
+func (idx *Index[K, V, I]) Keys(i I) []K {
+	cache := idx.cache
+	cache.mux.RLock()
+	defer cache.mux.RUnlock()
+
+	now := cache.instant()
+	var keys []K
+	for key := range idx.keys[i] {
+		if cache.cache[key].expiry.After(now) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Remove unregisters the index from its cache.
+func (idx *Index[K, V, I]) Remove() {
+	cache := idx.cache
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	for i, other := range cache.indexes {
+		if other == indexer[K, V](idx) {
+			cache.indexes = append(cache.indexes[:i], cache.indexes[i+1:]...)
+			break
+		}
+	}
+	idx.reset()
+}
+
+func (idx *Index[K, V, I]) update(key K, value V) {
+	var i I
+	idx.cache.guard("AddIndex", func() { i = idx.extract(value) })
+	if prev, ok := idx.byKey[key]; ok {
+		if prev == i {
+			return
+		}
+		idx.remove(key)
+	}
+	keys, ok := idx.keys[i]
+	if !ok {
+		keys = make(map[K]struct{})
+		idx.keys[i] = keys
+	}
+	keys[key] = struct{}{}
+	idx.byKey[key] = i
+}
+
+func (idx *Index[K, V, I]) remove(key K) {
+	i, ok := idx.byKey[key]
+	if !ok {
+		return
+	}
+	delete(idx.byKey, key)
+	keys := idx.keys[i]
+	delete(keys, key)
+	if len(keys) == 0 {
+		delete(idx.keys, i)
+	}
+}
+
+func (idx *Index[K, V, I]) reset() {
+	idx.keys = make(map[I]map[K]struct{})
+	idx.byKey = make(map[K]I)
+}
@@ -0,0 +1,54 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestIndex(t *testing.T) {
+	type session struct {
+		user string
+	}
+
+	c := New[int, session]()
+	c.Set(1, session{"alice"}, time.Hour)
+
+	byUser := AddIndex(c, func(s session) string { return s.user })
+	c.Set(2, session{"bob"}, time.Hour)
+	c.Set(3, session{"alice"}, time.Hour)
+	c.Set(4, session{"bob"}, time.Hour)
+
+	keysOf := func(user string) []int {
+		keys := byUser.Keys(user)
+		sort.Ints(keys)
+		return keys
+	}
+	if keys := keysOf("alice"); len(keys) != 2 || keys[0] != 1 || keys[1] != 3 {
+		t.Fatalf("expected sessions 1 and 3 for alice, got %v", keys)
+	}
+
+	c.Set(4, session{"alice"}, time.Hour)
+	c.Expire(2)
+	if keys := keysOf("bob"); len(keys) != 0 {
+		t.Fatalf("expected no sessions for bob, got %v", keys)
+	}
+	if keys := keysOf("alice"); len(keys) != 3 {
+		t.Fatalf("expected 3 sessions for alice, got %v", keys)
+	}
+	if len(byUser.keys) != 1 {
+		t.Fatalf("expected empty index entries to be dropped, got %v", byUser.keys)
+	}
+
+	byUser.Remove()
+	c.Set(5, session{"alice"}, time.Hour)
+	if len(c.indexes) != 0 || len(byUser.Keys("alice")) != 0 {
+		t.Fatal("expected removed index not to be maintained anymore")
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// entryGuard locks the value of a bucket for WithEntry.
+type entryGuard struct {
+	mux   sync.Mutex
+	stale uint32 // set atomically once the value was replaced or removed
+
+	// cleanup is postponed until no WithEntry call modifies the value of a
+	// bucket that left the cache anymore; guarded by the cache lock.
+	cleanup func()
+}
+
+// WithEntry calls fn with a pointer to the value of key, which fn may modify
+// in place, and reports whether the key was found. The value is locked for
+// the duration of fn rather than the whole cache: calls to WithEntry for the
+// same key are serialized, but the cache stays available for everything
+// else. Writes replacing or removing the key do not wait for fn either, and
+// modifications to a value replaced in the meantime are lost.
+//
+// Reading the value by other means, like Get, while fn may be running is a
+// data race unless V synchronizes its own accesses; values modified in place
+// are best only accessed through WithEntry. Copies of the whole cache, like
+// Snapshot, Freeze, Clone and Dump, wait for fn to return instead.
+// Modifications in place are not seen by SizeFunc or secondary indexes, and
+// do not change the expiration time of the key. fn must not call methods of
+// the cache.
+//
+// With a custom backend, fn is instead called with the cache locked, and
+// the modified value is stored back.
+func (cache *Cache[K, V]) WithEntry(key K, fn func(value *V)) bool {
+	for {
+		cache.mux.Lock()
+		bucket, ok := cache.cache[key]
+		if !ok || cache.backend != nil {
+			defer cache.mux.Unlock()
+			if ok {
+				value, _ := cache.load(bucket)
+				fn(&value)
+				cache.store(bucket, value)
+				cache.thaw()
+			}
+			return ok
+		}
+		if bucket.guard == nil {
+			bucket.guard = &entryGuard{}
+		}
+		guard := bucket.guard
+		cache.mux.Unlock()
+
+		// The bucket may have left the cache by the time the guard is
+		// locked, in which case it was marked stale first.
+		guard.mux.Lock()
+		stale := atomic.LoadUint32(&guard.stale) != 0
+		if !stale {
+			fn(&bucket.val)
+		}
+		guard.mux.Unlock()
+
+		cache.mux.Lock()
+		if !stale {
+			cache.thaw()
+		}
+		// Whoever locks the guard last cleans up after the bucket.
+		if cleanup := guard.cleanup; cleanup != nil && guard.mux.TryLock() {
+			guard.mux.Unlock()
+			guard.cleanup = nil
+			cleanup()
+		}
+		cache.mux.Unlock()
+		if !stale {
+			return true
+		}
+	}
+}
+
+// lockOut keeps pending WithEntry calls from touching the value of bucket,
+// before it gets replaced or removed, without waiting for a running one to
+// return. It reports whether one may still be modifying the value, in which
+// case bucket keeps its guard, must not be recycled, and anything reading
+// the value must be postponed with defer. cache.mux must be held for writing.
+func (bucket *cacheBucket[K, V]) lockOut() (busy bool) {
+	guard := bucket.guard
+	if guard == nil {
+		return false
+	}
+	if !guard.mux.TryLock() {
+		atomic.StoreUint32(&guard.stale, 1)
+		return true
+	}
+	atomic.StoreUint32(&guard.stale, 1)
+	guard.mux.Unlock()
+	bucket.guard = nil
+	return false
+}
+
+// postpone calls fn once no WithEntry call modifies the value of bucket
+// anymore, with the cache locked. bucket must be busy, as reported by
+// lockOut. cache.mux must be held for writing.
+func (bucket *cacheBucket[K, V]) postpone(fn func()) {
+	guard := bucket.guard
+	if prev := guard.cleanup; prev != nil {
+		guard.cleanup = func() { prev(); fn() }
+	} else {
+		guard.cleanup = fn
+	}
+}
+
+// settled returns the value of bucket once no WithEntry call modifies it,
+// for copies of the cache, which would otherwise race with them. cache.mux
+// must be held.
+func (cache *Cache[K, V]) settled(bucket *cacheBucket[K, V]) V {
+	if guard := bucket.guard; guard != nil {
+		guard.mux.Lock()
+		defer guard.mux.Unlock()
+	}
+	value, _ := cache.load(bucket)
+	return value
+}
+
+// takeOver returns a fresh bucket taking the place of bucket in the cache,
+// for writers replacing the value of a key while WithEntry modifies it in
+// place. The old value stays in bucket, which is out of the cache, for
+// WithEntry to finish with. cache.mux must be held for writing.
+func (cache *Cache[K, V]) takeOver(bucket *cacheBucket[K, V]) *cacheBucket[K, V] {
+	fresh := cache.slab.alloc()
+	fresh.hits = atomic.LoadUint64(&bucket.hits)
+	fresh.accessed = atomic.LoadInt64(&bucket.accessed)
+	fresh.expiry = bucket.expiry
+	fresh.softExpiry = bucket.softExpiry
+	fresh.created = bucket.created
+	fresh.rev = bucket.rev
+	fresh.idx = bucket.idx
+	fresh.eidx = bucket.eidx
+	fresh.priority = bucket.priority
+	fresh.cost = bucket.cost
+	fresh.seq = bucket.seq
+	fresh.recompute = bucket.recompute
+	fresh.key = bucket.key
+	fresh.deps = bucket.deps
+	fresh.group = bucket.group
+	fresh.gidx = bucket.gidx
+
+	cache.cache[fresh.key] = fresh
+	if fresh.idx >= 0 {
+		cache.expireList.elts[fresh.idx] = fresh
+	}
+	if fresh.group != nil {
+		fresh.group.buckets[fresh.gidx] = fresh
+	}
+	if cache.priorities && fresh.expiry != unset {
+		cache.evictList.elts[fresh.eidx] = fresh
+		cache.evictList.pos[fresh.eidx] = &fresh.eidx
+	}
+	if cache.expiries != nil {
+		cache.expiries.Remove(bucket)
+		cache.expiries.Insert(fresh)
+	}
+	bucket.idx = -1
+	bucket.deps = nil
+	bucket.group = nil
+	return fresh
+}
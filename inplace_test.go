@@ -0,0 +1,188 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithEntry(t *testing.T) {
+	c := New[string, [64]int]()
+	if c.WithEntry("foo", func(*[64]int) {}) {
+		t.Fatal("expected WithEntry to report missing keys")
+	}
+
+	c.Set("foo", [64]int{}, time.Hour)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				c.WithEntry("foo", func(v *[64]int) {
+					for k := range v {
+						v[k]++
+					}
+				})
+				c.Set("bar", [64]int{}, time.Hour)
+			}
+		}()
+	}
+	wg.Wait()
+	if v, _ := c.Get("foo"); v[0] != 800 || v[63] != 800 {
+		t.Fatalf("expected every modification to be kept, got %v and %v", v[0], v[63])
+	}
+}
+
+func TestWithEntryWrites(t *testing.T) {
+	c := New[string, int]()
+	c.Set("foo", 1, time.Hour)
+
+	replaced := make(chan struct{})
+	c.WithEntry("foo", func(v *int) {
+		go func() {
+			c.Set("foo", 10, time.Hour)
+			close(replaced)
+		}()
+		select {
+		case <-replaced:
+		case <-time.After(time.Second):
+			t.Error("expected Set not to wait for WithEntry")
+		}
+		*v = 2
+	})
+	if v, _ := c.Get("foo"); v != 10 {
+		t.Fatalf("expected the value to stay replaced after WithEntry, got %v", v)
+	}
+
+	c.WithEntry("foo", func(v *int) { *v++ })
+	c.Expire("foo")
+	c.Set("bar", 1, time.Hour)
+	if v, _ := c.Get("bar"); v != 1 {
+		t.Fatalf("expected recycled buckets to be left alone, got %v", v)
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if err := c.checkInvariants(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWithEntryTakeOver(t *testing.T) {
+	for _, resolution := range []time.Duration{0, time.Second} {
+		c := New[int, int]()
+		c.SetExpiryIndex(true)
+		c.SetExpiryResolution(resolution)
+		for i := 0; i < 8; i++ {
+			c.SetWithPriority(i, i, time.Duration(i+1)*time.Hour, i)
+		}
+		c.WithEntry(3, func(v *int) {
+			done := make(chan struct{})
+			go func() {
+				c.SetWithPriority(3, 30, time.Minute, 0)
+				close(done)
+			}()
+			<-done
+			*v = 4
+		})
+		if v, _ := c.Get(3); v != 30 {
+			t.Fatalf("expected the value to be replaced, got %v", v)
+		}
+		c.mux.Lock()
+		err := c.checkInvariants()
+		c.mux.Unlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e := c.ExpiringSoon(1); len(e) != 1 || e[0].Key != 3 {
+			t.Fatalf("expected the replaced key to expire first, got %v", e)
+		}
+	}
+}
+
+func TestWithEntryExpire(t *testing.T) {
+	c := New[string, int]()
+	c.Set("foo", 1, time.Hour)
+
+	var expired []int
+	c.OnExpire = func(key string, value int) { expired = append(expired, value) }
+
+	entered, done, returned := make(chan struct{}), make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(returned)
+		c.WithEntry("foo", func(v *int) {
+			close(entered)
+			<-done
+			*v = 2
+		})
+	}()
+	<-entered
+
+	got := make(chan int)
+	go func() {
+		v, _ := c.Expire("foo")
+		got <- v
+	}()
+	for c.Contains("foo") {
+		time.Sleep(time.Millisecond)
+	}
+	close(done)
+	if v := <-got; v != 2 {
+		t.Fatalf("expected Expire to return the modified value, got %v", v)
+	}
+	<-returned
+	if len(expired) != 1 || expired[0] != 2 {
+		t.Fatalf("expected OnExpire to be called with the modified value, got %v", expired)
+	}
+}
+
+func TestWithEntryCopies(t *testing.T) {
+	c := New[string, [64]int]()
+	c.Set("foo", [64]int{}, time.Hour)
+	if v, _ := c.Freeze().Get("foo"); v[0] != 0 {
+		t.Fatalf("expected a frozen view of the value, got %v", v[0])
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				c.WithEntry("foo", func(v *[64]int) {
+					for k := range v {
+						v[k]++
+					}
+				})
+			}
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		if v, _ := c.Snapshot().Get("foo"); v[0] != v[63] {
+			t.Fatalf("expected snapshots to wait for WithEntry, got %v and %v", v[0], v[63])
+		}
+		if v, _ := c.Clone().Get("foo"); v[0] != v[63] {
+			t.Fatalf("expected clones to wait for WithEntry, got %v and %v", v[0], v[63])
+		}
+	}
+	wg.Wait()
+	if v, _ := c.Freeze().Get("foo"); v[0] != 400 {
+		t.Fatalf("expected WithEntry to withdraw frozen views, got %v", v[0])
+	}
+}
+
+func TestWithEntryBackend(t *testing.T) {
+	c := NewWithBackend[string, int](NewMapBackend[string, int]())
+	c.Set("foo", 1, time.Hour)
+	c.WithEntry("foo", func(v *int) { *v++ })
+	if v, _ := c.Get("foo"); v != 2 {
+		t.Fatalf("expected the modified value to be stored back, got %v", v)
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"fmt"
+)
+
+// checkInvariants verifies that the internal structures of the cache agree
+// with each other. cache.mux must be held.
+func (cache *Cache[K, V]) checkInvariants() error {
+	elts := cache.expireList.elts
+	if len(elts) != len(cache.cache) {
+		return fmt.Errorf("ttlcache: %d keys in the map, but %d in the expire list", len(cache.cache), len(elts))
+	}
+	if n := len(cache.expireList.exp); !cache.expireList.coalesced() && n != len(elts) {
+		return fmt.Errorf("ttlcache: %d keys in the expire list, but %d expiration times", len(elts), n)
+	}
+	for i, bucket := range elts {
+		if bucket.idx != i {
+			return fmt.Errorf("ttlcache: bucket of key %v at index %d of the expire list thinks it is at %d", bucket.key, i, bucket.idx)
+		}
+		if cache.cache[bucket.key] != bucket {
+			return fmt.Errorf("ttlcache: bucket of key %v in the expire list is not the one in the map", bucket.key)
+		}
+		if cache.expireList.coalesced() {
+			if g := bucket.group; g == nil || g.buckets[bucket.gidx] != bucket || g.expiry != bucket.expiry {
+				return fmt.Errorf("ttlcache: bucket of key %v is not in the group of its expiration time", bucket.key)
+			}
+			continue
+		}
+		if cache.expireList.exp[i] != bucket.expiry {
+			return fmt.Errorf("ttlcache: key %v expires at %v, but the expire list thinks it expires at %v", bucket.key, bucket.expiry.Time(), cache.expireList.exp[i].Time())
+		}
+		if parent := (i - 1) / expireListArity; i > 0 && bucket.expiry.Before(elts[parent].expiry) {
+			return fmt.Errorf("ttlcache: key %v expires before its parent %v in the expire list", bucket.key, elts[parent].key)
+		}
+	}
+
+	if cache.expireList.coalesced() {
+		groups := cache.expireList.groups.elts
+		if len(groups) != len(cache.expireList.byExpiry) {
+			return fmt.Errorf("ttlcache: %d groups in the expire list, but %d expiration times", len(groups), len(cache.expireList.byExpiry))
+		}
+		var n int
+		for i, g := range groups {
+			if g.idx != i {
+				return fmt.Errorf("ttlcache: group at index %d of the expire list thinks it is at %d", i, g.idx)
+			}
+			if cache.expireList.groups.keys[i].at != g.expiry {
+				return fmt.Errorf("ttlcache: group expiring at %v is out of date in the expire list", g.expiry.Time())
+			}
+			if cache.expireList.byExpiry[g.expiry] != g {
+				return fmt.Errorf("ttlcache: group expiring at %v is not the one of its expiration time", g.expiry.Time())
+			}
+			if parent := (i - 1) / 2; i > 0 && g.expiry.Before(groups[parent].expiry) {
+				return fmt.Errorf("ttlcache: group expiring at %v expires before its parent in the expire list", g.expiry.Time())
+			}
+			n += len(g.buckets)
+		}
+		if n != len(elts) {
+			return fmt.Errorf("ttlcache: %d keys in the expire list, but %d in its groups", len(elts), n)
+		}
+	}
+
+	var cost int
+	for _, bucket := range elts {
+		cost += bucket.cost
+	}
+	if cost != cache.cost {
+		return fmt.Errorf("ttlcache: keys cost %d in total, but the cache thinks they cost %d", cost, cache.cost)
+	}
+
+	if cache.priorities {
+		evict := cache.evictList.elts
+		if len(evict) != len(elts) {
+			return fmt.Errorf("ttlcache: %d keys in the expire list, but %d in the evict list", len(elts), len(evict))
+		}
+		for i, bucket := range evict {
+			if bucket.eidx != i {
+				return fmt.Errorf("ttlcache: bucket of key %v at index %d of the evict list thinks it is at %d", bucket.key, i, bucket.eidx)
+			}
+			if cache.cache[bucket.key] != bucket {
+				return fmt.Errorf("ttlcache: bucket of key %v in the evict list is not the one in the map", bucket.key)
+			}
+			if cache.evictList.keys[i] != bucket.evictKey() {
+				return fmt.Errorf("ttlcache: key %v is out of date in the evict list", bucket.key)
+			}
+			if parent := (i - 1) / 2; i > 0 && cache.evictList.keys[i].before(cache.evictList.keys[parent]) {
+				return fmt.Errorf("ttlcache: key %v is evicted before its parent %v in the evict list", bucket.key, evict[parent].key)
+			}
+		}
+	}
+
+	if cache.keyIndex != nil {
+		if n := cache.keyIndex.Len(); n != len(elts) {
+			return fmt.Errorf("ttlcache: %d keys in the map, but %d in the key order index", len(elts), n)
+		}
+		for n := cache.keyIndex.First(); n != nil; n = n.Next() {
+			if _, ok := cache.cache[n.val]; !ok {
+				return fmt.Errorf("ttlcache: key %v of the key order index is not in the map", n.val)
+			}
+		}
+	}
+
+	if cache.expiries != nil {
+		if n := cache.expiries.Len(); n != len(elts) {
+			return fmt.Errorf("ttlcache: %d keys in the map, but %d in the expiry index", len(elts), n)
+		}
+		for n := cache.expiries.First(); n != nil; n = n.Next() {
+			if cache.cache[n.val.key] != n.val {
+				return fmt.Errorf("ttlcache: bucket of key %v in the expiry index is not the one in the map", n.val.key)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestInvariants(t *testing.T) {
+	c := New[string, int]()
+	c.SetKeyOrder(func(a, b string) bool { return a < b })
+	c.SetExpiryIndex(true)
+	c.Capacity = 50
+
+	for i := 0; i < 2000; i++ {
+		key := strconv.Itoa(rand.Intn(100))
+		ttl := time.Duration(rand.Intn(100)+1) * time.Minute
+		switch rand.Intn(6) {
+		case 0:
+			c.Expire(key)
+		case 1:
+			c.ExpireMany([]string{key, strconv.Itoa(rand.Intn(100))})
+		case 2:
+			c.SetWithPriority(key, i, ttl, rand.Intn(3))
+		case 3:
+			c.EvictN(1)
+		default:
+			c.Set(key, i, ttl)
+		}
+		if err := c.checkInvariants(); err != nil {
+			t.Fatalf("after %d operations: %v", i+1, err)
+		}
+	}
+
+	c.expireList.elts[0].idx = 1
+	if err := c.checkInvariants(); err == nil {
+		t.Fatal("expected corruption to be detected")
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"context"
+	"time"
+)
+
+// Janitor flushes a cache as its keys expire, so that OnExpire gets called
+// on time even when no keys are being set. Rather than waking up at a fixed
+// interval, it sleeps until the soonest key is due, and wakes up earlier if
+// a key due sooner gets set in the meantime.
+//
+// Keys are only flushed this way while Run is running, on the goroutine
+// that called it; closing the cache waits for Run to return.
+type Janitor[K comparable, V any] struct {
+	cache *Cache[K, V]
+}
+
+// NewJanitor returns a janitor for the cache.
+func (cache *Cache[K, V]) NewJanitor() *Janitor[K, V] {
+	return &Janitor[K, V]{cache: cache}
+}
+
+// Run flushes expired keys as they come due until ctx is done, in which case
+// the context error is returned, or until the cache gets closed, in which
+// case ErrClosed is returned.
+func (j *Janitor[K, V]) Run(ctx context.Context) error {
+	cache := j.cache
+	done, _, err := cache.startBackground()
+	if err != nil {
+		return err
+	}
+	defer cache.background.Done()
+
+	alarm := cache.addAlarm()
+	defer cache.removeAlarm(alarm)
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		wait, ok := cache.untilNextExpiry()
+		if ok && wait <= 0 {
+			cache.Flush()
+			continue
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		var tick <-chan time.Time
+		if ok {
+			timer.Reset(wait)
+			tick = timer.C
+		}
+		select {
+		case <-tick:
+		case <-alarm:
+		case <-done:
+			return ErrClosed
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// untilNextExpiry returns how long until the soonest key is due, and false
+// if the cache is empty.
+func (cache *Cache[K, V]) untilNextExpiry() (time.Duration, bool) {
+	cache.mux.RLock()
+	defer cache.mux.RUnlock()
+
+	bucket, ok := cache.expireList.Peek()
+	if !ok {
+		return 0, false
+	}
+	return bucket.expiry.Sub(cache.instant()), true
+}
+
+// addAlarm returns a channel receiving a value whenever the soonest key of
+// the cache changes.
+func (cache *Cache[K, V]) addAlarm() chan struct{} {
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	alarm := make(chan struct{}, 1)
+	cache.alarms = append(cache.alarms, alarm)
+	return alarm
+}
+
+func (cache *Cache[K, V]) removeAlarm(alarm chan struct{}) {
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	for i, other := range cache.alarms {
+		if other == alarm {
+			cache.alarms = append(cache.alarms[:i], cache.alarms[i+1:]...)
+			break
+		}
+	}
+}
+
+// rearm tells alarms and expiry timers that the soonest key of the cache
+// changed. cache.mux must be held for writing.
+func (cache *Cache[K, V]) rearm() {
+	for _, alarm := range cache.alarms {
+		select {
+		case alarm <- struct{}{}:
+		default:
+		}
+	}
+	for _, t := range cache.timers {
+		t.arm()
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestJanitor(t *testing.T) {
+	c := New[string, int]()
+	expired := make(chan string, 2)
+	c.OnExpire = func(key string, _ int) { expired <- key }
+	c.Set("later", 1, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- c.NewJanitor().Run(ctx)
+	}()
+
+	// The janitor may already be sleeping until the first key is due, and
+	// must be woken up by the sooner one.
+	time.Sleep(10 * time.Millisecond)
+	c.Set("sooner", 2, 10*time.Millisecond)
+	select {
+	case key := <-expired:
+		if key != "sooner" {
+			t.Fatalf("expected sooner to expire, got %q", key)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the janitor to flush sooner")
+	}
+
+	if err := c.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-stopped; !errors.Is(err, ErrClosed) {
+		t.Fatalf("expected the janitor to stop with ErrClosed, got %v", err)
+	}
+}
@@ -0,0 +1,383 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"bufio"
+	"context"
+	"crypto/cipher"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Journal persists a cache to a directory, as a snapshot followed by a
+// write-ahead log of the changes made since, so that the cache can be
+// recovered after a restart. Compaction folds the log into a fresh snapshot
+// and truncates it, bounding disk usage and recovery time.
+//
+// Changes get logged as they happen, with the cache locked. Compaction only
+// happens when calling Compact, or while Run is running on the goroutine
+// that called it; Close waits for Run to return before closing the cache.
+type Journal[K comparable, V any] struct {
+	// CompactSize is the size in bytes past which Run compacts the log. It
+	// defaults to 64MiB.
+	CompactSize int64
+
+	// Interval is how often Run checks the size of the log. It defaults to
+	// one second.
+	Interval time.Duration
+
+	cache *Cache[K, V]
+	dir   string
+	aead  cipher.AEAD
+	seq   uint64
+	f     *os.File
+	cw    *countingWriter
+	ew    *encryptWriter // when encrypted
+	bw    *bufio.Writer
+	enc   *gob.Encoder
+	err   error
+}
+
+type journalRecord[K, V any] struct {
+	Delete bool
+	Entry  Entry[K, V]
+}
+
+const (
+	journalSnapshot = "snapshot-"
+	journalLog      = "log-"
+)
+
+// OpenJournal recovers the cache from the journal in dir, if any, then
+// starts logging changes to the cache there. dir gets created if needed.
+// Keys and values must be encodable with encoding/gob.
+func OpenJournal[K comparable, V any](cache *Cache[K, V], dir string) (*Journal[K, V], error) {
+	return OpenJournalWithCipher(cache, dir, nil)
+}
+
+// OpenJournalWithCipher is like OpenJournal, but encrypts both the snapshots
+// and the logs of the journal with aead, as EncryptWriter does, keeping the
+// cache off the disk in plaintext. A nil aead leaves them unencrypted.
+//
+// Every change logged is sealed on its own, so that the log can still be
+// replayed up to the last change if the process dies while writing to it.
+func OpenJournalWithCipher[K comparable, V any](cache *Cache[K, V], dir string, aead cipher.AEAD) (*Journal[K, V], error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	snapshots, logs, err := journalFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	j := &Journal[K, V]{cache: cache, dir: dir, aead: aead}
+	var first uint64
+	if len(snapshots) > 0 {
+		first = snapshots[len(snapshots)-1]
+		if err := j.restore(first); err != nil {
+			return nil, err
+		}
+	}
+	for _, seq := range logs {
+		if seq < first {
+			continue
+		}
+		if err := j.replay(seq); err != nil {
+			return nil, err
+		}
+		j.seq = seq
+	}
+	if j.seq < first {
+		j.seq = first
+	}
+
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	if err := j.rotate(); err != nil {
+		return nil, err
+	}
+	cache.journal = j
+	return j, nil
+}
+
+// Compact writes a fresh snapshot of the cache, then removes the log of the
+// changes it covers.
+func (j *Journal[K, V]) Compact() error {
+	cache := j.cache
+	cache.mux.Lock()
+	if j.err != nil {
+		cache.mux.Unlock()
+		return j.err
+	}
+	// Compacting a closed cache would lose everything it held.
+	if j.f == nil || cache.closed {
+		cache.mux.Unlock()
+		return ErrClosed
+	}
+	now := cache.now()
+	live := toInstant(now)
+	entries := make([]Entry[K, V], 0, len(cache.expireList.elts))
+	for _, bucket := range cache.expireList.elts {
+		if bucket.expiry.After(live) {
+			entries = append(entries, cache.entry(bucket))
+		}
+	}
+	snap := &Snapshot[K, V]{time: now, version: cache.SnapshotVersion, entries: entries}
+	// Changes made from now on go to a new log, which the snapshot does not
+	// cover.
+	err := j.rotate()
+	seq := j.seq
+	cache.mux.Unlock()
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(j.dir, journalSnapshot+strconv.FormatUint(seq, 10))
+	f, err := os.Create(path + ".tmp")
+	if err != nil {
+		return err
+	}
+	if j.aead == nil {
+		_, err = snap.WriteTo(f)
+	} else {
+		w := EncryptWriter(f, j.aead)
+		_, err = snap.WriteTo(w)
+		if cerr := w.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(path+".tmp", path)
+	}
+	if err != nil {
+		os.Remove(path + ".tmp")
+		return err
+	}
+
+	snapshots, logs, err := journalFiles(j.dir)
+	if err != nil {
+		return err
+	}
+	for _, s := range snapshots {
+		if s < seq {
+			os.Remove(filepath.Join(j.dir, journalSnapshot+strconv.FormatUint(s, 10)))
+		}
+	}
+	for _, s := range logs {
+		if s < seq {
+			os.Remove(filepath.Join(j.dir, journalLog+strconv.FormatUint(s, 10)))
+		}
+	}
+	return nil
+}
+
+// Run compacts the journal whenever its log grows past CompactSize, until
+// ctx is done, in which case the context error is returned, or until the
+// cache gets closed, in which case ErrClosed is returned.
+func (j *Journal[K, V]) Run(ctx context.Context) error {
+	done, _, err := j.cache.startBackground()
+	if err != nil {
+		return err
+	}
+	defer j.cache.background.Done()
+
+	interval := j.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	limit := j.CompactSize
+	if limit <= 0 {
+		limit = 64 << 20
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			j.cache.mux.RLock()
+			size := j.cw.n
+			j.cache.mux.RUnlock()
+			if size >= limit {
+				if err := j.Compact(); err != nil {
+					j.cache.report(err)
+				}
+			}
+		case <-done:
+			return ErrClosed
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Close stops logging changes to the cache, and returns the first error
+// that happened while logging, if any.
+func (j *Journal[K, V]) Close() error {
+	cache := j.cache
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	if cache.journal == j {
+		cache.journal = nil
+	}
+	if j.f == nil {
+		return j.err
+	}
+	err := j.closeLog()
+	j.f = nil
+	if j.err != nil {
+		return j.err
+	}
+	return err
+}
+
+// record logs a change to the cache. cache.mux must be held for writing.
+func (j *Journal[K, V]) record(del bool, bucket *cacheBucket[K, V]) {
+	if j.err != nil {
+		return
+	}
+	rec := journalRecord[K, V]{Delete: del, Entry: Entry[K, V]{Key: bucket.key, Expiry: bucket.expiry.Time()}}
+	if !del {
+		rec.Entry.Value, _ = j.cache.load(bucket)
+	}
+	err := j.enc.Encode(&rec)
+	if err == nil {
+		err = j.bw.Flush()
+	}
+	if err == nil && j.ew != nil {
+		err = j.ew.flush()
+	}
+	if err != nil {
+		j.err = fmt.Errorf("ttlcache: journal: %w", err)
+		j.cache.report(j.err)
+	}
+}
+
+// rotate starts a new log. cache.mux must be held for writing.
+func (j *Journal[K, V]) rotate() error {
+	seq := j.seq + 1
+	f, err := os.OpenFile(filepath.Join(j.dir, journalLog+strconv.FormatUint(seq, 10)), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if j.f != nil {
+		j.closeLog()
+	}
+	j.seq = seq
+	j.f = f
+	j.cw = &countingWriter{w: f}
+	var w io.Writer = j.cw
+	j.ew = nil
+	if j.aead != nil {
+		j.ew = &encryptWriter{w: j.cw, aead: j.aead}
+		w = j.ew
+	}
+	j.bw = bufio.NewWriter(w)
+	// Every log gets its own encoder, since a gob stream cannot be resumed.
+	j.enc = gob.NewEncoder(j.bw)
+	return nil
+}
+
+// closeLog closes the current log. cache.mux must be held for writing.
+func (j *Journal[K, V]) closeLog() error {
+	var err error
+	if j.ew != nil {
+		err = j.ew.Close()
+	}
+	if cerr := j.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (j *Journal[K, V]) restore(seq uint64) error {
+	f, err := os.Open(filepath.Join(j.dir, journalSnapshot+strconv.FormatUint(seq, 10)))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if j.aead != nil {
+		r = DecryptReader(f, j.aead)
+	}
+	return j.cache.Restore(r)
+}
+
+func (j *Journal[K, V]) replay(seq uint64) error {
+	f, err := os.Open(filepath.Join(j.dir, journalLog+strconv.FormatUint(seq, 10)))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	cache := j.cache
+	var r io.Reader = bufio.NewReader(f)
+	if j.aead != nil {
+		r = bufio.NewReader(&decryptReader{r: r, aead: j.aead, partial: true})
+	}
+	dec := gob.NewDecoder(r)
+	for {
+		var rec journalRecord[K, V]
+		err := dec.Decode(&rec)
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			// A truncated record means the process died while writing it.
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if rec.Delete {
+			cache.Expire(rec.Entry.Key)
+			continue
+		}
+		if ttl := rec.Entry.Expiry.Sub(cache.now()); ttl > 0 {
+			cache.Set(rec.Entry.Key, rec.Entry.Value, ttl)
+		} else {
+			cache.Expire(rec.Entry.Key)
+		}
+	}
+}
+
+// journalFiles returns the sequence numbers of the snapshots and logs in
+// dir, in ascending order.
+func journalFiles(dir string) (snapshots, logs []uint64, err error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, f := range files {
+		name := f.Name()
+		for prefix, seqs := range map[string]*[]uint64{journalSnapshot: &snapshots, journalLog: &logs} {
+			if !strings.HasPrefix(name, prefix) {
+				continue
+			}
+			if seq, err := strconv.ParseUint(name[len(prefix):], 10, 64); err == nil {
+				*seqs = append(*seqs, seq)
+			}
+		}
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i] < snapshots[j] })
+	sort.Slice(logs, func(i, j int) bool { return logs[i] < logs[j] })
+	return snapshots, logs, nil
+}
@@ -0,0 +1,136 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJournal(t *testing.T) {
+	dir := t.TempDir()
+
+	c := New[string, int]()
+	j, err := OpenJournal(c, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Set("foo", 1, time.Hour)
+	c.Set("bar", 2, time.Hour)
+	c.Set("baz", 3, time.Hour)
+	c.Expire("bar")
+
+	if err := j.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	c.Set("foo", 4, time.Hour)
+	c.Expire("baz")
+	c.Set("qux", 5, time.Hour)
+	if err := c.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected a snapshot and a log after compaction, got %v", files)
+	}
+
+	recovered := New[string, int]()
+	j, err = OpenJournal(recovered, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	for key, want := range map[string]int{"foo": 4, "qux": 5} {
+		if v, ok := recovered.Get(key); !ok || v != want {
+			t.Fatalf("expected %q to be recovered as %v, got %v, %v", key, want, v, ok)
+		}
+	}
+	for _, key := range []string{"bar", "baz"} {
+		if _, ok := recovered.Get(key); ok {
+			t.Fatalf("expected %q to stay expired", key)
+		}
+	}
+}
+
+func TestJournalWithCipher(t *testing.T) {
+	dir := t.TempDir()
+	aead := newTestAEAD(t)
+
+	c := New[string, string]()
+	j, err := OpenJournalWithCipher(c, dir, aead)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Set("foo", "secret-foo", time.Hour)
+	if err := j.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	c.Set("bar", "secret-bar", time.Hour)
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(data, []byte("secret")) {
+			t.Fatalf("expected %s to be encrypted", file.Name())
+		}
+	}
+
+	// Recover without closing the journal, as after a crash.
+	recovered := New[string, string]()
+	j2, err := OpenJournalWithCipher(recovered, dir, aead)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j2.Close()
+	for key, want := range map[string]string{"foo": "secret-foo", "bar": "secret-bar"} {
+		if v, ok := recovered.Get(key); !ok || v != want {
+			t.Fatalf("expected %q to be recovered as %v, got %v, %v", key, want, v, ok)
+		}
+	}
+	j.Close()
+}
+
+func TestJournalCompactClosed(t *testing.T) {
+	dir := t.TempDir()
+
+	c := New[string, int]()
+	j, err := OpenJournal(c, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Set("foo", 1, time.Hour)
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Compact(); err != ErrClosed {
+		t.Fatalf("expected ErrClosed compacting a closed journal, got %v", err)
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected compaction not to reopen a log, got %v", files)
+	}
+}
@@ -0,0 +1,33 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type jsonEntry[V any] struct {
+	Value     V      `json:"value"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// MarshalJSON encodes the live entries of the cache as a JSON object mapping
+// keys to their value and expiration time, like:
+//
+//	{"key": {"value": ..., "expires_at": "2006-01-02T15:04:05Z07:00"}}
+//
+// Keys and values are encoded like encoding/json encodes map keys and
+// values, which fails for types it does not support.
+func (cache *Cache[K, V]) MarshalJSON() ([]byte, error) {
+	entries := cache.liveEntries()
+	m := make(map[K]jsonEntry[V], len(entries))
+	for _, e := range entries {
+		m[e.Key] = jsonEntry[V]{Value: e.Value, ExpiresAt: e.Expiry.Format(time.RFC3339Nano)}
+	}
+	return json.Marshal(m)
+}
@@ -0,0 +1,36 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := New[int, []string]()
+	c.Clock = fixedClock(now)
+	c.Set(1, []string{"foo"}, time.Hour)
+	c.Set(2, nil, 0)
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"1":{"value":["foo"],"expires_at":"2020-01-01T01:00:00Z"}}`
+	if string(data) != want {
+		t.Fatalf("expected %s, got %s", want, data)
+	}
+
+	unsupported := New[struct{}, int]()
+	unsupported.Set(struct{}{}, 0, time.Hour)
+	if _, err := json.Marshal(unsupported); err == nil {
+		t.Fatal("expected unsupported key types to fail")
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// LoadFunc loads the value of a key from its source of truth, along with the
+// TTL it should be cached with.
+type LoadFunc[K, V any] func(ctx context.Context, key K) (value V, ttl time.Duration, err error)
+
+// BulkLoadFunc loads the values of many keys at once from their source of
+// truth, along with the TTL they should be cached with. Keys missing from
+// the returned map are considered not to exist.
+type BulkLoadFunc[K comparable, V any] func(ctx context.Context, keys []K) (values map[K]V, ttl time.Duration, err error)
+
+// ErrNoLoader is returned by operations needing to load values when the
+// cache has no Loader.
+var ErrNoLoader = errors.New("ttlcache: cache has no loader")
+
+// GetMulti retrieves the values in the cache for the specified keys, loading
+// all the missing ones with a single call to the BulkLoader of the cache, or
+// one call to its Loader per missing key if it has no BulkLoader. Loaded
+// values are set in the cache.
+//
+// Keys that could not be found, even after loading, are missing from the
+// returned map. If loading fails, the values found so far are returned along
+// with the error.
+func (cache *Cache[K, V]) GetMulti(ctx context.Context, keys []K) (map[K]V, error) {
+	values := make(map[K]V, len(keys))
+	var missing []K
+
+	cache.mux.RLock()
+	for _, key := range keys {
+		if value, found := cache.get(key); found {
+			values[key] = value
+		} else {
+			missing = append(missing, key)
+		}
+	}
+	cache.mux.RUnlock()
+
+	if len(missing) == 0 {
+		return values, nil
+	}
+
+	switch {
+	case cache.BulkLoader != nil:
+		start := cache.now()
+		loaded, ttl, err := cache.BulkLoader(ctx, missing)
+		if err != nil {
+			return values, err
+		}
+		elapsed := cache.now().Sub(start)
+		cache.mux.Lock()
+		for key, value := range loaded {
+			cache.setComputed(key, value, ttl, elapsed)
+			values[key] = value
+		}
+		cache.mux.Unlock()
+	case cache.Loader != nil:
+		for _, key := range missing {
+			start := cache.now()
+			value, ttl, err := cache.Loader(ctx, key)
+			if err != nil {
+				return values, err
+			}
+			cache.SetWithRecomputeTime(key, value, ttl, cache.now().Sub(start))
+			values[key] = value
+		}
+	default:
+		return values, ErrNoLoader
+	}
+	return values, nil
+}
+
+// Refresh reloads the specified keys from their source of truth, whether
+// they are cached or not, which is useful to bust the cache after known
+// writes upstream. The keys are loaded with a single call to the BulkLoader
+// of the cache, or one call to its Loader per key if it has no BulkLoader,
+// then replaced all at once; keys missing from what the BulkLoader returns
+// are expired.
+//
+// If loading fails, the cache is left untouched and the error is returned.
+func (cache *Cache[K, V]) Refresh(ctx context.Context, keys ...K) error {
+	type loaded struct {
+		key       K
+		value     V
+		ttl       time.Duration
+		recompute time.Duration
+	}
+	var values []loaded
+	var gone []K
+
+	switch {
+	case cache.BulkLoader != nil:
+		start := cache.now()
+		found, ttl, err := cache.BulkLoader(ctx, keys)
+		if err != nil {
+			return err
+		}
+		elapsed := cache.now().Sub(start)
+		for _, key := range keys {
+			if value, ok := found[key]; ok {
+				values = append(values, loaded{key: key, value: value, ttl: ttl, recompute: elapsed})
+			} else {
+				gone = append(gone, key)
+			}
+		}
+	case cache.Loader != nil:
+		for _, key := range keys {
+			start := cache.now()
+			value, ttl, err := cache.Loader(ctx, key)
+			if err != nil {
+				return err
+			}
+			values = append(values, loaded{key: key, value: value, ttl: ttl, recompute: cache.now().Sub(start)})
+		}
+	default:
+		return ErrNoLoader
+	}
+
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	for _, v := range values {
+		cache.setComputed(v.key, v.value, v.ttl, v.recompute)
+	}
+	for _, key := range gone {
+		if bucket, ok := cache.cache[key]; ok {
+			cache.delete(bucket, EventDelete)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestGetMulti(t *testing.T) {
+	c := New[int, int]()
+	c.Set(1, 1, time.Hour)
+
+	if _, err := c.GetMulti(context.Background(), []int{1, 2}); err != ErrNoLoader {
+		t.Fatalf("expected ErrNoLoader, got %v", err)
+	}
+
+	var calls [][]int
+	c.BulkLoader = func(ctx context.Context, keys []int) (map[int]int, time.Duration, error) {
+		calls = append(calls, keys)
+		values := make(map[int]int)
+		for _, k := range keys {
+			if k != 4 {
+				values[k] = k * 10
+			}
+		}
+		return values, time.Hour, nil
+	}
+	c.Loader = func(ctx context.Context, key int) (int, time.Duration, error) {
+		t.Fatal("expected the bulk loader to be preferred")
+		return 0, 0, nil
+	}
+
+	values, err := c.GetMulti(context.Background(), []int{1, 2, 3, 4})
+	if err != nil {
+		t.Fatalf("expected GetMulti to succeed, got %v", err)
+	}
+	if len(calls) != 1 || len(calls[0]) != 3 {
+		t.Fatalf("expected a single load of the 3 missing keys, got %v", calls)
+	}
+	expected := map[int]int{1: 1, 2: 20, 3: 30}
+	if len(values) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, values)
+	}
+	for k, v := range expected {
+		if values[k] != v {
+			t.Fatalf("expected %v, got %v", expected, values)
+		}
+	}
+	if v, ok := c.Get(3); !ok || v != 30 {
+		t.Fatalf("expected loaded values to be cached, got %v", v)
+	}
+
+	c.BulkLoader = nil
+	c.Loader = func(ctx context.Context, key int) (int, time.Duration, error) {
+		return key * 100, time.Hour, nil
+	}
+	values, _ = c.GetMulti(context.Background(), []int{5, 6})
+	if values[5] != 500 || values[6] != 600 {
+		t.Fatalf("expected keys to be loaded one by one, got %v", values)
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	c := New[int, int]()
+	c.Set(1, 1, time.Hour)
+	c.Set(2, 2, time.Hour)
+	c.Set(3, 3, time.Hour)
+
+	if err := c.Refresh(context.Background(), 1); err != ErrNoLoader {
+		t.Fatalf("expected ErrNoLoader, got %v", err)
+	}
+
+	upstream := map[int]int{1: 10, 3: 30, 4: 40}
+	c.BulkLoader = func(ctx context.Context, keys []int) (map[int]int, time.Duration, error) {
+		values := make(map[int]int)
+		for _, key := range keys {
+			if value, ok := upstream[key]; ok {
+				values[key] = value
+			}
+		}
+		return values, time.Hour, nil
+	}
+	if err := c.Refresh(context.Background(), 1, 2, 4); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[int]int{1: 10, 3: 3, 4: 40} {
+		if v, ok := c.Get(key); !ok || v != want {
+			t.Fatalf("expected %d to be %d, got %v, %v", key, want, v, ok)
+		}
+	}
+	if _, ok := c.Get(2); ok {
+		t.Fatal("expected keys gone upstream to be expired")
+	}
+
+	c.BulkLoader = nil
+	c.Loader = func(ctx context.Context, key int) (int, time.Duration, error) {
+		if key == 3 {
+			return 0, 0, context.DeadlineExceeded
+		}
+		return key * 100, time.Hour, nil
+	}
+	if err := c.Refresh(context.Background(), 1, 3); err != context.DeadlineExceeded {
+		t.Fatalf("expected the load error, got %v", err)
+	}
+	if v, _ := c.Get(1); v != 10 {
+		t.Fatalf("expected a failed refresh to leave the cache untouched, got %v", v)
+	}
+	if err := c.Refresh(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := c.Get(1); v != 100 {
+		t.Fatalf("expected 1 to be reloaded, got %v", v)
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"context"
+	"math"
+	"runtime/metrics"
+	"time"
+)
+
+// Shrinker evicts keys from a cache when the memory used by the process gets
+// close to its memory limit, as set with GOMEMLIMIT or debug.SetMemoryLimit,
+// so that the cache gives way before the process runs out of memory.
+//
+// Memory is only watched while Run is running, on the goroutine that called
+// it; closing the cache waits for Run to return.
+type Shrinker[K comparable, V any] struct {
+	// Threshold is the fraction of the memory limit past which keys get
+	// evicted. It defaults to 0.9.
+	Threshold float64
+
+	// Step is the fraction of the keys evicted at each check while memory
+	// usage is above Threshold. It defaults to 0.05.
+	Step float64
+
+	// Interval is how often memory usage is checked. It defaults to one
+	// second.
+	Interval time.Duration
+
+	// Limit, if non-zero, is used as the memory limit instead of the one of
+	// the process.
+	Limit uint64
+
+	cache *Cache[K, V]
+	usage func() (used, limit uint64)
+}
+
+// NewShrinker returns a shrinker for the cache.
+func (cache *Cache[K, V]) NewShrinker() *Shrinker[K, V] {
+	return &Shrinker[K, V]{
+		cache: cache,
+		usage: memoryUsage,
+	}
+}
+
+// Check evicts keys from the cache if memory usage is above the threshold,
+// and returns how many were evicted. Keys are evicted in the same order as
+// when the cache is at Capacity: by ascending priority, then expiration
+// time.
+func (s *Shrinker[K, V]) Check() int {
+	used, limit := s.usage()
+	if s.Limit != 0 {
+		limit = s.Limit
+	}
+	threshold := s.Threshold
+	if threshold <= 0 {
+		threshold = 0.9
+	}
+	if limit == 0 || float64(used) < threshold*float64(limit) {
+		return 0
+	}
+	step := s.Step
+	if step <= 0 {
+		step = 0.05
+	}
+
+	cache := s.cache
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	n := int(math.Ceil(step * float64(len(cache.cache))))
+	var evicted int
+	for evicted < n && cache.evict() {
+		evicted++
+	}
+	return evicted
+}
+
+// Run checks memory usage every Interval until ctx is done, in which case
+// the context error is returned, or until the cache gets closed, in which
+// case ErrClosed is returned.
+func (s *Shrinker[K, V]) Run(ctx context.Context) error {
+	done, _, err := s.cache.startBackground()
+	if err != nil {
+		return err
+	}
+	defer s.cache.background.Done()
+
+	interval := s.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.Check()
+		case <-done:
+			return ErrClosed
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// memoryUsage returns the memory mapped by the Go runtime and not released
+// to the operating system, which is what the memory limit applies to, and
+// the memory limit itself, or zero if there is none.
+func memoryUsage() (used, limit uint64) {
+	samples := []metrics.Sample{
+		{Name: "/memory/classes/total:bytes"},
+		{Name: "/memory/classes/heap/released:bytes"},
+		{Name: "/gc/gomemlimit:bytes"},
+	}
+	metrics.Read(samples)
+	for _, s := range samples {
+		if s.Value.Kind() != metrics.KindUint64 {
+			// The memory limit is only supported as of Go 1.19.
+			return 0, 0
+		}
+	}
+	used = samples[0].Value.Uint64() - samples[1].Value.Uint64()
+	limit = samples[2].Value.Uint64()
+	if limit == math.MaxInt64 {
+		limit = 0
+	}
+	return used, limit
+}
@@ -0,0 +1,52 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestShrinker(t *testing.T) {
+	c := New[int, int]()
+	for i := 0; i < 100; i++ {
+		c.Set(i, i, time.Duration(i+1)*time.Hour)
+	}
+
+	s := c.NewShrinker()
+	used := uint64(80)
+	s.usage = func() (uint64, uint64) { return used, 100 }
+
+	if n := s.Check(); n != 0 {
+		t.Fatalf("expected no eviction below the threshold, got %d", n)
+	}
+	used = 95
+	if n := s.Check(); n != 5 {
+		t.Fatalf("expected 5 keys to be evicted, got %d", n)
+	}
+	for i := 0; i < 10; i++ {
+		if _, ok := c.Get(i); ok != (i >= 5) {
+			t.Fatalf("unexpected presence of key %d: %v", i, ok)
+		}
+	}
+
+	s.Limit = 1000
+	if n := s.Check(); n != 0 {
+		t.Fatalf("expected the limit override to be used, got %d evictions", n)
+	}
+
+	// The real memory usage should never get close to no limit at all.
+	if used, limit := memoryUsage(); limit != 0 && used >= limit {
+		t.Fatalf("unexpected memory usage %d over limit %d", used, limit)
+	}
+
+	go c.Close(context.Background())
+	if err := s.Run(context.Background()); err != ErrClosed {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+}
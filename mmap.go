@@ -0,0 +1,188 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package ttlcache
+
+import (
+	"errors"
+	"os"
+	"sort"
+	"sync"
+	"syscall"
+)
+
+// ErrMmapFull is reported when a MmapBackend has no room left for a value.
+var ErrMmapFull = errors.New("ttlcache: memory-mapped file is full")
+
+// MmapBackend is a Backend for byte slices keeping the values larger than a
+// threshold in a memory-mapped file rather than on the Go heap, so that
+// large blobs neither weigh on the garbage collector nor count towards the
+// heap size. Smaller values are kept in memory.
+//
+// Values loaded from the file alias the mapping, which saves copying them:
+// they must not be modified, and are only valid until their key is set again
+// or removed, after which their memory may be reused for other values. The
+// values of keys removed from a cache using the backend, as passed to
+// OnExpire or returned by Expire, are copied out of the file first, and stay
+// valid.
+type MmapBackend[K comparable] struct {
+	// OnError, if set, gets called whenever a value cannot be stored, in
+	// which case it is dropped.
+	OnError func(err error)
+
+	threshold int
+	file      *os.File
+	data      []byte
+	small     map[K][]byte
+	large     map[K]mmapExtent
+	free      []mmapExtent // sorted by offset
+	mux       sync.RWMutex
+}
+
+type mmapExtent struct {
+	off, len int
+}
+
+// NewMmapBackend returns a MmapBackend keeping values larger than threshold
+// bytes in the file at path, which gets created or truncated to size bytes.
+// The file is scratch space: its contents do not outlive the backend.
+func NewMmapBackend[K comparable](path string, size, threshold int) (*MmapBackend[K], error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Truncate(int64(size)); err != nil {
+		f.Close()
+		return nil, err
+	}
+	data, err := syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &MmapBackend[K]{
+		threshold: threshold,
+		file:      f,
+		data:      data,
+		small:     make(map[K][]byte),
+		large:     make(map[K]mmapExtent),
+		free:      []mmapExtent{{0, size}},
+	}, nil
+}
+
+func (b *MmapBackend[K]) Load(key K) (value []byte, found bool) {
+	b.mux.RLock()
+	defer b.mux.RUnlock()
+
+	if ext, ok := b.large[key]; ok {
+		return b.data[ext.off : ext.off+ext.len : ext.off+ext.len], true
+	}
+	value, found = b.small[key]
+	return value, found
+}
+
+func (b *MmapBackend[K]) Store(key K, value []byte) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	b.remove(key)
+	if len(value) <= b.threshold {
+		b.small[key] = value
+		return
+	}
+	ext, ok := b.alloc(len(value))
+	if !ok {
+		if onError := b.OnError; onError != nil {
+			onError(ErrMmapFull)
+		}
+		return
+	}
+	copy(b.data[ext.off:], value)
+	b.large[key] = ext
+}
+
+func (b *MmapBackend[K]) Delete(key K) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	b.remove(key)
+}
+
+// Close unmaps and closes the file. Values loaded from the file become
+// invalid, and the backend must not be used anymore. Closing a cache using
+// the backend closes it too; closing it again does nothing.
+func (b *MmapBackend[K]) Close() error {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	if b.data == nil {
+		return nil
+	}
+
+	err := syscall.Munmap(b.data)
+	if cerr := b.file.Close(); err == nil {
+		err = cerr
+	}
+	b.data = nil
+	b.large = nil
+	return err
+}
+
+func (b *MmapBackend[K]) take(key K) (value []byte, found bool) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	if ext, ok := b.large[key]; ok {
+		value, found = append([]byte(nil), b.data[ext.off:ext.off+ext.len]...), true
+	} else {
+		value, found = b.small[key]
+	}
+	b.remove(key)
+	return value, found
+}
+
+func (b *MmapBackend[K]) remove(key K) {
+	delete(b.small, key)
+	if ext, ok := b.large[key]; ok {
+		delete(b.large, key)
+		b.release(ext)
+	}
+}
+
+// alloc finds room for n bytes, first-fit.
+func (b *MmapBackend[K]) alloc(n int) (mmapExtent, bool) {
+	for i, ext := range b.free {
+		if ext.len < n {
+			continue
+		}
+		if ext.len == n {
+			b.free = append(b.free[:i], b.free[i+1:]...)
+		} else {
+			b.free[i] = mmapExtent{ext.off + n, ext.len - n}
+		}
+		return mmapExtent{ext.off, n}, true
+	}
+	return mmapExtent{}, false
+}
+
+// release gives back an extent, merging it with its free neighbours.
+func (b *MmapBackend[K]) release(ext mmapExtent) {
+	i := sort.Search(len(b.free), func(i int) bool { return b.free[i].off > ext.off })
+	if i > 0 && b.free[i-1].off+b.free[i-1].len == ext.off {
+		i--
+		ext = mmapExtent{b.free[i].off, b.free[i].len + ext.len}
+		b.free = append(b.free[:i], b.free[i+1:]...)
+	}
+	if i < len(b.free) && ext.off+ext.len == b.free[i].off {
+		ext.len += b.free[i].len
+		b.free = append(b.free[:i], b.free[i+1:]...)
+	}
+	b.free = append(b.free, mmapExtent{})
+	copy(b.free[i+1:], b.free[i:])
+	b.free[i] = ext
+}
@@ -0,0 +1,83 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package ttlcache
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMmapBackend(t *testing.T) {
+	backend, err := NewMmapBackend[int](filepath.Join(t.TempDir(), "values"), 1000, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+	var errs []error
+	backend.OnError = func(err error) {
+		errs = append(errs, err)
+	}
+
+	c := NewWithBackend[int, []byte](backend)
+	c.Set(0, []byte("small"), time.Hour)
+	for i := 1; i <= 3; i++ {
+		c.Set(i, bytes.Repeat([]byte{byte(i)}, 300), time.Hour)
+	}
+	if len(backend.small) != 1 || len(backend.large) != 3 {
+		t.Fatalf("expected 1 small and 3 large values, got %d and %d", len(backend.small), len(backend.large))
+	}
+
+	c.Set(4, make([]byte, 300), time.Hour)
+	if len(errs) != 1 || errs[0] != ErrMmapFull {
+		t.Fatalf("expected the file to be full, got %v", errs)
+	}
+
+	// Freeing two neighbouring extents makes room for a larger value.
+	c.Expire(1)
+	c.Expire(2)
+	c.Set(5, bytes.Repeat([]byte{5}, 600), time.Hour)
+	for key, want := range map[int][]byte{
+		0: []byte("small"),
+		3: bytes.Repeat([]byte{3}, 300),
+		5: bytes.Repeat([]byte{5}, 600),
+	} {
+		if v, ok := c.Get(key); !ok || !bytes.Equal(v, want) {
+			t.Fatalf("unexpected value for key %d: %v, %v", key, v, ok)
+		}
+	}
+	if len(errs) != 1 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+}
+
+func TestMmapBackendExpire(t *testing.T) {
+	backend, err := NewMmapBackend[int](filepath.Join(t.TempDir(), "values"), 1000, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+
+	c := NewWithBackend[int, []byte](backend)
+	var expired []byte
+	c.OnExpire = func(key int, value []byte) { expired = value }
+	c.Set(1, bytes.Repeat([]byte{1}, 300), time.Hour)
+	old, _ := c.Expire(1)
+	// The freed extent gets reused for the next value.
+	c.Set(2, bytes.Repeat([]byte{2}, 300), time.Hour)
+
+	want := bytes.Repeat([]byte{1}, 300)
+	if !bytes.Equal(old, want) {
+		t.Fatal("expected the value returned by Expire to survive the next Set")
+	}
+	if !bytes.Equal(expired, want) {
+		t.Fatal("expected the value passed to OnExpire to survive the next Set")
+	}
+}
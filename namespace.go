@@ -0,0 +1,79 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"strings"
+	"time"
+)
+
+// Namespace is a view of a cache with string keys, operating on the keys
+// starting with a given prefix. It lets several components share a single
+// cache without stepping on each other's keys.
+type Namespace[V any] struct {
+	cache  *Cache[string, V]
+	prefix string
+}
+
+// NewNamespace returns a view of the cache whose keys are implicitly
+// prefixed with prefix.
+//
+// Namespaces do not add a separator between the prefix and the keys; callers
+// are expected to include one in the prefix if needed, like "users:".
+func NewNamespace[V any](cache *Cache[string, V], prefix string) *Namespace[V] {
+	return &Namespace[V]{cache: cache, prefix: prefix}
+}
+
+// Namespace returns a view of the namespace whose keys are implicitly
+// prefixed with prefix, in addition to the prefix of the namespace.
+func (ns *Namespace[V]) Namespace(prefix string) *Namespace[V] {
+	return &Namespace[V]{cache: ns.cache, prefix: ns.prefix + prefix}
+}
+
+// Prefix returns the prefix of the namespace in the underlying cache.
+func (ns *Namespace[V]) Prefix() string {
+	return ns.prefix
+}
+
+// Set is like Cache.Set, within the namespace.
+func (ns *Namespace[V]) Set(key string, value V, ttl time.Duration) {
+	ns.cache.Set(ns.prefix+key, value, ttl)
+}
+
+// Get is like Cache.Get, within the namespace.
+func (ns *Namespace[V]) Get(key string) (value V, found bool) {
+	return ns.cache.Get(ns.prefix + key)
+}
+
+// Expire is like Cache.Expire, within the namespace.
+func (ns *Namespace[V]) Expire(key string) (value V, found bool) {
+	return ns.cache.Expire(ns.prefix + key)
+}
+
+// Clear expires all the keys in the namespace at once, and returns how many
+// were expired. In caches created by NewOrdered, only the keys of the
+// namespace are visited; otherwise, Clear goes through the whole cache.
+func (ns *Namespace[V]) Clear() int {
+	cache := ns.cache
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	var matched []*cacheBucket[string, V]
+	if cache.keyIndex != nil && cache.natural {
+		// Keys sharing a prefix are next to each other in lexicographic order.
+		for n := cache.keyIndex.Seek(ns.prefix); n != nil && strings.HasPrefix(n.val, ns.prefix); n = n.Next() {
+			matched = append(matched, cache.cache[n.val])
+		}
+	} else {
+		for _, bucket := range cache.expireList.elts {
+			if strings.HasPrefix(bucket.key, ns.prefix) {
+				matched = append(matched, bucket)
+			}
+		}
+	}
+	return cache.deleteAll(matched, EventDelete)
+}
@@ -0,0 +1,69 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNamespace(t *testing.T) {
+	c := New[string, int]()
+	users := NewNamespace(c, "users:")
+	admins := users.Namespace("admins:")
+	groups := NewNamespace(c, "groups:")
+
+	users.Set("1", 1, time.Hour)
+	admins.Set("1", 2, time.Hour)
+	groups.Set("1", 3, time.Hour)
+
+	if v, ok := c.Get("users:admins:1"); !ok || v != 2 {
+		t.Fatalf("expected admins:1 to be stored under its full key, got %v", v)
+	}
+	if v, ok := users.Get("1"); !ok || v != 1 {
+		t.Fatalf("expected users:1 to be 1, got %v", v)
+	}
+
+	if n := users.Clear(); n != 2 {
+		t.Fatalf("expected 2 keys to be cleared, got %v", n)
+	}
+	if _, ok := admins.Get("1"); ok {
+		t.Fatal("expected nested namespace to be cleared too")
+	}
+	if v, ok := groups.Get("1"); !ok || v != 3 {
+		t.Fatalf("expected groups:1 to survive, got %v", v)
+	}
+
+	groups.Expire("1")
+	if _, ok := c.Get("groups:1"); ok {
+		t.Fatal("expected groups:1 to have expired")
+	}
+}
+
+func TestNamespaceClear(t *testing.T) {
+	reversed := New[string, int]()
+	reversed.SetKeyOrder(func(a, b string) bool { return a > b })
+	for _, c := range []*Cache[string, int]{New[string, int](), NewOrdered[string, int](), reversed} {
+		for _, key := range []string{"user", "users:", "users:1", "users:2", "users;1", "usert:1", "a"} {
+			c.Set(key, 0, time.Hour)
+		}
+		if n := NewNamespace(c, "users:").Clear(); n != 3 {
+			t.Fatalf("expected 3 keys to be cleared, got %v", n)
+		}
+		for _, key := range []string{"user", "users;1", "usert:1", "a"} {
+			if !c.Contains(key) {
+				t.Fatalf("expected %q outside the namespace to survive", key)
+			}
+		}
+		c.mux.Lock()
+		err := c.checkInvariants()
+		c.mux.Unlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"strings"
+)
+
+// Ordered is a constraint for the types whose values can be compared with
+// the < operator.
+type Ordered interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64 | ~string
+}
+
+// NewOrdered returns a cache keeping its keys in ascending order, enabling
+// Range queries. See SetKeyOrder.
+func NewOrdered[K Ordered, V any]() *Cache[K, V] {
+	cache := New[K, V]()
+	cache.SetKeyOrder(func(a, b K) bool { return a < b })
+	cache.natural = true
+	return cache
+}
+
+// SetKeyOrder makes the cache maintain an index of its keys ordered by less,
+// enabling Range queries, at the price of slower insertions and removals.
+// less must define a strict total order over keys. Passing nil drops the
+// index.
+func (cache *Cache[K, V]) SetKeyOrder(less func(a, b K) bool) {
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	cache.setKeyOrder(less)
+}
+
+func (cache *Cache[K, V]) setKeyOrder(less func(a, b K) bool) {
+	cache.natural = false
+	if less == nil {
+		cache.keyIndex = nil
+		return
+	}
+	cache.keyIndex = newSkiplist(less)
+	for key := range cache.cache {
+		cache.keyIndex.Insert(key)
+	}
+}
+
+// Range calls fn for each entry in the cache whose key is within [from, to),
+// in ascending key order, until fn returns false. Entries that expired but
+// were not flushed yet are included.
+//
+// Range panics if the cache has no key order; see SetKeyOrder. fn is called
+// with the cache locked, and must not call methods of the cache.
+func (cache *Cache[K, V]) Range(from, to K, fn func(e Entry[K, V]) bool) {
+	cache.mux.RLock()
+	defer cache.mux.RUnlock()
+
+	less := cache.orderedIndex().less
+	cache.rangeFrom(from, func(e Entry[K, V]) bool {
+		return less(e.Key, to) && fn(e)
+	})
+}
+
+// RangeFrom calls fn for each entry in the cache whose key is at or after
+// from, in ascending key order, until fn returns false. It is otherwise like
+// Range.
+func (cache *Cache[K, V]) RangeFrom(from K, fn func(e Entry[K, V]) bool) {
+	cache.mux.RLock()
+	defer cache.mux.RUnlock()
+
+	cache.rangeFrom(from, fn)
+}
+
+func (cache *Cache[K, V]) rangeFrom(from K, fn func(e Entry[K, V]) bool) {
+	for n := cache.orderedIndex().Seek(from); n != nil; n = n.Next() {
+		if !fn(cache.entry(cache.cache[n.val])) {
+			return
+		}
+	}
+}
+
+func (cache *Cache[K, V]) orderedIndex() *skiplist[K] {
+	if cache.keyIndex == nil {
+		panic("ttlcache: cache has no key order")
+	}
+	return cache.keyIndex
+}
+
+// RangePrefix calls fn for each entry in the cache whose key starts with
+// prefix, in ascending key order, until fn returns false. The keys of the
+// cache must be ordered lexicographically, as done by NewOrdered. It is
+// otherwise like Range.
+func RangePrefix[V any](cache *Cache[string, V], prefix string, fn func(e Entry[string, V]) bool) {
+	cache.RangeFrom(prefix, func(e Entry[string, V]) bool {
+		return strings.HasPrefix(e.Key, prefix) && fn(e)
+	})
+}
@@ -0,0 +1,59 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestRange(t *testing.T) {
+	c := NewOrdered[int, int]()
+	for _, i := range rand.Perm(100) {
+		c.Set(i, i, time.Hour)
+	}
+	c.Expire(15)
+
+	var keys []int
+	c.Range(10, 20, func(e Entry[int, int]) bool {
+		keys = append(keys, e.Key)
+		return true
+	})
+	expected := []int{10, 11, 12, 13, 14, 16, 17, 18, 19}
+	if len(keys) != len(expected) {
+		t.Fatalf("expected keys %v, got %v", expected, keys)
+	}
+	for i := range expected {
+		if keys[i] != expected[i] {
+			t.Fatalf("expected keys %v, got %v", expected, keys)
+		}
+	}
+
+	// Enabling the index afterwards indexes existing keys.
+	s := New[string, int]()
+	for _, key := range []string{"user:2", "group:1", "user:1", "users"} {
+		s.Set(key, 0, time.Hour)
+	}
+	s.SetKeyOrder(func(a, b string) bool { return a < b })
+
+	var prefixed []string
+	RangePrefix(s, "user:", func(e Entry[string, int]) bool {
+		prefixed = append(prefixed, e.Key)
+		return true
+	})
+	if len(prefixed) != 2 || prefixed[0] != "user:1" || prefixed[1] != "user:2" {
+		t.Fatalf("expected user:1 and user:2, got %v", prefixed)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected Range to panic without a key order")
+		}
+	}()
+	New[int, int]().Range(0, 1, func(Entry[int, int]) bool { return true })
+}
@@ -0,0 +1,55 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is reported to OnError when a user callback panics.
+type PanicError struct {
+	// Callback is the name of the callback that panicked, like "OnExpire".
+	Callback string
+
+	// Value is the value the callback panicked with.
+	Value any
+
+	// Stack is the stack trace of the goroutine at the time of the panic.
+	Stack []byte
+}
+
+func (err *PanicError) Error() string {
+	return fmt.Sprintf("ttlcache: %s panicked: %v", err.Callback, err.Value)
+}
+
+// guard calls fn, which calls the specified user callback. If the cache has
+// an OnError handler, panics in fn are recovered and reported to it, and
+// guard returns false.
+func (cache *Cache[K, V]) guard(callback string, fn func()) (ok bool) {
+	onError := cache.OnError
+	if onError == nil {
+		fn()
+		return true
+	}
+
+	defer func() {
+		if v := recover(); v != nil {
+			onError(&PanicError{Callback: callback, Value: v, Stack: debug.Stack()})
+			ok = false
+		}
+	}()
+	fn()
+	return true
+}
+
+// report passes err to OnError, if set.
+func (cache *Cache[K, V]) report(err error) {
+	if onError := cache.OnError; onError != nil {
+		onError(err)
+	}
+}
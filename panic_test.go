@@ -0,0 +1,57 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPanicIsolation(t *testing.T) {
+	c := New[string, int]()
+	var errs []error
+	c.OnError = func(err error) {
+		errs = append(errs, err)
+	}
+	c.OnExpire = func(key string, value int) {
+		panic("boom")
+	}
+	c.Renew = func(ctx context.Context, key string, value int) (int, time.Duration, bool) {
+		panic("boom")
+	}
+
+	c.Set("foo", 1, time.Hour)
+	c.Set("bar", 2, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	c.Expire("foo")
+	if n := c.Flush(); n != 1 {
+		t.Fatalf("expected bar to be flushed despite panics, got %v", n)
+	}
+	if len(errs) != 3 {
+		t.Fatalf("expected 3 reported panics, got %v", errs)
+	}
+	var perr *PanicError
+	if !errors.As(errs[0], &perr) || perr.Callback != "OnExpire" || perr.Value != "boom" {
+		t.Fatalf("expected OnExpire panic to be reported, got %v", errs[0])
+	}
+	if !errors.As(errs[1], &perr) || perr.Callback != "Renew" {
+		t.Fatalf("expected Renew panic to be reported, got %v", errs[1])
+	}
+
+	// The cache must still be usable afterwards.
+	c.OnExpire = nil
+	c.Set("baz", 3, time.Hour)
+	if v, ok := c.Get("baz"); !ok || v != 3 {
+		t.Fatalf("expected baz to be 3, got %v", v)
+	}
+	if len(c.cache) != 1 || c.expireList.Len() != 1 {
+		t.Fatalf("expected a single key left, got %v", c.cache)
+	}
+}
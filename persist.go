@@ -0,0 +1,148 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// snapshotMagic starts every persisted snapshot.
+const snapshotMagic = "ttlcache"
+
+// snapshotFormat is the version of the layout of persisted snapshots, which
+// gets bumped whenever it changes incompatibly. Older formats stay readable.
+const snapshotFormat = 1
+
+var (
+	// ErrSnapshotFormat is returned when restoring from data that is not a
+	// persisted snapshot.
+	ErrSnapshotFormat = errors.New("ttlcache: not a snapshot")
+
+	// ErrSnapshotVersion is returned when restoring a snapshot written by a
+	// newer version of the package, or of the program when no migration
+	// leads from its SnapshotVersion to the current one.
+	ErrSnapshotVersion = errors.New("ttlcache: unsupported snapshot version")
+)
+
+// SnapshotMigration migrates the payload of a persisted snapshot from one
+// version of a program to the next, by reading the old payload from r and
+// returning a reader of the new one. See Cache.SnapshotMigrations.
+type SnapshotMigration func(r io.Reader) (io.Reader, error)
+
+// WriteTo persists the snapshot to w, and returns how many bytes were
+// written. The snapshot starts with a header holding the version of the
+// format and the SnapshotVersion of the cache, followed by the entries
+// encoded with encoding/gob, so keys and values must be encodable.
+//
+// gob tolerates fields being added to or removed from structs, so values
+// can usually evolve without migrations; other changes need the
+// SnapshotVersion of the cache to be bumped, along with a migration.
+func (s *Snapshot[K, V]) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+
+	var header [len(snapshotMagic) + 2*binary.MaxVarintLen64]byte
+	n := copy(header[:], snapshotMagic)
+	n += binary.PutUvarint(header[n:], snapshotFormat)
+	n += binary.PutUvarint(header[n:], uint64(s.version))
+	bw.Write(header[:n])
+
+	enc := gob.NewEncoder(bw)
+	if err := enc.Encode(len(s.entries)); err != nil {
+		return cw.n, err
+	}
+	for i := range s.entries {
+		if err := enc.Encode(&s.entries[i]); err != nil {
+			return cw.n, err
+		}
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// Restore reads a snapshot persisted with Snapshot.WriteTo from r, and sets
+// its entries in the cache with their original expiration times, skipping
+// the ones that expired since. Entries already in the cache are kept,
+// unless the snapshot holds the same keys.
+//
+// Snapshots written with an older SnapshotVersion go through the matching
+// SnapshotMigrations first, one version at a time.
+func (cache *Cache[K, V]) Restore(r io.Reader) error {
+	br := bufio.NewReader(r)
+
+	magic := make([]byte, len(snapshotMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != snapshotMagic {
+		return ErrSnapshotFormat
+	}
+	format, err := binary.ReadUvarint(br)
+	if err != nil {
+		return ErrSnapshotFormat
+	}
+	if format > snapshotFormat {
+		return fmt.Errorf("%w: format %d", ErrSnapshotVersion, format)
+	}
+	version, err := binary.ReadUvarint(br)
+	if err != nil {
+		return ErrSnapshotFormat
+	}
+
+	var payload io.Reader = br
+	for v := int(version); v != cache.SnapshotVersion; v++ {
+		migrate, ok := cache.SnapshotMigrations[v]
+		if !ok || v > cache.SnapshotVersion {
+			return fmt.Errorf("%w: no migration from version %d", ErrSnapshotVersion, v)
+		}
+		if payload, err = migrate(payload); err != nil {
+			return err
+		}
+	}
+
+	dec := gob.NewDecoder(payload)
+	var n int
+	if err := dec.Decode(&n); err != nil {
+		return err
+	}
+	if n < 0 {
+		return ErrSnapshotFormat
+	}
+	// The count comes from the snapshot, which may be corrupt: entries are
+	// appended as they get decoded rather than allocated upfront.
+	size := n
+	if size > maxSnapshotPrealloc {
+		size = maxSnapshotPrealloc
+	}
+	entries := make([]Entry[K, V], 0, size)
+	for i := 0; i < n; i++ {
+		var e Entry[K, V]
+		if err := dec.Decode(&e); err != nil {
+			return err
+		}
+		entries = append(entries, e)
+	}
+	cache.Warm(entries)
+	return nil
+}
+
+// maxSnapshotPrealloc is the most entries Restore allocates room for before
+// decoding them.
+const maxSnapshotPrealloc = 4096
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,118 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRestore(t *testing.T) {
+	c := New[string, int]()
+	c.Set("foo", 1, time.Hour)
+	c.Set("bar", 2, time.Minute)
+
+	var buf bytes.Buffer
+	n, err := c.Snapshot().WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(buf.Len()) {
+		t.Fatalf("expected WriteTo to report %d bytes, got %d", buf.Len(), n)
+	}
+
+	restored := New[string, int]()
+	if err := restored.Restore(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"foo", "bar"} {
+		want, _ := c.Get(key)
+		if v, ok := restored.Get(key); !ok || v != want {
+			t.Fatalf("expected %q to be restored to %v, got %v, %v", key, want, v, ok)
+		}
+		if restored.cache[key].expiry != c.cache[key].expiry {
+			t.Fatalf("expected %q to keep its expiration time", key)
+		}
+	}
+
+	if err := restored.Restore(strings.NewReader("garbage")); err != ErrSnapshotFormat {
+		t.Fatalf("expected ErrSnapshotFormat, got %v", err)
+	}
+	if err := restored.Restore(strings.NewReader(snapshotMagic + "\x02\x00")); !errors.Is(err, ErrSnapshotVersion) {
+		t.Fatalf("expected ErrSnapshotVersion for a newer format, got %v", err)
+	}
+}
+
+func TestSnapshotMigrations(t *testing.T) {
+	old := New[string, int]()
+	old.Set("foo", 42, time.Hour)
+	var buf bytes.Buffer
+	if _, err := old.Snapshot().WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	// Values used to be ints, and are now strings.
+	c := New[string, string]()
+	c.SnapshotVersion = 1
+	c.SnapshotMigrations = map[int]SnapshotMigration{
+		0: func(r io.Reader) (io.Reader, error) {
+			dec := gob.NewDecoder(r)
+			var n int
+			if err := dec.Decode(&n); err != nil {
+				return nil, err
+			}
+			var out bytes.Buffer
+			enc := gob.NewEncoder(&out)
+			enc.Encode(n)
+			for i := 0; i < n; i++ {
+				var e Entry[string, int]
+				if err := dec.Decode(&e); err != nil {
+					return nil, err
+				}
+				enc.Encode(Entry[string, string]{Key: e.Key, Value: strconv.Itoa(e.Value), Expiry: e.Expiry})
+			}
+			return &out, nil
+		},
+	}
+
+	data := buf.Bytes()
+	if err := c.Restore(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := c.Get("foo"); v != "42" {
+		t.Fatalf("expected foo to be migrated, got %q", v)
+	}
+
+	c.SnapshotVersion = 2
+	if err := c.Restore(bytes.NewReader(data)); !errors.Is(err, ErrSnapshotVersion) {
+		t.Fatalf("expected a missing migration to fail, got %v", err)
+	}
+}
+
+func TestRestoreCorrupt(t *testing.T) {
+	for _, n := range []int{-1, 1 << 30} {
+		var buf bytes.Buffer
+		buf.WriteString(snapshotMagic + "\x01\x00")
+		enc := gob.NewEncoder(&buf)
+		enc.Encode(n)
+		enc.Encode(Entry[string, int]{Key: "foo", Value: 1, Expiry: time.Now().Add(time.Hour)})
+
+		c := New[string, int]()
+		if err := c.Restore(&buf); err == nil {
+			t.Fatalf("expected a snapshot claiming %d entries to fail to restore", n)
+		}
+		if _, ok := c.Get("foo"); ok {
+			t.Fatal("expected a corrupt snapshot to leave the cache untouched")
+		}
+	}
+}
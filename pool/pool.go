@@ -0,0 +1,205 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package pool implements pools of idle connections, or of any other
+// resource that can be dialed and closed, on top of ttlcache.
+//
+// Every idle resource is its own cache entry living for the idle TTL of the
+// pool, and gets closed by the expiry callback of the cache once it runs
+// out, unless it was handed out again first. Resources are handed out most
+// recently used first, so that the ones left over in quiet times age out.
+package pool
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"snai.pe/go-ttlcache"
+)
+
+// Dialer establishes a new resource for the specified address.
+type Dialer[K comparable, C io.Closer] func(ctx context.Context, addr K) (C, error)
+
+// Pool holds idle resources keyed by address, dialing new ones on demand.
+type Pool[K comparable, C io.Closer] struct {
+	// MaxIdle is the maximum number of idle resources kept per address.
+	// When a resource is put back past this limit, the oldest idle one is
+	// closed. Zero means no limit.
+	MaxIdle int
+
+	// Check, if set, is called on idle resources before handing them out.
+	// Resources it returns an error for are closed and discarded, and
+	// the next one is tried, until a new one gets dialed.
+	Check func(ctx context.Context, conn C) error
+
+	dial Dialer[K, C]
+	ttl  time.Duration
+	idle *ttlcache.Cache[idleKey[K], *idleConn[C]]
+	lifo map[K][]uint64
+	next uint64
+	mux  sync.Mutex
+}
+
+type idleKey[K comparable] struct {
+	addr K
+	id   uint64
+}
+
+type idleConn[C io.Closer] struct {
+	conn  C
+	taken int32
+}
+
+// take claims the resource, and reports whether it was still idle. Once
+// taken, the resource is no longer closed on expiry.
+func (c *idleConn[C]) take() bool {
+	return atomic.CompareAndSwapInt32(&c.taken, 0, 1)
+}
+
+// New returns a pool dialing resources with dial, and closing those that
+// stay idle for longer than idleTTL.
+func New[K comparable, C io.Closer](dial Dialer[K, C], idleTTL time.Duration) *Pool[K, C] {
+	if idleTTL <= 0 {
+		panic("pool: idle TTL must be positive")
+	}
+	p := &Pool[K, C]{
+		dial: dial,
+		ttl:  idleTTL,
+		idle: ttlcache.New[idleKey[K], *idleConn[C]](),
+		lifo: make(map[K][]uint64),
+	}
+	p.idle.ExpireOnClose = true
+	p.idle.OnExpire = func(_ idleKey[K], c *idleConn[C]) {
+		if c.take() {
+			c.conn.Close()
+		}
+	}
+	return p
+}
+
+// Get returns an idle resource for addr, or dials a new one if there is
+// none left that passes Check.
+func (p *Pool[K, C]) Get(ctx context.Context, addr K) (C, error) {
+	for {
+		conn, ok, err := p.takeIdle(addr)
+		if err != nil || !ok {
+			if err == nil {
+				conn, err = p.dial(ctx, addr)
+			}
+			return conn, err
+		}
+		if p.Check == nil {
+			return conn, nil
+		}
+		if err := p.Check(ctx, conn); err == nil {
+			return conn, nil
+		}
+		conn.Close()
+	}
+}
+
+// takeIdle claims the most recently put back resource for addr that did not
+// expire yet.
+func (p *Pool[K, C]) takeIdle(addr K) (conn C, found bool, err error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	if p.lifo == nil {
+		return conn, false, ttlcache.ErrClosed
+	}
+	// The cache only expires entries on write; flush first so that no
+	// expired resource is handed out, and expired ones get closed.
+	p.idle.Flush()
+	ids := p.lifo[addr]
+	defer func() { p.setIdle(addr, ids) }()
+	for len(ids) > 0 {
+		key := idleKey[K]{addr: addr, id: ids[len(ids)-1]}
+		ids = ids[:len(ids)-1]
+		c, ok := p.idle.Get(key)
+		if ok && c.take() {
+			p.idle.Expire(key)
+			return c.conn, true, nil
+		}
+	}
+	return conn, false, nil
+}
+
+// Put hands conn back to the pool, to be reused for addr until it stays
+// idle for too long. Resources put back once the pool is closed are closed
+// right away.
+func (p *Pool[K, C]) Put(addr K, conn C) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	if p.lifo == nil {
+		conn.Close()
+		return
+	}
+
+	// Idle resources all live for the same TTL, so the ones that expired
+	// since are the oldest.
+	p.idle.Flush()
+	ids := p.lifo[addr]
+	for len(ids) > 0 {
+		if _, ok := p.idle.Get(idleKey[K]{addr: addr, id: ids[0]}); ok {
+			break
+		}
+		ids = ids[1:]
+	}
+	if p.MaxIdle > 0 {
+		for len(ids) >= p.MaxIdle {
+			p.idle.Expire(idleKey[K]{addr: addr, id: ids[0]})
+			ids = ids[1:]
+		}
+	}
+
+	p.next++
+	p.idle.Set(idleKey[K]{addr: addr, id: p.next}, &idleConn[C]{conn: conn}, p.ttl)
+	p.setIdle(addr, append(ids, p.next))
+}
+
+func (p *Pool[K, C]) setIdle(addr K, ids []uint64) {
+	if len(ids) == 0 {
+		delete(p.lifo, addr)
+	} else {
+		p.lifo[addr] = ids
+	}
+}
+
+// Idle returns the number of idle resources held for addr.
+func (p *Pool[K, C]) Idle(addr K) int {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	p.idle.Flush()
+	n := 0
+	for _, id := range p.lifo[addr] {
+		if _, ok := p.idle.Get(idleKey[K]{addr: addr, id: id}); ok {
+			n++
+		}
+	}
+	return n
+}
+
+// Run closes idle resources as soon as they expire until ctx is done, in
+// which case the context error is returned, or until the pool gets closed,
+// in which case ttlcache.ErrClosed is returned. Without it, expired
+// resources are only closed on the next use of the pool.
+func (p *Pool[K, C]) Run(ctx context.Context) error {
+	return p.idle.NewJanitor().Run(ctx)
+}
+
+// Close closes all the idle resources of the pool. Resources handed out are
+// left alone, and get closed when put back.
+func (p *Pool[K, C]) Close(ctx context.Context) error {
+	p.mux.Lock()
+	p.lifo = nil
+	p.mux.Unlock()
+	return p.idle.Close(ctx)
+}
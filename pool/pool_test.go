@@ -0,0 +1,115 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package pool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"snai.pe/go-ttlcache"
+)
+
+type testConn struct {
+	addr   string
+	closed bool
+	broken bool
+}
+
+func (c *testConn) Close() error {
+	c.closed = true
+	return nil
+}
+
+type clockFunc func() time.Time
+
+func (f clockFunc) Now() time.Time {
+	return f()
+}
+
+func TestPool(t *testing.T) {
+	now := time.Now()
+	dialed := 0
+	p := New(func(ctx context.Context, addr string) (*testConn, error) {
+		dialed++
+		return &testConn{addr: addr}, nil
+	}, time.Minute)
+	p.idle.Clock = clockFunc(func() time.Time { return now })
+
+	ctx := context.Background()
+	a, _ := p.Get(ctx, "a")
+	b, _ := p.Get(ctx, "a")
+	p.Put("a", a)
+	p.Put("a", b)
+	if n := p.Idle("a"); n != 2 {
+		t.Fatalf("expected 2 idle connections, got %d", n)
+	}
+	if c, _ := p.Get(ctx, "a"); c != b || c.closed {
+		t.Fatal("expected the most recently put back connection to be reused")
+	}
+	if c, _ := p.Get(ctx, "b"); c.addr != "b" {
+		t.Fatal("expected connections to be keyed by address")
+	}
+	if dialed != 3 {
+		t.Fatalf("expected 3 dials, got %d", dialed)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if c, _ := p.Get(ctx, "a"); c == a {
+		t.Fatal("expected idle connections to expire")
+	}
+	if !a.closed {
+		t.Fatal("expected expired connections to be closed")
+	}
+	if b.closed {
+		t.Fatal("expected connections handed out not to be closed")
+	}
+}
+
+func TestPoolLimits(t *testing.T) {
+	p := New(func(ctx context.Context, addr string) (*testConn, error) {
+		return &testConn{addr: addr}, nil
+	}, time.Minute)
+	p.MaxIdle = 2
+	p.Check = func(ctx context.Context, c *testConn) error {
+		if c.broken {
+			return errors.New("broken")
+		}
+		return nil
+	}
+
+	conns := []*testConn{{}, {}, {}}
+	for _, c := range conns {
+		p.Put("a", c)
+	}
+	if !conns[0].closed || p.Idle("a") != 2 {
+		t.Fatal("expected the oldest idle connection to be closed past MaxIdle")
+	}
+
+	conns[2].broken = true
+	if c, _ := p.Get(context.Background(), "a"); c != conns[1] {
+		t.Fatal("expected connections failing the health check to be skipped")
+	}
+	if !conns[2].closed {
+		t.Fatal("expected connections failing the health check to be closed")
+	}
+
+	p.Put("a", conns[1])
+	p.Close(context.Background())
+	if !conns[1].closed {
+		t.Fatal("expected idle connections to be closed with the pool")
+	}
+	if _, err := p.Get(context.Background(), "a"); err != ttlcache.ErrClosed {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+	late := &testConn{}
+	p.Put("a", late)
+	if !late.closed {
+		t.Fatal("expected connections put back after closing to be closed")
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import "time"
+
+// SetWithPriority is like Set, but also assigns a priority to the key. When
+// the cache is at Capacity, keys with the lowest priority are evicted first,
+// regardless of their expiration time, and keys of equal priority are
+// evicted by ascending expiration time.
+//
+// Keys set with Set keep their current priority, which is zero for new keys.
+func (cache *Cache[K, V]) SetWithPriority(key K, value V, ttl time.Duration, priority int) {
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	bucket := cache.set(key, value, ttl, ttl)
+	if bucket == nil || bucket.priority == priority {
+		return
+	}
+	if !cache.priorities {
+		cache.prioritize()
+	}
+	bucket.priority = priority
+	cache.evictList.update(bucket.eidx, bucket.evictKey())
+}
+
+// prioritize starts maintaining the evict list, which is only needed once
+// keys have different priorities. cache.mux must be held for writing.
+func (cache *Cache[K, V]) prioritize() {
+	cache.priorities = true
+	n := len(cache.expireList.elts)
+	l := &cache.evictList
+	l.elts = make([]*cacheBucket[K, V], n)
+	l.keys = make([]heapKey, n)
+	pos := make([]*int, n)
+	for i, bucket := range cache.expireList.elts {
+		l.elts[i] = bucket
+		l.keys[i] = bucket.evictKey()
+		pos[i] = &bucket.eidx
+	}
+	l.init(pos)
+}
+
+// evict removes the next key to evict from the cache. cache.mux must be held
+// for writing.
+func (cache *Cache[K, V]) evict() bool {
+	if cache.EvictionSamples > 0 {
+		return cache.evictSampled(cache.EvictionSamples)
+	}
+	bucket, ok := cache.expireList.Peek()
+	if cache.priorities && ok {
+		bucket = cache.evictList.elts[0]
+	}
+	if !ok {
+		return false
+	}
+	cache.delete(bucket, EventEvict)
+	return true
+}
+
+// evictList is a min-heap of buckets ordered by priority, then expiration
+// time.
+type evictList[K, V any] struct {
+	minHeap[*cacheBucket[K, V]]
+}
+
+// evictKey returns the key ordering bucket in the evict list.
+func (bucket *cacheBucket[K, V]) evictKey() heapKey {
+	return heapKey{rank: bucket.priority, at: bucket.expiry}
+}
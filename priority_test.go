@@ -0,0 +1,65 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCapacity(t *testing.T) {
+	c := New[int, int]()
+	c.Capacity = 3
+	for i := 0; i < 5; i++ {
+		c.Set(i, i, time.Duration(5-i)*time.Hour)
+	}
+	if len(c.cache) != 3 {
+		t.Fatalf("expected the cache to hold 3 keys, got %d", len(c.cache))
+	}
+	for i := 0; i < 5; i++ {
+		// The keys closest to expiry when the cache was full were evicted.
+		if _, ok := c.Get(i); ok != (i <= 1 || i == 4) {
+			t.Fatalf("unexpected presence of key %d: %v", i, ok)
+		}
+	}
+}
+
+func TestSetWithPriority(t *testing.T) {
+	c := New[string, int]()
+	c.Capacity = 3
+
+	var evicted []string
+	c.OnExpire = func(key string, value int) {
+		evicted = append(evicted, key)
+	}
+	c.SetWithPriority("expensive", 0, time.Minute, 10)
+	c.Set("cheap", 0, time.Hour)
+	c.SetWithPriority("normal", 0, time.Minute, 5)
+	c.Set("cheaper", 0, 2*time.Hour)
+	c.Set("another", 0, 2*time.Hour)
+	c.SetWithPriority("other", 0, time.Hour, 5)
+
+	want := []string{"cheap", "cheaper", "another"}
+	if len(evicted) != len(want) {
+		t.Fatalf("expected %v to be evicted, got %v", want, evicted)
+	}
+	for i := range want {
+		if evicted[i] != want[i] {
+			t.Fatalf("expected %v to be evicted, got %v", want, evicted)
+		}
+	}
+
+	// Set keeps the priority of existing keys.
+	c.Set("expensive", 1, time.Second)
+	c.Set("last", 0, time.Hour)
+	if _, ok := c.Get("expensive"); !ok {
+		t.Fatal("expected the high-priority key to be kept")
+	}
+	if evicted[len(evicted)-1] != "normal" {
+		t.Fatalf("expected the soonest key of lowest priority to be evicted, got %v", evicted)
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"path"
+	"regexp"
+)
+
+// KeysFunc returns all the live keys in the cache for which match returns
+// true, in no particular order. match is called with the cache locked, and
+// must not call methods of the cache.
+func (cache *Cache[K, V]) KeysFunc(match func(key K) bool) []K {
+	cache.mux.RLock()
+	defer cache.mux.RUnlock()
+
+	now := cache.instant()
+	var keys []K
+	for _, bucket := range cache.expireList.elts {
+		if bucket.expiry.After(now) && match(bucket.key) {
+			keys = append(keys, bucket.key)
+		}
+	}
+	return keys
+}
+
+// KeysMatching returns all the live keys in the cache matching the specified
+// shell pattern, as implemented by path.Match. The only possible error is
+// path.ErrBadPattern.
+func KeysMatching[V any](cache *Cache[string, V], pattern string) ([]string, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	return cache.KeysFunc(MatchGlob(pattern)), nil
+}
+
+// ExpireMatching expires all the keys in the cache matching the specified
+// shell pattern, as implemented by path.Match, and returns how many were
+// expired. The only possible error is path.ErrBadPattern.
+func ExpireMatching[V any](cache *Cache[string, V], pattern string) (int, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return 0, err
+	}
+	match := MatchGlob(pattern)
+	return cache.ExpireFunc(func(key string, _ V) bool { return match(key) }), nil
+}
+
+// KeysMatchingRegexp returns all the live keys in the cache matching the
+// specified regular expression.
+func KeysMatchingRegexp[V any](cache *Cache[string, V], re *regexp.Regexp) []string {
+	return cache.KeysFunc(re.MatchString)
+}
+
+// ExpireMatchingRegexp expires all the keys in the cache matching the
+// specified regular expression, and returns how many were expired.
+func ExpireMatchingRegexp[V any](cache *Cache[string, V], re *regexp.Regexp) int {
+	return cache.ExpireFunc(func(key string, _ V) bool { return re.MatchString(key) })
+}
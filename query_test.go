@@ -0,0 +1,48 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"path"
+	"regexp"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestKeysMatching(t *testing.T) {
+	c := New[string, int]()
+	for _, key := range []string{"user:1", "user:2", "user:10", "group:1"} {
+		c.Set(key, 0, time.Hour)
+	}
+
+	keys, err := KeysMatching(c, "user:?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(keys)
+	if len(keys) != 2 || keys[0] != "user:1" || keys[1] != "user:2" {
+		t.Fatalf("expected user:1 and user:2, got %v", keys)
+	}
+	if _, err := KeysMatching(c, "["); err != path.ErrBadPattern {
+		t.Fatalf("expected bad pattern error, got %v", err)
+	}
+
+	if keys := KeysMatchingRegexp(c, regexp.MustCompile(`^user:\d{2}$`)); len(keys) != 1 || keys[0] != "user:10" {
+		t.Fatalf("expected user:10, got %v", keys)
+	}
+
+	if n := ExpireMatchingRegexp(c, regexp.MustCompile(`^group:`)); n != 1 {
+		t.Fatalf("expected 1 group to be expired, got %v", n)
+	}
+	if n, err := ExpireMatching(c, "user:*"); err != nil || n != 3 {
+		t.Fatalf("expected 3 users to be expired, got %v (err: %v)", n, err)
+	}
+	if keys := c.KeysFunc(func(string) bool { return true }); len(keys) != 0 {
+		t.Fatalf("expected the cache to be empty, got %v", keys)
+	}
+}
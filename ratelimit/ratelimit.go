@@ -0,0 +1,97 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package ratelimit implements per-key token bucket rate limiters on top of
+// ttlcache.
+//
+// Buckets that have been idle for long enough to be full again are
+// indistinguishable from fresh ones, so they are given exactly that long to
+// live in the cache. This keeps memory bounded by the set of recently active
+// keys without changing the limiting behaviour.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"snai.pe/go-ttlcache"
+)
+
+// Limiter is a token bucket rate limiter keyed by arbitrary comparable keys.
+// Each key gets its own bucket holding up to burst tokens, refilled at rate
+// tokens per second.
+type Limiter[K comparable] struct {
+	rate    float64
+	burst   float64
+	idle    time.Duration
+	buckets *ttlcache.Cache[K, *bucket]
+	mux     sync.Mutex
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// New returns a limiter allowing rate events per second and per key, with
+// bursts of up to burst events.
+func New[K comparable](rate float64, burst int) *Limiter[K] {
+	if rate <= 0 {
+		panic("ratelimit: rate must be positive")
+	}
+	if burst <= 0 {
+		panic("ratelimit: burst must be positive")
+	}
+	// Time needed for an empty bucket to be full again; past that, dropping
+	// the bucket loses no information.
+	idle := time.Duration(math.Ceil(float64(burst) / rate * float64(time.Second)))
+	return &Limiter[K]{
+		rate:    rate,
+		burst:   float64(burst),
+		idle:    idle,
+		buckets: ttlcache.New[K, *bucket](),
+	}
+}
+
+// Allow reports whether an event for the specified key may happen now.
+func (l *Limiter[K]) Allow(key K) bool {
+	return l.AllowN(key, 1)
+}
+
+// AllowN reports whether n events for the specified key may happen now. If
+// they may, the corresponding tokens are consumed; otherwise the bucket is
+// left untouched.
+func (l *Limiter[K]) AllowN(key K, n int) bool {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	now := time.Now()
+	b, ok := l.buckets.Get(key)
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+	} else {
+		elapsed := now.Sub(b.last).Seconds()
+		b.tokens = math.Min(l.burst, b.tokens+elapsed*l.rate)
+		b.last = now
+	}
+
+	allowed := b.tokens >= float64(n)
+	if allowed {
+		b.tokens -= float64(n)
+	}
+	l.buckets.Set(key, b, l.idle)
+	return allowed
+}
+
+// Reset forgets the state of the bucket for the specified key, making it
+// full again.
+func (l *Limiter[K]) Reset(key K) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	l.buckets.Expire(key)
+}
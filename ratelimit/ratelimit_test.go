@@ -0,0 +1,40 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	l := New[string](1000, 2)
+
+	if !l.Allow("foo") || !l.Allow("foo") {
+		t.Fatal("expected the first two events for foo to be allowed")
+	}
+	if l.Allow("foo") {
+		t.Fatal("expected the third event for foo to be denied")
+	}
+	if !l.Allow("bar") {
+		t.Fatal("expected bar to have its own bucket")
+	}
+	if l.AllowN("bar", 3) {
+		t.Fatal("expected an event larger than the burst to be denied")
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	if !l.Allow("foo") {
+		t.Fatal("expected foo to have been refilled")
+	}
+
+	l.AllowN("bar", 1)
+	l.Reset("bar")
+	if !l.AllowN("bar", 2) {
+		t.Fatal("expected bar to be full after a reset")
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+// entryRef counts the references to a value handed out by Acquire.
+type entryRef struct {
+	count   int
+	cleanup func() // postponed until the last reference is released
+}
+
+// Acquire is like Get, but also pins the value until release is called. If
+// the key leaves the cache in the meantime, it does so right away, but the
+// cleanup of its value is postponed until every reference to it was
+// released: OnExpire, expiry listeners and OnEvicted are then called from
+// the last call to release, with the cache locked. This is also the case
+// for values replaced by Set and the like, for the purpose of OnEvicted.
+//
+// release must be called once the value is no longer in use; calling it
+// more than once does nothing. If the key is not found, release is nil.
+func (cache *Cache[K, V]) Acquire(key K) (value V, release func(), found bool) {
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	value, found = cache.get(key)
+	if !found {
+		return value, nil, false
+	}
+	bucket := cache.cache[key]
+	if bucket.ref == nil {
+		bucket.ref = &entryRef{}
+	}
+	ref := bucket.ref
+	ref.count++
+
+	released := false
+	release = func() {
+		cache.mux.Lock()
+		defer cache.mux.Unlock()
+
+		if released {
+			return
+		}
+		released = true
+		ref.count--
+		if cleanup := ref.cleanup; ref.count == 0 && cleanup != nil {
+			ref.cleanup = nil
+			cleanup()
+		}
+	}
+	return value, release, true
+}
+
+// pinned reports whether the value of bucket is still referenced, in which
+// case its cleanup must be set on bucket.ref rather than done right away.
+func (bucket *cacheBucket[K, V]) pinned() bool {
+	return bucket.ref != nil && bucket.ref.count > 0
+}
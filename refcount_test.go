@@ -0,0 +1,67 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAcquire(t *testing.T) {
+	c := New[string, int]()
+	var expired []int
+	c.OnExpire = func(key string, value int) {
+		expired = append(expired, value)
+	}
+
+	if _, release, ok := c.Acquire("foo"); ok || release != nil {
+		t.Fatal("expected acquiring a missing key to fail")
+	}
+
+	c.Set("foo", 1, time.Hour)
+	_, release1, _ := c.Acquire("foo")
+	v, release2, ok := c.Acquire("foo")
+	if !ok || v != 1 {
+		t.Fatalf("expected to acquire 1, got %v, %v", v, ok)
+	}
+
+	c.Expire("foo")
+	if _, ok := c.Get("foo"); ok {
+		t.Fatal("expected the key to leave the cache right away")
+	}
+	release1()
+	release1()
+	if len(expired) != 0 {
+		t.Fatalf("expected OnExpire to wait for every reference, got %v", expired)
+	}
+	release2()
+	if len(expired) != 1 || expired[0] != 1 {
+		t.Fatalf("expected OnExpire to be called on the last release, got %v", expired)
+	}
+
+	c.Set("foo", 2, time.Hour)
+	c.Expire("foo")
+	if len(expired) != 2 {
+		t.Fatal("expected values no longer referenced to expire right away")
+	}
+}
+
+func TestAcquireReplaced(t *testing.T) {
+	c := New[string, *testResource]()
+	old, replacement := &testResource{}, &testResource{}
+	c.Set("foo", old, time.Hour)
+	_, release, _ := c.Acquire("foo")
+	c.Set("foo", old, time.Hour)
+	c.Set("foo", replacement, time.Hour)
+	expectReasons(t, "old", old)
+
+	// The reference was to the old value, and must not hold the new one.
+	c.Expire("foo")
+	expectReasons(t, "replacement", replacement, ReasonDeleted)
+	release()
+	expectReasons(t, "old", old, ReasonReplaced)
+}
@@ -0,0 +1,203 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Refresher periodically reloads registered keys into a cache through its
+// Loader, regardless of whether they are being accessed, keeping a known
+// set of keys always warm.
+//
+// Refreshes only happen while Run is running, from worker goroutines it
+// starts, and closing the cache waits for Run to return.
+type Refresher[K comparable, V any] struct {
+	// OnError gets called whenever loading a key fails. The previous value,
+	// if any, is left untouched.
+	OnError func(key K, err error)
+
+	cache   *Cache[K, V]
+	workers int
+	jobs    map[K]*refreshJob[K]
+	queue   minHeap[*refreshJob[K]]
+	wake    chan struct{}
+	mux     sync.Mutex
+}
+
+type refreshJob[K any] struct {
+	key      K
+	interval time.Duration
+	next     time.Time
+	idx      int
+}
+
+// NewRefresher returns a refresher for the cache, loading at most workers
+// keys concurrently.
+func (cache *Cache[K, V]) NewRefresher(workers int) *Refresher[K, V] {
+	if workers <= 0 {
+		workers = 1
+	}
+	return &Refresher[K, V]{
+		cache:   cache,
+		workers: workers,
+		jobs:    make(map[K]*refreshJob[K]),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Register schedules the specified key to be reloaded every interval, the
+// first time being immediately. Registering a key again changes its interval.
+// Register panics if interval is not positive.
+func (r *Refresher[K, V]) Register(key K, interval time.Duration) {
+	if interval <= 0 {
+		panic("ttlcache: refresh interval must be positive")
+	}
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if job, ok := r.jobs[key]; ok {
+		job.interval = interval
+		return
+	}
+	job := &refreshJob[K]{key: key, interval: interval, next: r.cache.now()}
+	r.jobs[key] = job
+	r.queue.push(job, heapKey{at: toInstant(job.next)}, &job.idx)
+
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Unregister stops reloading the specified key. It does not remove the key
+// from the cache.
+func (r *Refresher[K, V]) Unregister(key K) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if job, ok := r.jobs[key]; ok {
+		r.queue.remove(job.idx)
+		delete(r.jobs, key)
+	}
+}
+
+// Run reloads registered keys as they come due until ctx is done, then
+// waits for in-flight loads to finish and returns the context error. If the
+// cache gets closed, in-flight loads are given until Close gives up on
+// draining to finish, and ErrClosed is returned.
+func (r *Refresher[K, V]) Run(ctx context.Context) error {
+	if r.cache.Loader == nil {
+		return ErrNoLoader
+	}
+	done, shutdown, err := r.cache.startBackground()
+	if err != nil {
+		return err
+	}
+	defer r.cache.background.Done()
+
+	// In-flight loads get cancelled once the cache is closed without them.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-shutdown:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	keys := make(chan K)
+	var wg sync.WaitGroup
+	wg.Add(r.workers)
+	for i := 0; i < r.workers; i++ {
+		go func() {
+			defer wg.Done()
+			for key := range keys {
+				r.refresh(ctx, key)
+			}
+		}()
+	}
+	defer wg.Wait()
+	defer close(keys)
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		key, wait, due := r.next()
+		if due {
+			select {
+			case keys <- key:
+				continue
+			case <-done:
+				return ErrClosed
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		var tick <-chan time.Time
+		if wait >= 0 {
+			timer.Reset(wait)
+			tick = timer.C
+		}
+		select {
+		case <-tick:
+		case <-r.wake:
+		case <-done:
+			return ErrClosed
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// next pops the next due key and schedules its following refresh. If no key
+// is due, it returns how long to wait until one is, or a negative duration if
+// there are no keys at all.
+func (r *Refresher[K, V]) next() (key K, wait time.Duration, due bool) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if len(r.queue.elts) == 0 {
+		return key, -1, false
+	}
+	job := r.queue.elts[0]
+	now := r.cache.now()
+	if wait = job.next.Sub(now); wait > 0 {
+		return key, wait, false
+	}
+	job.next = now.Add(job.interval)
+	r.queue.update(job.idx, heapKey{at: toInstant(job.next)})
+	return job.key, 0, true
+}
+
+func (r *Refresher[K, V]) refresh(ctx context.Context, key K) {
+	var value V
+	var ttl time.Duration
+	err := errors.New("ttlcache: Loader panicked")
+	start := r.cache.now()
+	r.cache.guard("Loader", func() {
+		value, ttl, err = r.cache.Loader(ctx, key)
+	})
+	if err != nil {
+		if onError := r.OnError; onError != nil {
+			onError(key, err)
+		}
+		return
+	}
+	r.cache.SetWithRecomputeTime(key, value, ttl, r.cache.now().Sub(start))
+}
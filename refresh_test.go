@@ -0,0 +1,96 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRefresher(t *testing.T) {
+	var loads int64
+	c := New[string, int64]()
+	c.Loader = func(ctx context.Context, key string) (int64, time.Duration, error) {
+		if key == "bad" {
+			return 0, 0, errors.New("bad key")
+		}
+		return atomic.AddInt64(&loads, 1), time.Hour, nil
+	}
+
+	r := c.NewRefresher(2)
+	failed := make(chan string, 10)
+	r.OnError = func(key string, err error) {
+		failed <- key
+	}
+	r.Register("foo", time.Millisecond)
+	r.Register("bad", time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := r.Run(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected Run to stop with the context, got %v", err)
+	}
+
+	v, ok := c.Get("foo")
+	if !ok || v < 2 {
+		t.Fatalf("expected foo to have been refreshed several times, got %v", v)
+	}
+	if key := <-failed; key != "bad" {
+		t.Fatalf("expected loading bad to fail, got %v", key)
+	}
+	if _, ok := c.Get("bad"); ok {
+		t.Fatal("expected bad not to be in the cache")
+	}
+
+	r.Unregister("foo")
+	if len(r.jobs) != 1 || len(r.queue.elts) != 1 {
+		t.Fatalf("expected foo to be unregistered, got %v", r.jobs)
+	}
+
+	if err := New[string, int]().NewRefresher(1).Run(context.Background()); err != ErrNoLoader {
+		t.Fatalf("expected ErrNoLoader, got %v", err)
+	}
+}
+
+func TestRefresherClock(t *testing.T) {
+	now := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := New[string, int]()
+	c.Clock = clockFunc(func() time.Time { return now })
+	r := c.NewRefresher(1)
+
+	r.Register("foo", time.Hour)
+	if _, _, due := r.next(); !due {
+		t.Fatal("expected registered keys to be due right away")
+	}
+	if _, wait, due := r.next(); due || wait != time.Hour {
+		t.Fatalf("expected the next refresh to be an hour away on the cache clock, got %v", wait)
+	}
+	now = now.Add(time.Hour)
+	if key, _, due := r.next(); !due || key != "foo" {
+		t.Fatal("expected the key to be due once the cache clock moved past its interval")
+	}
+}
+
+func TestRefresherInterval(t *testing.T) {
+	r := New[string, int]().NewRefresher(1)
+	for _, interval := range []time.Duration{0, -time.Second} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected registering an interval of %v to panic", interval)
+				}
+			}()
+			r.Register("foo", interval)
+		}()
+	}
+	if len(r.jobs) != 0 {
+		t.Fatalf("expected no key to be registered, got %d", len(r.jobs))
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Instrumented is the part of the API of caches that does not depend on
+// their key and value types, for code inspecting every registered cache,
+// like metrics exporters or debug handlers.
+type Instrumented interface {
+	Name() string
+	Stats() Stats
+	Health() Health
+	Cost() int
+	EstimatedBytes() uint64
+	DroppedEvents() uint64
+	fmt.Stringer
+}
+
+type registered interface {
+	Instrumented
+	unregistered()
+}
+
+var registry struct {
+	caches map[string]registered
+	mux    sync.RWMutex
+}
+
+// Register makes the cache known process-wide under the specified name, until
+// it gets closed or unregistered. It panics if the name is already taken, or
+// if the cache is already registered under another name, like expvar does.
+func Register[K comparable, V any](name string, cache *Cache[K, V]) {
+	registry.mux.Lock()
+	defer registry.mux.Unlock()
+
+	if _, ok := registry.caches[name]; ok {
+		panic(fmt.Sprintf("ttlcache: a cache is already registered as %q", name))
+	}
+
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	if cache.name != "" {
+		panic(fmt.Sprintf("ttlcache: cache is already registered as %q", cache.name))
+	}
+	if cache.closing {
+		return
+	}
+	cache.name = name
+	if registry.caches == nil {
+		registry.caches = make(map[string]registered)
+	}
+	registry.caches[name] = cache
+}
+
+// Unregister forgets about the cache registered under the specified name, if
+// any.
+func Unregister(name string) {
+	registry.mux.Lock()
+	defer registry.mux.Unlock()
+
+	if cache, ok := registry.caches[name]; ok {
+		cache.unregistered()
+		delete(registry.caches, name)
+	}
+}
+
+// Lookup returns the cache registered under the specified name, if any.
+func Lookup(name string) (Instrumented, bool) {
+	registry.mux.RLock()
+	defer registry.mux.RUnlock()
+
+	cache, ok := registry.caches[name]
+	return cache, ok
+}
+
+// Range calls fn for each registered cache, by ascending name, until fn
+// returns false. fn is called without the registry locked, and may register
+// and unregister caches.
+func Range(fn func(name string, cache Instrumented) bool) {
+	registry.mux.RLock()
+	names := make([]string, 0, len(registry.caches))
+	caches := make(map[string]Instrumented, len(registry.caches))
+	for name, cache := range registry.caches {
+		names = append(names, name)
+		caches[name] = cache
+	}
+	registry.mux.RUnlock()
+
+	sort.Strings(names)
+	for _, name := range names {
+		if !fn(name, caches[name]) {
+			return
+		}
+	}
+}
+
+// Name returns the name the cache is registered under, or the empty string
+// if it is not registered.
+func (cache *Cache[K, V]) Name() string {
+	cache.mux.RLock()
+	defer cache.mux.RUnlock()
+
+	return cache.name
+}
+
+func (cache *Cache[K, V]) unregistered() {
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	cache.name = ""
+}
+
+// unregister removes the cache from the registry when it gets closed.
+func (cache *Cache[K, V]) unregister(name string) {
+	registry.mux.Lock()
+	defer registry.mux.Unlock()
+
+	if registry.caches[name] == registered(cache) {
+		delete(registry.caches, name)
+	}
+}
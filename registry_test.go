@@ -0,0 +1,56 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRegister(t *testing.T) {
+	users := New[string, int]()
+	sessions := New[int, string]()
+	Register("test/users", users)
+	Register("test/sessions", sessions)
+	defer Unregister("test/users")
+	defer Unregister("test/sessions")
+
+	users.Set("foo", 1, time.Hour)
+	if c, ok := Lookup("test/users"); !ok || c.Health().Keys != 1 || c.Name() != "test/users" {
+		t.Fatal("expected to look up the registered cache by name")
+	}
+
+	var names []string
+	Range(func(name string, c Instrumented) bool {
+		names = append(names, name)
+		return true
+	})
+	if len(names) < 2 || names[0] > names[1] {
+		t.Fatalf("expected registered caches by ascending name, got %v", names)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected registering a name twice to panic")
+			}
+		}()
+		Register("test/users", New[string, int]())
+	}()
+
+	Unregister("test/users")
+	if _, ok := Lookup("test/users"); ok || users.Name() != "" {
+		t.Fatal("expected the cache to be unregistered")
+	}
+	Register("test/users", users)
+
+	sessions.Close(context.Background())
+	if _, ok := Lookup("test/sessions"); ok {
+		t.Fatal("expected closed caches to be unregistered")
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"time"
+)
+
+// GetWithRevision retrieves the value in the cache for the specified key
+// like Get, along with its revision. Revisions change every time the value
+// of a key is set, and can be passed to SetIfRevision to only write a key if
+// nobody else did in the meantime.
+//
+// Missing keys have a revision of 0.
+func (cache *Cache[K, V]) GetWithRevision(key K) (value V, rev uint64, found bool) {
+	cache.mux.RLock()
+	defer cache.mux.RUnlock()
+
+	value, found = cache.get(key)
+	if found {
+		rev = cache.cache[key].rev
+	}
+	return value, rev, found
+}
+
+// SetIfRevision assigns the specified value to the specified key like Set,
+// but only if the revision of the key is still rev, and reports whether it
+// did. Passing a revision of 0 only sets the key if it is missing.
+//
+// This gives compare-and-swap semantics without requiring values to be
+// comparable.
+func (cache *Cache[K, V]) SetIfRevision(key K, value V, ttl time.Duration, rev uint64) bool {
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	var current uint64
+	if bucket, found := cache.cache[key]; found {
+		current = bucket.rev
+	}
+	if current != rev {
+		return false
+	}
+	return cache.set(key, value, ttl, ttl) != nil
+}
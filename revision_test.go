@@ -0,0 +1,78 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRevision(t *testing.T) {
+	c := New[string, []int]()
+
+	_, rev, ok := c.GetWithRevision("foo")
+	if ok || rev != 0 {
+		t.Fatalf("expected missing key to have revision 0, got %v", rev)
+	}
+	if !c.SetIfRevision("foo", []int{1}, time.Hour, 0) {
+		t.Fatal("expected setting missing key with revision 0 to succeed")
+	}
+	if c.SetIfRevision("foo", []int{2}, time.Hour, 0) {
+		t.Fatal("expected setting existing key with revision 0 to fail")
+	}
+
+	v, rev, ok := c.GetWithRevision("foo")
+	if !ok || len(v) != 1 || rev == 0 {
+		t.Fatalf("expected foo with a revision, got %v (rev: %v)", v, rev)
+	}
+
+	c.Set("foo", []int{3}, time.Hour) // concurrent writer
+	if c.SetIfRevision("foo", append(v, 4), time.Hour, rev) {
+		t.Fatal("expected setting foo with an outdated revision to fail")
+	}
+
+	v, rev, _ = c.GetWithRevision("foo")
+	if !c.SetIfRevision("foo", append(v, 4), time.Hour, rev) {
+		t.Fatal("expected setting foo with its current revision to succeed")
+	}
+	if v, _ := c.Get("foo"); len(v) != 2 || v[0] != 3 || v[1] != 4 {
+		t.Fatalf("expected foo to be [3 4], got %v", v)
+	}
+}
+
+func TestRevisionClone(t *testing.T) {
+	c := New[string, int]()
+	c.Set("foo", 1, time.Hour)
+	c.Set("bar", 2, time.Hour)
+	_, stale, _ := c.GetWithRevision("bar")
+
+	clone := c.Clone()
+	clone.Set("baz", 3, time.Hour)
+	clone.Set("bar", 4, time.Hour)
+	if clone.SetIfRevision("bar", 5, time.Hour, stale) {
+		t.Fatal("expected a revision from before the clone was written to be outdated")
+	}
+	if _, rev, _ := clone.GetWithRevision("bar"); rev <= stale {
+		t.Fatalf("expected the clone to hand out new revisions, got %v after %v", rev, stale)
+	}
+}
+
+func TestRevisionNewFromMap(t *testing.T) {
+	c := NewFromMap(map[string]int{"foo": 1, "bar": 2}, time.Hour)
+	_, foo, _ := c.GetWithRevision("foo")
+	_, bar, _ := c.GetWithRevision("bar")
+	if foo == 0 || bar == 0 || foo == bar {
+		t.Fatalf("expected keys to have distinct revisions, got %v and %v", foo, bar)
+	}
+	if c.SetIfRevision("foo", 3, time.Hour, 0) {
+		t.Fatal("expected setting an existing key with revision 0 to fail")
+	}
+	c.Set("baz", 4, time.Hour)
+	if _, rev, _ := c.GetWithRevision("baz"); rev == foo || rev == bar {
+		t.Fatalf("expected new revisions not to reuse existing ones, got %v", rev)
+	}
+}
@@ -0,0 +1,295 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"context"
+	"path"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Rule configures the behavior of the cache for a family of keys.
+type Rule struct {
+	// Prefix selects the keys starting with it. An empty prefix selects all
+	// the keys.
+	Prefix string
+
+	// Pattern, if set, further restricts the rule to the keys matching it,
+	// with the syntax of path.Match.
+	Pattern string
+
+	// TTL, if non-zero, is the TTL of the keys of the family set with
+	// DefaultTTL.
+	TTL time.Duration
+
+	// Share, if non-zero, is the fraction of the Capacity of the cache the
+	// family may hold; its soonest expiring keys get evicted past it.
+	Share float64
+
+	// Refresh, if non-zero, is how often the keys of the family get reloaded
+	// by the Refresher of the rules.
+	Refresh time.Duration
+}
+
+func (rule *Rule) match(key string) bool {
+	if !strings.HasPrefix(key, rule.Prefix) {
+		return false
+	}
+	if rule.Pattern == "" {
+		return true
+	}
+	ok, _ := path.Match(rule.Pattern, key)
+	return ok
+}
+
+// Rules is a table of rules applying to the keys of a cache with string
+// keys, which can be swapped at runtime, letting operators tune the cache
+// for specific families of keys without redeploying. Each key follows the
+// first rule of the table it matches, if any.
+//
+// Capacity shares are enforced by Enforce, which Run calls every Interval.
+type Rules[V any] struct {
+	// Interval is how often Run enforces capacity shares. It defaults to
+	// one second.
+	Interval time.Duration
+
+	// Refresher, if set, reloads keys following rules with a Refresh
+	// interval. It must be set before keys are set, or be followed by a
+	// call to Swap.
+	Refresher *Refresher[string, V]
+
+	cache    *Cache[string, V]
+	table    atomic.Value // []Rule
+	fallback func(key string, value V) time.Duration
+
+	// rule holds the index in the table of the rule followed by each key,
+	// and counts how many keys follow each rule. Both are protected by
+	// cache.mux.
+	rule   map[string]int
+	counts []int
+}
+
+// NewRules applies the specified table of rules to the cache, and returns
+// it. The rules take over the TTLFunc of the cache, which keeps determining
+// the TTL of keys that no rule gives a TTL to.
+func NewRules[V any](cache *Cache[string, V], rules ...Rule) *Rules[V] {
+	r := &Rules[V]{cache: cache}
+	r.table.Store(append([]Rule(nil), rules...))
+
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	r.fallback = cache.TTLFunc
+	cache.TTLFunc = r.ttl
+	r.reset()
+	for key := range cache.cache {
+		r.classify(key)
+	}
+	cache.indexes = append(cache.indexes, r)
+	return r
+}
+
+// Rules returns a copy of the current table of rules.
+func (r *Rules[V]) Rules() []Rule {
+	return append([]Rule(nil), r.table.Load().([]Rule)...)
+}
+
+// Swap atomically replaces the table of rules, which applies immediately
+// to the TTLs of keys being set, and to the refresh intervals and capacity
+// shares of the keys already in the cache.
+func (r *Rules[V]) Swap(rules []Rule) {
+	cache := r.cache
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	// Keys are classified anew against the new table, so that keys whose
+	// rule is gone stop being refreshed.
+	old := r.table.Load().([]Rule)
+	r.table.Store(append([]Rule(nil), rules...))
+	keys := r.rule
+	r.reset()
+	for key, prev := range keys {
+		r.classify(key)
+		if r.Refresher == nil || prev < 0 || old[prev].Refresh <= 0 {
+			continue
+		}
+		if i := r.rule[key]; i < 0 || rules[i].Refresh <= 0 {
+			r.Refresher.Unregister(key)
+		}
+	}
+}
+
+// Match returns the rule the specified key follows, if any.
+func (r *Rules[V]) Match(key string) (Rule, bool) {
+	table := r.table.Load().([]Rule)
+	if i := r.match(table, key); i >= 0 {
+		return table[i], true
+	}
+	return Rule{}, false
+}
+
+// Remove stops applying the rules to the cache, and gives it its TTLFunc
+// back. Keys keep their current expiration times.
+func (r *Rules[V]) Remove() {
+	cache := r.cache
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	for i, other := range cache.indexes {
+		if other == indexer[string, V](r) {
+			cache.indexes = append(cache.indexes[:i], cache.indexes[i+1:]...)
+			break
+		}
+	}
+	cache.TTLFunc = r.fallback
+	if r.Refresher != nil {
+		for key, i := range r.rule {
+			if i >= 0 {
+				r.Refresher.Unregister(key)
+			}
+		}
+	}
+	r.reset()
+}
+
+// Enforce evicts the soonest expiring keys of the families holding more
+// than their share of the capacity of the cache, and returns how many were
+// evicted.
+func (r *Rules[V]) Enforce() int {
+	cache := r.cache
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	if cache.Capacity <= 0 {
+		return 0
+	}
+	table := r.table.Load().([]Rule)
+	excess := make([]int, len(table))
+	var total int
+	for i, rule := range table {
+		if rule.Share <= 0 || i >= len(r.counts) {
+			continue
+		}
+		if n := r.counts[i] - int(rule.Share*float64(cache.Capacity)); n > 0 {
+			excess[i] = n
+			total += n
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+
+	var victims []*cacheBucket[string, V]
+	cache.walkByExpiry(func(bucket *cacheBucket[string, V]) bool {
+		if i, ok := r.rule[bucket.key]; ok && i >= 0 && excess[i] > 0 {
+			excess[i]--
+			victims = append(victims, bucket)
+		}
+		return len(victims) < total
+	})
+	return cache.deleteAll(victims, EventEvict)
+}
+
+// Run enforces capacity shares every Interval until ctx is done, in which
+// case the context error is returned, or until the cache gets closed, in
+// which case ErrClosed is returned.
+func (r *Rules[V]) Run(ctx context.Context) error {
+	done, _, err := r.cache.startBackground()
+	if err != nil {
+		return err
+	}
+	defer r.cache.background.Done()
+
+	interval := r.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.Enforce()
+		case <-done:
+			return ErrClosed
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (r *Rules[V]) ttl(key string, value V) time.Duration {
+	table := r.table.Load().([]Rule)
+	if i := r.match(table, key); i >= 0 && table[i].TTL != 0 {
+		return table[i].TTL
+	}
+	if fallback := r.fallback; fallback != nil {
+		return fallback(key, value)
+	}
+	return 0
+}
+
+func (r *Rules[V]) match(table []Rule, key string) int {
+	for i := range table {
+		if table[i].match(key) {
+			return i
+		}
+	}
+	return -1
+}
+
+// classify records the rule followed by key, and registers it for refresh
+// if needed. cache.mux must be held for writing.
+func (r *Rules[V]) classify(key string) {
+	table := r.table.Load().([]Rule)
+	prev, known := r.rule[key]
+	i := r.match(table, key)
+	if known && prev == i {
+		return
+	}
+	if known && prev >= 0 {
+		r.counts[prev]--
+	}
+	r.rule[key] = i
+	if i >= 0 {
+		r.counts[i]++
+	}
+
+	if r.Refresher == nil {
+		return
+	}
+	if i >= 0 && table[i].Refresh > 0 {
+		r.Refresher.Register(key, table[i].Refresh)
+	} else if known && prev >= 0 {
+		r.Refresher.Unregister(key)
+	}
+}
+
+func (r *Rules[V]) update(key string, value V) {
+	r.classify(key)
+}
+
+func (r *Rules[V]) remove(key string) {
+	i, ok := r.rule[key]
+	if !ok {
+		return
+	}
+	delete(r.rule, key)
+	if i >= 0 {
+		r.counts[i]--
+		if r.Refresher != nil {
+			r.Refresher.Unregister(key)
+		}
+	}
+}
+
+func (r *Rules[V]) reset() {
+	r.rule = make(map[string]int)
+	r.counts = make([]int, len(r.table.Load().([]Rule)))
+}
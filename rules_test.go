@@ -0,0 +1,121 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRulesTTL(t *testing.T) {
+	now := time.Now()
+	c := New[string, int]()
+	c.Clock = fixedClock(now)
+	c.TTLFunc = func(string, int) time.Duration { return time.Minute }
+
+	r := NewRules(c,
+		Rule{Prefix: "users:", Pattern: "users:*:avatar", TTL: time.Hour},
+		Rule{Prefix: "users:", TTL: 10 * time.Minute},
+		Rule{Prefix: "sessions:"},
+	)
+	ttls := map[string]time.Duration{
+		"users:42:avatar": time.Hour,
+		"users:42":        10 * time.Minute,
+		"sessions:42":     time.Minute,
+		"other":           time.Minute,
+	}
+	for key := range ttls {
+		c.Set(key, 0, DefaultTTL)
+	}
+	checkTTLs := func() {
+		t.Helper()
+		for _, e := range c.ExpiringSoon(len(ttls)) {
+			if ttl := e.Expiry.Sub(now); ttl != ttls[e.Key] {
+				t.Fatalf("expected %q to have a TTL of %v, got %v", e.Key, ttls[e.Key], ttl)
+			}
+		}
+	}
+	checkTTLs()
+
+	r.Swap([]Rule{{Prefix: "sessions:", TTL: 30 * time.Second}})
+	ttls["users:42:avatar"] = time.Minute
+	ttls["users:42"] = time.Minute
+	ttls["sessions:42"] = 30 * time.Second
+	for key := range ttls {
+		c.Set(key, 0, DefaultTTL)
+	}
+	checkTTLs()
+	if rule, ok := r.Match("sessions:1"); !ok || rule.TTL != 30*time.Second {
+		t.Fatalf("expected sessions to follow the swapped rule, got %v, %v", rule, ok)
+	}
+
+	r.Remove()
+	c.Set("sessions:42", 0, DefaultTTL)
+	ttls["sessions:42"] = time.Minute
+	checkTTLs()
+}
+
+func TestRulesEnforce(t *testing.T) {
+	now := time.Now()
+	c := New[string, int]()
+	c.Clock = fixedClock(now)
+	c.Capacity = 10
+
+	r := NewRules(c, Rule{Prefix: "a", Share: 0.3})
+	for i := 0; i < 5; i++ {
+		c.Set(fmt.Sprintf("a%d", i), i, time.Duration(i+1)*time.Minute)
+		c.Set(fmt.Sprintf("b%d", i), i, time.Minute)
+	}
+	if n := r.Enforce(); n != 2 {
+		t.Fatalf("expected 2 keys to be evicted, got %d", n)
+	}
+	for i := 0; i < 5; i++ {
+		if _, ok := c.Get(fmt.Sprintf("a%d", i)); ok != (i >= 2) {
+			t.Fatalf("expected the soonest expiring keys to be evicted, a%d present: %v", i, ok)
+		}
+		if _, ok := c.Get(fmt.Sprintf("b%d", i)); !ok {
+			t.Fatalf("expected keys of other families to be kept, b%d is missing", i)
+		}
+	}
+	if n := r.Enforce(); n != 0 {
+		t.Fatalf("expected nothing more to be evicted, got %d", n)
+	}
+	if err := c.checkInvariants(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRulesRefresh(t *testing.T) {
+	c := New[string, int]()
+	r := NewRules(c, Rule{Prefix: "hot:", Refresh: time.Minute})
+	r.Refresher = c.NewRefresher(1)
+
+	registered := func(key string) bool {
+		r.Refresher.mux.Lock()
+		defer r.Refresher.mux.Unlock()
+		_, ok := r.Refresher.jobs[key]
+		return ok
+	}
+
+	c.Set("hot:1", 1, time.Hour)
+	c.Set("cold:1", 1, time.Hour)
+	if !registered("hot:1") || registered("cold:1") {
+		t.Fatal("expected only keys with a refresh interval to be registered")
+	}
+
+	c.Expire("hot:1")
+	if registered("hot:1") {
+		t.Fatal("expected expired keys to be unregistered")
+	}
+
+	c.Set("hot:2", 2, time.Hour)
+	r.Swap([]Rule{{Prefix: "cold:", Refresh: time.Minute}})
+	if registered("hot:2") || !registered("cold:1") {
+		t.Fatal("expected swapping rules to update registrations")
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package s3blob implements a ttlcache.BlobStore on top of Amazon S3, and
+// services compatible with its API, like Google Cloud Storage through its
+// interoperability endpoint, or MinIO.
+//
+// Requests are signed with AWS Signature Version 4 using only the standard
+// library, to keep ttlcache free of dependencies; there is no support for
+// multipart uploads, so blobs are limited to what a single PUT accepts.
+package s3blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"snai.pe/go-ttlcache"
+)
+
+// Store is a ttlcache.BlobStore keeping blobs as objects in an S3 bucket.
+type Store struct {
+	// Endpoint is the base URL of the service, like
+	// "https://s3.us-east-1.amazonaws.com" or
+	// "https://storage.googleapis.com".
+	Endpoint string
+
+	// Region is the region of the bucket, like "us-east-1". Services that
+	// ignore regions usually expect "auto" or "us-east-1".
+	Region string
+
+	// Bucket is the name of the bucket, addressed with path-style URLs.
+	Bucket string
+
+	// Prefix is prepended to the names of blobs to form object keys.
+	Prefix string
+
+	// AccessKey, SecretKey and, for temporary credentials, SessionToken
+	// are the credentials requests are signed with.
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+
+	// Client sends the requests. It defaults to http.DefaultClient.
+	Client *http.Client
+
+	now func() time.Time
+}
+
+var _ ttlcache.BlobStore = (*Store)(nil)
+
+// Put uploads the contents of r as the object for the blob called name.
+func (s *Store) Put(ctx context.Context, name string, r io.Reader) error {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodPut, name, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return responseError(resp)
+	}
+	return nil
+}
+
+// Get downloads the object for the blob called name, or returns
+// ttlcache.ErrBlobNotFound if there is none.
+func (s *Store) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	resp, err := s.do(ctx, http.MethodGet, name, nil)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		resp.Body.Close()
+		return nil, ttlcache.ErrBlobNotFound
+	case resp.StatusCode/100 != 2:
+		defer resp.Body.Close()
+		return nil, responseError(resp)
+	}
+	return resp.Body, nil
+}
+
+func (s *Store) do(ctx context.Context, method, name string, body []byte) (*http.Response, error) {
+	u, err := url.Parse(strings.TrimSuffix(s.Endpoint, "/"))
+	if err != nil {
+		return nil, err
+	}
+	u.Path += "/" + s.Bucket + "/" + s.Prefix + name
+	// Send the path as it gets signed.
+	u.RawPath = escapePath(u.Path)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	s.sign(req, body)
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(req)
+}
+
+// sign adds the headers of AWS Signature Version 4 to req.
+func (s *Store) sign(req *http.Request, body []byte) {
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	t := now().UTC()
+	stamp := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+
+	payload := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(payload[:])
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", stamp)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, strings.ToLower(name))
+	}
+	sort.Strings(names)
+	var headers strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&headers, "%s:%s\n", name, strings.TrimSpace(req.Header.Get(name)))
+	}
+	signed := strings.Join(names, ";")
+
+	canonical := strings.Join([]string{
+		req.Method,
+		escapePath(req.URL.Path),
+		req.URL.Query().Encode(),
+		headers.String(),
+		signed,
+		payloadHash,
+	}, "\n")
+	canonicalHash := sha256.Sum256([]byte(canonical))
+
+	scope := date + "/" + s.Region + "/s3/aws4_request"
+	toSign := "AWS4-HMAC-SHA256\n" + stamp + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+	signature := hex.EncodeToString(hmacSHA256(signingKey(s.SecretKey, date, s.Region, "s3"), toSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKey, scope, signed, signature))
+	// net/http sends the Host header from req.Host, not req.Header.
+	req.Header.Del("Host")
+}
+
+// escapePath encodes path for the canonical request of Signature Version 4:
+// every byte but unreserved characters and slashes is percent-encoded, which
+// is stricter than url.URL.EscapedPath.
+func escapePath(path string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '.', c == '_', c == '~', c == '/':
+			b.WriteByte(c)
+		default:
+			b.WriteByte('%')
+			b.WriteByte(hex[c>>4])
+			b.WriteByte(hex[c&15])
+		}
+	}
+	return b.String()
+}
+
+func signingKey(secret, date, region, service string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secret), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	return hmacSHA256(key, "aws4_request")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func responseError(resp *http.Response) error {
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+	return fmt.Errorf("s3blob: %s %s: %s: %s", resp.Request.Method, resp.Request.URL.Path, resp.Status, bytes.TrimSpace(msg))
+}
@@ -0,0 +1,117 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package s3blob
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"snai.pe/go-ttlcache"
+)
+
+func TestSigningKey(t *testing.T) {
+	// Example from the AWS Signature Version 4 documentation.
+	key := signingKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam")
+	want := "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d"
+	if got := hex.EncodeToString(key); got != want {
+		t.Fatalf("expected signing key %s, got %s", want, got)
+	}
+}
+
+func TestEscapePath(t *testing.T) {
+	path := "/bucket/caches/a b+c!$&'()*,;=:@~-._/é"
+	want := "/bucket/caches/a%20b%2Bc%21%24%26%27%28%29%2A%2C%3B%3D%3A%40~-._/%C3%A9"
+	if got := escapePath(path); got != want {
+		t.Fatalf("expected %s to be escaped as %s, got %s", path, want, got)
+	}
+}
+
+func TestStore(t *testing.T) {
+	var mux sync.Mutex
+	objects := map[string][]byte{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/20200101/us-east-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=") {
+			http.Error(w, "bad authorization: "+auth, http.StatusForbidden)
+			return
+		}
+
+		mux.Lock()
+		defer mux.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			objects[r.URL.Path], _ = io.ReadAll(r.Body)
+		case http.MethodGet:
+			data, ok := objects[r.URL.Path]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write(data)
+		}
+	}))
+	defer srv.Close()
+
+	store := &Store{
+		Endpoint:  srv.URL,
+		Region:    "us-east-1",
+		Bucket:    "bucket",
+		Prefix:    "caches/",
+		AccessKey: "AKID",
+		SecretKey: "secret",
+		now:       func() time.Time { return time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC) },
+	}
+
+	ctx := context.Background()
+	c := ttlcache.New[string, int]()
+	c.Set("foo", 1, time.Hour)
+	if err := c.Snapshot().Upload(ctx, store, "main"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := objects["/bucket/caches/main"]; !ok {
+		t.Fatalf("expected the snapshot to be uploaded to its object key, got %v", objects)
+	}
+
+	restored := ttlcache.New[string, int]()
+	if err := restored.RestoreBlob(ctx, store, "main"); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := restored.Get("foo"); !ok || v != 1 {
+		t.Fatalf("expected foo to be restored, got %v, %v", v, ok)
+	}
+	if err := restored.RestoreBlob(ctx, store, "missing"); err != ttlcache.ErrBlobNotFound {
+		t.Fatalf("expected ErrBlobNotFound, got %v", err)
+	}
+
+	store.AccessKey = "other"
+	if err := c.Snapshot().Upload(ctx, store, "main"); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Fatalf("expected the upload to be rejected, got %v", err)
+	}
+}
+
+func TestStoreEscapedNames(t *testing.T) {
+	var uri string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uri = r.RequestURI
+	}))
+	defer srv.Close()
+
+	store := &Store{Endpoint: srv.URL, Region: "us-east-1", Bucket: "bucket", AccessKey: "AKID", SecretKey: "secret"}
+	if err := store.Put(context.Background(), "a b!", strings.NewReader("")); err != nil {
+		t.Fatal(err)
+	}
+	if want := "/bucket/a%20b%21"; uri != want {
+		t.Fatalf("expected the path to be sent as signed, %s, got %s", want, uri)
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"math/rand"
+	"sync/atomic"
+)
+
+// evictSampled evicts the best candidate for eviction among n keys picked at
+// random, with replacement. cache.mux must be held for writing.
+func (cache *Cache[K, V]) evictSampled(n int) bool {
+	elts := cache.expireList.elts
+	if len(elts) == 0 {
+		return false
+	}
+
+	var victim *cacheBucket[K, V]
+	var victimUsed int64
+	for i := 0; i < n; i++ {
+		bucket := elts[rand.Intn(len(elts))]
+		used := cache.lastUsed(bucket)
+		if victim == nil || bucket.evictsBefore(used, victim, victimUsed) {
+			victim, victimUsed = bucket, used
+		}
+	}
+	cache.delete(victim, EventEvict)
+	return true
+}
+
+// lastUsed returns when bucket was last read while TrackAccess is set, or
+// else when it was created, in nanoseconds on the wall clock.
+func (cache *Cache[K, V]) lastUsed(bucket *cacheBucket[K, V]) int64 {
+	if cache.TrackAccess {
+		if accessed := atomic.LoadInt64(&bucket.accessed); accessed != 0 {
+			return accessed
+		}
+	}
+	return bucket.created.Time().UnixNano()
+}
+
+// evictsBefore reports whether bucket, last used at used, makes a better
+// candidate for eviction than other, last used at otherUsed.
+func (bucket *cacheBucket[K, V]) evictsBefore(used int64, other *cacheBucket[K, V], otherUsed int64) bool {
+	switch {
+	case bucket.priority != other.priority:
+		return bucket.priority < other.priority
+	case used != otherUsed:
+		return used < otherUsed
+	default:
+		return bucket.expiry.Before(other.expiry)
+	}
+}
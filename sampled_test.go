@@ -0,0 +1,72 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEvictionSamples(t *testing.T) {
+	now := time.Now()
+	c := New[int, int]()
+	c.Clock = clockFunc(func() time.Time {
+		now = now.Add(time.Millisecond)
+		return now
+	})
+	c.Capacity = 100
+	c.EvictionSamples = 5
+	c.TrackAccess = true
+
+	for i := 0; i < 100; i++ {
+		c.Set(i, i, time.Hour)
+	}
+	for i := 0; i < 50; i++ {
+		c.Get(i)
+	}
+	for i := 100; i < 150; i++ {
+		c.Set(i, i, time.Hour)
+	}
+
+	if n := c.Snapshot().Len(); n != 100 {
+		t.Fatalf("expected the cache to stay at capacity, got %d keys", n)
+	}
+	var hot int
+	for i := 0; i < 50; i++ {
+		if _, ok := c.Get(i); ok {
+			hot++
+		}
+	}
+	if hot < 30 {
+		t.Fatalf("expected recently read keys to mostly survive, got %d out of 50", hot)
+	}
+
+	c.mux.Lock()
+	err := c.checkInvariants()
+	c.mux.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEvictionSamplesPriority(t *testing.T) {
+	c := New[int, int]()
+	c.Capacity = 10
+	c.EvictionSamples = 200
+
+	c.SetWithPriority(0, 0, time.Hour, -1)
+	for i := 1; i < 10; i++ {
+		c.Set(i, i, time.Hour)
+	}
+	c.Set(10, 10, time.Hour)
+
+	// With many samples, the lowest priority key is all but certain to be
+	// picked.
+	if _, ok := c.Get(0); ok {
+		t.Fatal("expected the lowest priority key to be evicted")
+	}
+}
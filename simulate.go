@@ -0,0 +1,118 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"sort"
+)
+
+// EvictionPolicy determines which keys get evicted first in a Simulation.
+type EvictionPolicy int
+
+const (
+	// EvictByPriority evicts keys by ascending priority, then expiration
+	// time, like the cache does when it is at Capacity.
+	EvictByPriority EvictionPolicy = iota
+
+	// EvictByExpiry evicts keys by ascending expiration time, ignoring
+	// priorities.
+	EvictByExpiry
+
+	// EvictLRU evicts the least recently read keys first.
+	EvictLRU
+
+	// EvictLFU evicts the least frequently read keys first.
+	EvictLFU
+)
+
+// Simulation is the outcome of evicting keys from a cache under a
+// hypothetical capacity and eviction policy. See Simulate.
+type Simulation[K any] struct {
+	// Evicted holds the keys that would be evicted, in eviction order.
+	Evicted []K
+
+	// LostHits is how many of the recorded hits were on evicted keys.
+	LostHits uint64
+
+	// HitRate is the recorded hit rate, and ProjectedHitRate the hit rate
+	// had the evicted keys not been in the cache.
+	HitRate          float64
+	ProjectedHitRate float64
+}
+
+// Simulate reports which keys would be evicted if the cache had the
+// specified capacity and evicted keys following policy, and how the hit rate
+// would have been affected, without changing anything.
+//
+// Hit rates, as well as the LRU and LFU policies, rely on the access
+// statistics recorded while TrackAccess is set; see HotKeys.
+func (cache *Cache[K, V]) Simulate(capacity int, policy EvictionPolicy) Simulation[K] {
+	cache.mux.RLock()
+	defer cache.mux.RUnlock()
+
+	now := cache.instant()
+	type candidate struct {
+		bucket *cacheBucket[K, V]
+		access Access[K]
+	}
+	var hits uint64
+	live := make([]candidate, 0, len(cache.expireList.elts))
+	for _, bucket := range cache.expireList.elts {
+		if !bucket.expiry.After(now) {
+			continue
+		}
+		access, _ := bucket.access()
+		hits += access.Hits
+		live = append(live, candidate{bucket: bucket, access: access})
+	}
+
+	var sim Simulation[K]
+	var misses uint64
+	cache.misses.mux.Lock()
+	for _, n := range cache.misses.counts {
+		misses += n
+	}
+	cache.misses.mux.Unlock()
+	if hits+misses > 0 {
+		sim.HitRate = float64(hits) / float64(hits+misses)
+	}
+	sim.ProjectedHitRate = sim.HitRate
+
+	n := len(live) - capacity
+	if n <= 0 {
+		return sim
+	}
+
+	sort.Slice(live, func(i, j int) bool {
+		a, b := live[i], live[j]
+		switch policy {
+		case EvictByPriority:
+			if a.bucket.priority != b.bucket.priority {
+				return a.bucket.priority < b.bucket.priority
+			}
+		case EvictLRU:
+			if !a.access.LastAccess.Equal(b.access.LastAccess) {
+				return a.access.LastAccess.Before(b.access.LastAccess)
+			}
+		case EvictLFU:
+			if a.access.Hits != b.access.Hits {
+				return a.access.Hits < b.access.Hits
+			}
+		}
+		return a.bucket.expiry.Before(b.bucket.expiry)
+	})
+
+	sim.Evicted = make([]K, n)
+	for i, c := range live[:n] {
+		sim.Evicted[i] = c.bucket.key
+		sim.LostHits += c.access.Hits
+	}
+	if hits+misses > 0 {
+		sim.ProjectedHitRate = float64(hits-sim.LostHits) / float64(hits+misses)
+	}
+	return sim
+}
@@ -0,0 +1,60 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSimulate(t *testing.T) {
+	now := time.Now()
+	clock := &now
+	c := New[int, int]()
+	c.Clock = clockFunc(func() time.Time { return *clock })
+	c.TrackAccess = true
+
+	// Key i expires last when i is small, is read i times, and read last
+	// when i is large.
+	for i := 0; i < 4; i++ {
+		c.SetWithPriority(i, i, time.Duration(10-i)*time.Minute, i%2)
+	}
+	for i := 0; i < 4; i++ {
+		for j := 0; j < i; j++ {
+			now = now.Add(time.Second)
+			c.Get(i)
+		}
+	}
+	c.Get(100)
+	c.Get(101)
+
+	for _, tc := range []struct {
+		policy EvictionPolicy
+		want   []int
+	}{
+		{EvictByPriority, []int{2, 0}},
+		{EvictByExpiry, []int{3, 2}},
+		{EvictLRU, []int{0, 1}},
+		{EvictLFU, []int{0, 1}},
+	} {
+		sim := c.Simulate(2, tc.policy)
+		if len(sim.Evicted) != 2 || sim.Evicted[0] != tc.want[0] || sim.Evicted[1] != tc.want[1] {
+			t.Fatalf("policy %d: expected %v to be evicted, got %v", tc.policy, tc.want, sim.Evicted)
+		}
+	}
+
+	sim := c.Simulate(2, EvictLFU)
+	if sim.LostHits != 1 || sim.HitRate != 6.0/8 || sim.ProjectedHitRate != 5.0/8 {
+		t.Fatalf("unexpected hit rates: %+v", sim)
+	}
+	if sim := c.Simulate(10, EvictLFU); sim.Evicted != nil || sim.ProjectedHitRate != sim.HitRate {
+		t.Fatalf("expected nothing to be evicted under a large capacity, got %+v", sim)
+	}
+	if len(c.cache) != 4 {
+		t.Fatal("expected the simulation not to evict anything")
+	}
+}
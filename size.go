@@ -0,0 +1,52 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"unsafe"
+)
+
+// mapLoadFactor is the average load of Go map slots, used to estimate map
+// memory: maps grow once they are about 80% full.
+const mapLoadFactor = 0.8
+
+// EstimatedBytes returns an estimate of the memory used by the cache, in
+// bytes, covering its map, its expiry heaps and the entries themselves.
+//
+// Keys and values are counted by the size of their type, which does not
+// cover memory they reference, like the contents of strings, slices or maps.
+// If SizeFunc is set, it gets called for every entry to add the size of such
+// dynamic contents, which makes EstimatedBytes take linear time. Values kept
+// in a custom backend are not counted.
+func (cache *Cache[K, V]) EstimatedBytes() uint64 {
+	cache.mux.RLock()
+	defer cache.mux.RUnlock()
+
+	var bucket cacheBucket[K, V]
+	var ptr *cacheBucket[K, V]
+	n := uint64(len(cache.cache))
+
+	// Map slots hold a key and a pointer to the bucket, plus a byte of
+	// metadata each.
+	slot := uint64(unsafe.Sizeof(bucket.key)+unsafe.Sizeof(ptr)) + 1
+	size := uint64(float64(n*slot) / mapLoadFactor)
+	size += n * uint64(unsafe.Sizeof(bucket))
+	size += uint64(cap(cache.expireList.elts)+cap(cache.evictList.elts)) * uint64(unsafe.Sizeof(ptr))
+	size += uint64(cap(cache.expireList.exp)) * uint64(unsafe.Sizeof(bucket.expiry))
+	size += uint64(cap(cache.evictList.keys))*uint64(unsafe.Sizeof(heapKey{})) + uint64(cap(cache.evictList.pos))*uint64(unsafe.Sizeof(&bucket.eidx))
+
+	if sizeFunc := cache.SizeFunc; sizeFunc != nil {
+		for _, bucket := range cache.expireList.elts {
+			var dynamic int
+			cache.guard("SizeFunc", func() { dynamic = sizeFunc(bucket.key, bucket.val) })
+			if dynamic > 0 {
+				size += uint64(dynamic)
+			}
+		}
+	}
+	return size
+}
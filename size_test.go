@@ -0,0 +1,34 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEstimatedBytes(t *testing.T) {
+	c := New[string, []byte]()
+	if n := c.EstimatedBytes(); n != 0 {
+		t.Fatalf("expected an empty cache to use no memory, got %d", n)
+	}
+
+	for _, key := range []string{"a", "b", "c"} {
+		c.Set(key, make([]byte, 1000), time.Hour)
+	}
+	static := c.EstimatedBytes()
+	if static == 0 || static >= 3000 {
+		t.Fatalf("expected a small estimate without SizeFunc, got %d", static)
+	}
+
+	c.SizeFunc = func(key string, value []byte) int {
+		return len(key) + cap(value)
+	}
+	if n := c.EstimatedBytes(); n != static+3003 {
+		t.Fatalf("expected SizeFunc to be accounted for, got %d instead of %d", n, static+3003)
+	}
+}
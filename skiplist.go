@@ -0,0 +1,112 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"math/rand"
+)
+
+const skiplistMaxLevel = 32
+
+// skiplist is an ordered set of values. Values must be totally ordered by
+// less: values that are neither less nor greater than each other are
+// considered to be the same value.
+type skiplist[T any] struct {
+	less  func(a, b T) bool
+	head  slNode[T]
+	level int
+	len   int
+}
+
+type slNode[T any] struct {
+	val  T
+	next []*slNode[T]
+}
+
+func newSkiplist[T any](less func(a, b T) bool) *skiplist[T] {
+	return &skiplist[T]{
+		less:  less,
+		head:  slNode[T]{next: make([]*slNode[T], skiplistMaxLevel)},
+		level: 1,
+	}
+}
+
+func (l *skiplist[T]) Len() int {
+	return l.len
+}
+
+// path fills update with the last node before v at every level, and
+// returns the first node at or after v, if any.
+func (l *skiplist[T]) path(v T, update *[skiplistMaxLevel]*slNode[T]) *slNode[T] {
+	n := &l.head
+	for i := l.level - 1; i >= 0; i-- {
+		for n.next[i] != nil && l.less(n.next[i].val, v) {
+			n = n.next[i]
+		}
+		if update != nil {
+			update[i] = n
+		}
+	}
+	return n.next[0]
+}
+
+// Insert adds v to the list, and reports whether it was not already in it.
+func (l *skiplist[T]) Insert(v T) bool {
+	var update [skiplistMaxLevel]*slNode[T]
+	if n := l.path(v, &update); n != nil && !l.less(v, n.val) {
+		return false
+	}
+
+	level := 1
+	for level < skiplistMaxLevel && rand.Intn(4) == 0 {
+		level++
+	}
+	for ; l.level < level; l.level++ {
+		update[l.level] = &l.head
+	}
+
+	n := &slNode[T]{val: v, next: make([]*slNode[T], level)}
+	for i := 0; i < level; i++ {
+		n.next[i] = update[i].next[i]
+		update[i].next[i] = n
+	}
+	l.len++
+	return true
+}
+
+// Remove removes v from the list, and reports whether it was in it.
+func (l *skiplist[T]) Remove(v T) bool {
+	var update [skiplistMaxLevel]*slNode[T]
+	n := l.path(v, &update)
+	if n == nil || l.less(v, n.val) {
+		return false
+	}
+
+	for i := 0; i < len(n.next); i++ {
+		update[i].next[i] = n.next[i]
+	}
+	for l.level > 1 && l.head.next[l.level-1] == nil {
+		l.level--
+	}
+	l.len--
+	return true
+}
+
+// Seek returns the first node holding a value at or after v, or nil.
+func (l *skiplist[T]) Seek(v T) *slNode[T] {
+	return l.path(v, nil)
+}
+
+// First returns the node holding the smallest value, or nil.
+func (l *skiplist[T]) First() *slNode[T] {
+	return l.head.next[0]
+}
+
+// Next returns the node following n, or nil.
+func (n *slNode[T]) Next() *slNode[T] {
+	return n.next[0]
+}
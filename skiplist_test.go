@@ -0,0 +1,48 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestSkiplist(t *testing.T) {
+	l := newSkiplist(func(a, b int) bool { return a < b })
+	for _, i := range rand.Perm(1000) {
+		if !l.Insert(i * 2) {
+			t.Fatalf("expected %d to be inserted", i*2)
+		}
+	}
+	if l.Insert(42) {
+		t.Fatal("expected duplicate insertion to fail")
+	}
+	for i := 0; i < 1000; i += 3 {
+		if !l.Remove(i * 2) {
+			t.Fatalf("expected %d to be removed", i*2)
+		}
+	}
+	if l.Remove(1) {
+		t.Fatal("expected removing a missing value to fail")
+	}
+
+	prev := -1
+	count := 0
+	for n := l.First(); n != nil; n = n.Next() {
+		if n.val <= prev || n.val%6 == 0 {
+			t.Fatalf("unexpected value %d after %d", n.val, prev)
+		}
+		prev = n.val
+		count++
+	}
+	if count != l.Len() || count != 666 {
+		t.Fatalf("expected 666 values, got %v (len: %v)", count, l.Len())
+	}
+	if n := l.Seek(11); n == nil || n.val != 14 {
+		t.Fatalf("expected seeking 11 to find 14, got %v", n)
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import "unsafe"
+
+const (
+	minSlabChunk = 8
+	maxSlabChunk = 1024
+)
+
+// slab allocates buckets in chunks rather than one by one, and recycles the
+// buckets of deleted keys, which spares the allocator and the garbage
+// collector most of the work in caches where keys come and go constantly.
+//
+// A chunk is only released once none of its buckets are referenced anymore,
+// so the free list is kept no longer than the number of live keys: caches
+// that shrink eventually give their memory back.
+//
+// Buckets hold fields accessed atomically, which must be 64-bit aligned on
+// 32-bit platforms. Only the first word of an allocation is guaranteed to
+// be, so buckets whose size is not a multiple of 8 bytes are allocated one
+// by one instead, and only get recycled.
+type slab[K, V any] struct {
+	chunk []cacheBucket[K, V]
+	free  []*cacheBucket[K, V]
+	total int // buckets carved out of chunks so far
+}
+
+// alloc returns a zeroed bucket.
+func (s *slab[K, V]) alloc() *cacheBucket[K, V] {
+	if n := len(s.free); n > 0 {
+		bucket := s.free[n-1]
+		s.free[n-1] = nil
+		s.free = s.free[:n-1]
+		return bucket
+	}
+	if !s.chunked() {
+		s.total++
+		return new(cacheBucket[K, V])
+	}
+	if len(s.chunk) == 0 {
+		// Chunks grow along with the cache, so that small caches stay small.
+		n := s.total
+		if n < minSlabChunk {
+			n = minSlabChunk
+		}
+		if n > maxSlabChunk {
+			n = maxSlabChunk
+		}
+		s.reserve(n)
+	}
+	bucket := &s.chunk[0]
+	s.chunk = s.chunk[1:]
+	s.total++
+	return bucket
+}
+
+// reserve allocates a chunk of n buckets at once, replacing the current one.
+func (s *slab[K, V]) reserve(n int) {
+	if s.chunked() {
+		s.chunk = make([]cacheBucket[K, V], n)
+	}
+}
+
+// chunked reports whether buckets stay 64-bit aligned when allocated next to
+// each other.
+func (s *slab[K, V]) chunked() bool {
+	return unsafe.Sizeof(cacheBucket[K, V]{})%8 == 0
+}
+
+// release recycles the bucket of a key deleted from a cache holding live
+// keys. The bucket must not be referenced anymore.
+func (s *slab[K, V]) release(bucket *cacheBucket[K, V], live int) {
+	*bucket = cacheBucket[K, V]{}
+	if len(s.free) < live {
+		s.free = append(s.free, bucket)
+	}
+	for len(s.free) > live {
+		s.free[len(s.free)-1] = nil
+		s.free = s.free[:len(s.free)-1]
+	}
+}
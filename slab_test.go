@@ -0,0 +1,58 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSlabChurn(t *testing.T) {
+	c := New[int, int]()
+	for i := 0; i < 100; i++ {
+		c.Set(i, i, time.Hour)
+	}
+
+	// Replacing keys reuses the buckets of the ones that were removed.
+	next := 100
+	allocs := testing.AllocsPerRun(1000, func() {
+		c.Expire(next - 100)
+		c.Set(next, next, time.Hour)
+		next++
+	})
+	if allocs != 0 {
+		t.Fatalf("expected churn not to allocate, got %v allocations per run", allocs)
+	}
+	for i := next - 100; i < next; i++ {
+		if v, ok := c.Get(i); !ok || v != i {
+			t.Fatalf("expected %d to be cached, got %v, %v", i, v, ok)
+		}
+	}
+	c.mux.Lock()
+	err := c.checkInvariants()
+	c.mux.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSlabShrink(t *testing.T) {
+	c := New[int, int]()
+	for i := 0; i < 1000; i++ {
+		c.Set(i, i, time.Hour)
+	}
+	c.ExpireFunc(func(key, _ int) bool { return key >= 10 })
+
+	if n := len(c.slab.free); n > 10 {
+		t.Fatalf("expected at most 10 recycled buckets, got %d", n)
+	}
+	for _, bucket := range c.slab.free {
+		if bucket.key != 0 || bucket.expiry != 0 {
+			t.Fatal("expected recycled buckets to be zeroed")
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"time"
+)
+
+// Snapshot is an immutable, point-in-time view of the live entries of a
+// cache. It can be read from any number of goroutines without locking, while
+// the cache keeps changing.
+type Snapshot[K comparable, V any] struct {
+	time    time.Time
+	version int
+	entries []Entry[K, V]
+	index   map[K]int
+}
+
+// Snapshot returns a consistent view of the live entries of the cache.
+//
+// The cache is only locked while its entries get copied, which takes time
+// linear in the size of the cache; all reads from the snapshot afterwards are
+// done on the copy.
+func (cache *Cache[K, V]) Snapshot() *Snapshot[K, V] {
+	now := cache.now()
+	entries := cache.liveEntries()
+
+	index := make(map[K]int, len(entries))
+	for i, e := range entries {
+		index[e.Key] = i
+	}
+	return &Snapshot[K, V]{time: now, version: cache.SnapshotVersion, entries: entries, index: index}
+}
+
+// Time returns the time at which the snapshot was taken.
+func (s *Snapshot[K, V]) Time() time.Time {
+	return s.time
+}
+
+// Len returns the number of entries in the snapshot.
+func (s *Snapshot[K, V]) Len() int {
+	return len(s.entries)
+}
+
+// Get retrieves the value for the specified key in the snapshot, as well as
+// whether it was found.
+func (s *Snapshot[K, V]) Get(key K) (value V, found bool) {
+	e, found := s.Entry(key)
+	return e.Value, found
+}
+
+// Entry retrieves the entry for the specified key in the snapshot, as well
+// as whether it was found.
+func (s *Snapshot[K, V]) Entry(key K) (e Entry[K, V], found bool) {
+	i, found := s.index[key]
+	if found {
+		e = s.entries[i]
+	}
+	return e, found
+}
+
+// Range calls fn for each entry in the snapshot, in no particular order,
+// until fn returns false.
+func (s *Snapshot[K, V]) Range(fn func(e Entry[K, V]) bool) {
+	for _, e := range s.entries {
+		if !fn(e) {
+			return
+		}
+	}
+}
+
+// Entries returns a copy of all entries in the snapshot, in no particular
+// order.
+func (s *Snapshot[K, V]) Entries() []Entry[K, V] {
+	return append([]Entry[K, V](nil), s.entries...)
+}
@@ -0,0 +1,49 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSnapshot(t *testing.T) {
+	c := New[string, int]()
+	c.Set("foo", 1, time.Hour)
+	c.Set("bar", 2, time.Hour)
+	c.Set("expired", 3, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	snap := c.Snapshot()
+	c.Set("foo", 4, time.Hour)
+	c.Expire("bar")
+	c.Set("baz", 5, time.Hour)
+
+	if snap.Len() != 2 {
+		t.Fatalf("expected 2 entries in the snapshot, got %v", snap.Entries())
+	}
+	if v, ok := snap.Get("foo"); !ok || v != 1 {
+		t.Fatalf("expected foo to be 1 in the snapshot, got %v", v)
+	}
+	if _, ok := snap.Get("bar"); !ok {
+		t.Fatal("expected bar to still be in the snapshot")
+	}
+	for _, key := range []string{"baz", "expired"} {
+		if _, ok := snap.Get(key); ok {
+			t.Fatalf("expected %v not to be in the snapshot", key)
+		}
+	}
+
+	var n int
+	snap.Range(func(e Entry[string, int]) bool {
+		n++
+		return false
+	})
+	if n != 1 {
+		t.Fatalf("expected Range to stop after the first entry, got %v", n)
+	}
+}
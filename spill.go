@@ -0,0 +1,210 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"container/list"
+	"crypto/cipher"
+	"encoding/gob"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// SpillBackend is a Backend keeping a bounded number of values in memory,
+// and spilling the least recently used ones to files in a directory. Spilled
+// values are transparently read back into memory when loaded.
+//
+// This suits values that are expensive to recompute but too large to all
+// hold in memory. Values are encoded with encoding/gob, so their type must
+// be encodable; values that fail to be spilled are dropped.
+type SpillBackend[K comparable, V any] struct {
+	// OnError, if set, gets called whenever spilling or reading back a
+	// value fails.
+	OnError func(err error)
+
+	// Cipher, if set, encrypts spilled values, keeping them off the disk in
+	// plaintext. See EncryptWriter.
+	Cipher cipher.AEAD
+
+	dir      string
+	resident int
+	values   map[K]*list.Element
+	lru      *list.List
+	spilled  map[K]uint64
+	seq      uint64
+	mux      sync.Mutex
+}
+
+type spillEntry[K, V any] struct {
+	key   K
+	value V
+}
+
+// NewSpillBackend returns a SpillBackend keeping at most resident values in
+// memory, and spilling the others to files in dir, which gets created if
+// needed.
+//
+// Files are spilled to a new directory of their own in dir, so that
+// backends sharing dir, or files left over by previous processes, never
+// collide with them. Close removes that directory; closing a cache using the
+// backend closes it too.
+func NewSpillBackend[K comparable, V any](dir string, resident int) (*SpillBackend[K, V], error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp(dir, "spill-")
+	if err != nil {
+		return nil, err
+	}
+	return &SpillBackend[K, V]{
+		dir:      dir,
+		resident: resident,
+		values:   make(map[K]*list.Element),
+		lru:      list.New(),
+		spilled:  make(map[K]uint64),
+	}, nil
+}
+
+func (b *SpillBackend[K, V]) Load(key K) (value V, found bool) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	if elt, ok := b.values[key]; ok {
+		b.lru.MoveToFront(elt)
+		return elt.Value.(*spillEntry[K, V]).value, true
+	}
+	id, ok := b.spilled[key]
+	if !ok {
+		return value, false
+	}
+	delete(b.spilled, key)
+	defer os.Remove(b.path(id))
+
+	f, err := os.Open(b.path(id))
+	if err == nil {
+		var r io.Reader = f
+		if b.Cipher != nil {
+			r = DecryptReader(f, b.Cipher)
+		}
+		err = gob.NewDecoder(r).Decode(&value)
+		f.Close()
+	}
+	if err != nil {
+		b.report(err)
+		return value, false
+	}
+	b.insert(key, value)
+	return value, true
+}
+
+func (b *SpillBackend[K, V]) Store(key K, value V) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	if elt, ok := b.values[key]; ok {
+		elt.Value.(*spillEntry[K, V]).value = value
+		b.lru.MoveToFront(elt)
+		return
+	}
+	b.unspill(key)
+	b.insert(key, value)
+}
+
+func (b *SpillBackend[K, V]) Delete(key K) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	if elt, ok := b.values[key]; ok {
+		b.lru.Remove(elt)
+		delete(b.values, key)
+	}
+	b.unspill(key)
+}
+
+// Close drops all the values of the backend, and removes the directory
+// values were spilled to. Values stored afterwards are kept in memory, or
+// dropped if they would have to be spilled.
+func (b *SpillBackend[K, V]) Close() error {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	b.values = make(map[K]*list.Element)
+	b.lru.Init()
+	b.spilled = make(map[K]uint64)
+	return os.RemoveAll(b.dir)
+}
+
+// Resident returns how many values are held in memory, and how many are
+// spilled to disk.
+func (b *SpillBackend[K, V]) Resident() (resident, spilled int) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	return len(b.values), len(b.spilled)
+}
+
+// insert adds a value in memory, spilling the least recently used values to
+// make room if needed. b.mux must be held.
+func (b *SpillBackend[K, V]) insert(key K, value V) {
+	b.values[key] = b.lru.PushFront(&spillEntry[K, V]{key: key, value: value})
+	for b.lru.Len() > b.resident {
+		elt := b.lru.Back()
+		b.lru.Remove(elt)
+		entry := elt.Value.(*spillEntry[K, V])
+		delete(b.values, entry.key)
+		b.spill(entry.key, entry.value)
+	}
+}
+
+func (b *SpillBackend[K, V]) spill(key K, value V) {
+	b.seq++
+	id := b.seq
+	f, err := os.OpenFile(b.path(id), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		b.report(err)
+		return
+	}
+	var w io.WriteCloser = f
+	if b.Cipher != nil {
+		w = EncryptWriter(f, b.Cipher)
+	}
+	err = gob.NewEncoder(w).Encode(&value)
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	if w != f {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		os.Remove(b.path(id))
+		b.report(err)
+		return
+	}
+	b.spilled[key] = id
+}
+
+func (b *SpillBackend[K, V]) unspill(key K) {
+	if id, ok := b.spilled[key]; ok {
+		delete(b.spilled, key)
+		os.Remove(b.path(id))
+	}
+}
+
+func (b *SpillBackend[K, V]) path(id uint64) string {
+	return filepath.Join(b.dir, strconv.FormatUint(id, 36))
+}
+
+func (b *SpillBackend[K, V]) report(err error) {
+	if onError := b.OnError; onError != nil {
+		onError(err)
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSpillBackend(t *testing.T) {
+	dir := t.TempDir()
+	backend, err := NewSpillBackend[int, []byte](dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend.OnError = func(err error) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	c := NewWithBackend[int, []byte](backend)
+	for i := 0; i < 5; i++ {
+		c.Set(i, []byte{byte(i)}, time.Hour)
+	}
+	if resident, spilled := backend.Resident(); resident != 2 || spilled != 3 {
+		t.Fatalf("expected 2 resident and 3 spilled values, got %d and %d", resident, spilled)
+	}
+
+	for i := 0; i < 5; i++ {
+		v, ok := c.Get(i)
+		if !ok || len(v) != 1 || v[0] != byte(i) {
+			t.Fatalf("expected key %d to be read back, got %v, %v", i, v, ok)
+		}
+	}
+
+	for i := 0; i < 5; i++ {
+		c.Expire(i)
+	}
+	files, err := os.ReadDir(backend.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 0 {
+		t.Fatalf("expected spilled files to be removed, got %v", files)
+	}
+}
+
+func TestSpillBackendLeftovers(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "1"), []byte("stale"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var backends []*SpillBackend[int, int]
+	for i := 0; i < 2; i++ {
+		backend, err := NewSpillBackend[int, int](dir, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		backend.OnError = func(err error) {
+			t.Errorf("unexpected error: %v", err)
+		}
+		backends = append(backends, backend)
+	}
+	for i, backend := range backends {
+		c := NewWithBackend[int, int](backend)
+		c.Set(1, i, time.Hour)
+		if v, ok := c.Get(1); !ok || v != i {
+			t.Fatalf("expected backend %d to read back its own value, got %v, %v", i, v, ok)
+		}
+	}
+}
+
+func TestSpillBackendCipher(t *testing.T) {
+	dir := t.TempDir()
+	backend, err := NewSpillBackend[int, string](dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend.Cipher = newTestAEAD(t)
+	backend.OnError = func(err error) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	c := NewWithBackend[int, string](backend)
+	c.Set(1, "secret", time.Hour)
+
+	files, err := os.ReadDir(backend.dir)
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected the value to be spilled, got %v, %v", files, err)
+	}
+	data, err := os.ReadFile(filepath.Join(backend.dir, files[0].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("secret")) {
+		t.Fatal("expected the spilled value to be encrypted")
+	}
+	if v, ok := c.Get(1); !ok || v != "secret" {
+		t.Fatalf("expected the value to be read back, got %q, %v", v, ok)
+	}
+}
+
+func TestSpillBackendClose(t *testing.T) {
+	dir := t.TempDir()
+	backend, err := NewSpillBackend[int, int](dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewWithBackend[int, int](backend)
+	for i := 0; i < 3; i++ {
+		c.Set(i, i, time.Hour)
+	}
+	if err := c.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(backend.dir); !os.IsNotExist(err) {
+		t.Fatalf("expected closing the cache to remove the spill directory, got %v", err)
+	}
+	if resident, spilled := backend.Resident(); resident != 0 || spilled != 0 {
+		t.Fatalf("expected the backend to be emptied, got %d resident and %d spilled values", resident, spilled)
+	}
+}
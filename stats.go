@@ -0,0 +1,106 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"math"
+	"math/bits"
+	"time"
+)
+
+// Stats holds statistics about the cache.
+type Stats struct {
+	// TTLs is the distribution of TTLs passed to Set.
+	TTLs Histogram
+
+	// Lifetimes is the distribution of the age of entries at the time they
+	// were removed from the cache, be it because they expired or because
+	// they were deleted. Comparing it with TTLs tells whether entries tend
+	// to be invalidated long before their TTL is up.
+	Lifetimes Histogram
+}
+
+// Stats returns a copy of the current statistics of the cache.
+func (cache *Cache[K, V]) Stats() Stats {
+	cache.mux.RLock()
+	defer cache.mux.RUnlock()
+
+	return cache.stats
+}
+
+// Histogram is a distribution of durations, bucketed by powers of two of
+// nanoseconds.
+type Histogram struct {
+	// Buckets[0] counts zero and negative durations, and Buckets[i] counts
+	// durations d such that 2^(i-1) <= d < 2^i nanoseconds.
+	Buckets [65]uint64
+
+	// Count is the total number of observed durations.
+	Count uint64
+
+	// Sum is the sum of all observed durations. It saturates at the bounds
+	// of time.Duration rather than overflowing.
+	Sum time.Duration
+}
+
+func (h *Histogram) observe(d time.Duration) {
+	i := 0
+	if d > 0 {
+		i = bits.Len64(uint64(d))
+	}
+	h.Buckets[i]++
+	h.Count++
+	switch {
+	case d > 0 && h.Sum > math.MaxInt64-d:
+		h.Sum = math.MaxInt64
+	case d < 0 && h.Sum < math.MinInt64-d:
+		h.Sum = math.MinInt64
+	default:
+		h.Sum += d
+	}
+}
+
+// Mean returns the average of all observed durations.
+func (h *Histogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// Quantile returns an upper bound of the q-quantile of observed durations,
+// with q between 0 and 1. Due to the bucketing, the bound is within a factor
+// of two of the actual value.
+func (h *Histogram) Quantile(q float64) time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	rank := uint64(q * float64(h.Count))
+	if rank >= h.Count {
+		rank = h.Count - 1
+	}
+	var seen uint64
+	for i, n := range h.Buckets {
+		seen += n
+		if seen > rank {
+			return h.UpperBound(i)
+		}
+	}
+	return h.UpperBound(len(h.Buckets) - 1)
+}
+
+// UpperBound returns the exclusive upper bound of durations counted in the
+// specified bucket.
+func (h *Histogram) UpperBound(bucket int) time.Duration {
+	if bucket == 0 {
+		return 1
+	}
+	if bucket >= 63 {
+		return time.Duration(1<<63 - 1)
+	}
+	return time.Duration(1) << bucket
+}
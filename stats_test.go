@@ -0,0 +1,53 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	c := New[string, int]()
+	c.Set("foo", 1, time.Hour)
+	c.Set("bar", 2, time.Hour)
+	c.Set("baz", 3, time.Second)
+	c.Expire("foo")
+
+	stats := c.Stats()
+	if stats.TTLs.Count != 3 {
+		t.Fatalf("expected 3 observed TTLs, got %v", stats.TTLs.Count)
+	}
+	if mean := stats.TTLs.Mean(); mean != (2*time.Hour+time.Second)/3 {
+		t.Fatalf("unexpected mean TTL %v", mean)
+	}
+	if q := stats.TTLs.Quantile(0.5); q < time.Hour || q >= 2*time.Hour {
+		t.Fatalf("expected median TTL to be bounded by [1h, 2h), got %v", q)
+	}
+	if q := stats.TTLs.Quantile(0); q < time.Second || q >= 2*time.Second {
+		t.Fatalf("expected minimum TTL to be bounded by [1s, 2s), got %v", q)
+	}
+	if stats.Lifetimes.Count != 1 {
+		t.Fatalf("expected 1 observed lifetime, got %v", stats.Lifetimes.Count)
+	}
+	if q := stats.Lifetimes.Quantile(1); q > time.Second {
+		t.Fatalf("expected lifetime of foo to be short, got %v", q)
+	}
+}
+
+func TestHistogramSaturation(t *testing.T) {
+	var h Histogram
+	h.observe(math.MaxInt64)
+	h.observe(time.Hour)
+	if h.Sum != math.MaxInt64 {
+		t.Fatalf("expected the sum to saturate, got %v", h.Sum)
+	}
+	if m := h.Mean(); m <= 0 {
+		t.Fatalf("expected a positive mean, got %v", m)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+//go:build go1.25
+
+package ttlcache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+// The cache only ever gets the time through package time, so that fake time
+// in synctest bubbles drives expiry like real time would.
+
+func TestSynctestExpiry(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		c := New[string, int]()
+		c.Set("foo", 1, time.Hour)
+		c.Set("bar", 2, 2*time.Hour)
+
+		time.Sleep(time.Hour - time.Nanosecond)
+		c.Flush()
+		if _, ok := c.Get("foo"); !ok {
+			t.Fatal("expected foo not to have expired yet")
+		}
+
+		time.Sleep(time.Nanosecond)
+		c.Flush()
+		if _, ok := c.Get("foo"); ok {
+			t.Fatal("expected foo to have expired")
+		}
+		if next, _ := c.NextExpiry(); !next.Equal(time.Now().Add(time.Hour)) {
+			t.Fatalf("expected bar to expire in exactly one hour, got %v", next)
+		}
+	})
+}
+
+func TestSynctestWaitFor(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		c := New[string, int]()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		go func() {
+			time.Sleep(30 * time.Second)
+			c.Set("foo", 1, time.Hour)
+		}()
+		if v, err := c.WaitFor(ctx, "foo"); err != nil || v != 1 {
+			t.Fatalf("expected WaitFor to return 1, got %v (err: %v)", v, err)
+		}
+		if _, err := c.WaitFor(ctx, "bar"); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected WaitFor on bar to time out, got %v", err)
+		}
+	})
+}
+
+func TestSynctestRefresher(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		var loads int
+		c := New[string, int]()
+		c.Loader = func(ctx context.Context, key string) (int, time.Duration, error) {
+			loads++
+			return loads, time.Hour, nil
+		}
+
+		r := c.NewRefresher(1)
+		r.Register("foo", time.Minute)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute+time.Second)
+		defer cancel()
+		r.Run(ctx)
+
+		if v, _ := c.Get("foo"); v != 11 {
+			t.Fatalf("expected foo to have been loaded 11 times, got %v", v)
+		}
+	})
+}
+
+func TestSynctestJanitor(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		c := New[string, int]()
+		expired := make(chan time.Time, 2)
+		c.OnExpire = func(string, int) { expired <- time.Now() }
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go c.NewJanitor().Run(ctx)
+
+		start := time.Now()
+		c.Set("foo", 1, time.Hour)
+		synctest.Wait()
+		c.Set("bar", 2, time.Minute)
+
+		if at := <-expired; !at.Equal(start.Add(time.Minute)) {
+			t.Fatalf("expected bar to be flushed right as it expired, got %v", at.Sub(start))
+		}
+		if at := <-expired; !at.Equal(start.Add(time.Hour)) {
+			t.Fatalf("expected foo to be flushed right as it expired, got %v", at.Sub(start))
+		}
+	})
+}
@@ -0,0 +1,88 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"time"
+)
+
+// ExpiryTimer delivers the time on C when the soonest key of a cache becomes
+// due, letting applications with their own event loop flush the cache on
+// time without a dedicated goroutine:
+//
+//	t := cache.NewExpiryTimer()
+//	defer t.Stop()
+//	for {
+//		select {
+//		case <-t.C:
+//			t.Flush()
+//		case ...:
+//		}
+//	}
+//
+// The timer is moved earlier whenever a key due sooner gets set.
+type ExpiryTimer[K comparable, V any] struct {
+	C <-chan time.Time
+
+	cache *Cache[K, V]
+	timer *time.Timer
+}
+
+// NewExpiryTimer returns a timer firing when the soonest key of the cache
+// becomes due.
+func (cache *Cache[K, V]) NewExpiryTimer() *ExpiryTimer[K, V] {
+	timer := time.NewTimer(time.Hour)
+	t := &ExpiryTimer[K, V]{C: timer.C, cache: cache, timer: timer}
+
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	t.arm()
+	cache.timers = append(cache.timers, t)
+	return t
+}
+
+// Flush is like Cache.Flush, and sets the timer to fire when the next key is
+// due. It should be called whenever the timer fires.
+func (t *ExpiryTimer[K, V]) Flush() int {
+	cache := t.cache
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	n := cache.flush()
+	t.arm()
+	return n
+}
+
+// Stop stops the timer, which no longer fires.
+func (t *ExpiryTimer[K, V]) Stop() {
+	cache := t.cache
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	for i, other := range cache.timers {
+		if other == t {
+			cache.timers = append(cache.timers[:i], cache.timers[i+1:]...)
+			break
+		}
+	}
+	t.timer.Stop()
+}
+
+// arm sets the timer to fire when the soonest key is due, or stops it if the
+// cache is empty. cache.mux must be held for writing.
+func (t *ExpiryTimer[K, V]) arm() {
+	if !t.timer.Stop() {
+		select {
+		case <-t.timer.C:
+		default:
+		}
+	}
+	if bucket, ok := t.cache.expireList.Peek(); ok {
+		t.timer.Reset(bucket.expiry.Sub(t.cache.instant()))
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpiryTimer(t *testing.T) {
+	c := New[string, int]()
+	timer := c.NewExpiryTimer()
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		t.Fatal("expected the timer of an empty cache not to fire")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	c.Set("later", 1, time.Hour)
+	c.Set("sooner", 2, 10*time.Millisecond)
+	select {
+	case <-timer.C:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the timer to fire when sooner expired")
+	}
+	if n := timer.Flush(); n != 1 {
+		t.Fatalf("expected 1 key to be flushed, got %d", n)
+	}
+
+	select {
+	case <-timer.C:
+		t.Fatal("expected the timer to be set for the next key")
+	case <-time.After(20 * time.Millisecond):
+	}
+	if _, ok := c.Get("later"); !ok {
+		t.Fatal("expected later not to have expired")
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Op is a kind of cache operation, as recorded in traces.
+type Op int
+
+const (
+	OpGet Op = iota
+	OpSet
+	OpExpire
+)
+
+func (op Op) String() string {
+	switch op {
+	case OpGet:
+		return "get"
+	case OpSet:
+		return "set"
+	case OpExpire:
+		return "expire"
+	default:
+		return "unknown"
+	}
+}
+
+// TraceRecord describes an operation done on a cache.
+type TraceRecord[K any] struct {
+	Op   Op
+	Key  K
+	TTL  time.Duration // only set for OpSet
+	Time time.Time
+}
+
+// Recorder records sampled cache operations in memory, for later analysis
+// or replay. To record the operations of a cache, set its Trace callback to
+// the Record method of a recorder.
+type Recorder[K any] struct {
+	rate    float64
+	records []TraceRecord[K]
+	mux     sync.Mutex
+}
+
+// NewRecorder returns a recorder keeping a fraction rate, between 0 and 1,
+// of the operations it is given.
+//
+// Operations are sampled independently from each other, so that replaying a
+// sampled trace yields lower hit rates than the full trace would.
+func NewRecorder[K any](rate float64) *Recorder[K] {
+	return &Recorder[K]{rate: rate}
+}
+
+// Record records rec, subject to sampling. It is safe for concurrent use.
+func (r *Recorder[K]) Record(rec TraceRecord[K]) {
+	if r.rate < 1 && rand.Float64() >= r.rate {
+		return
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	r.records = append(r.records, rec)
+}
+
+// Records returns a copy of the records recorded so far.
+func (r *Recorder[K]) Records() []TraceRecord[K] {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	return append([]TraceRecord[K](nil), r.records...)
+}
+
+// Reset drops all the records recorded so far.
+func (r *Recorder[K]) Reset() {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	r.records = nil
+}
+
+func (cache *Cache[K, V]) trace(op Op, key K, ttl time.Duration) {
+	if trace := cache.Trace; trace != nil {
+		rec := TraceRecord[K]{Op: op, Key: key, TTL: ttl, Time: cache.now()}
+		cache.guard("Trace", func() { trace(rec) })
+	}
+}
+
+// ReplayStats holds the outcome of replaying a trace.
+type ReplayStats struct {
+	Gets    uint64
+	Hits    uint64
+	Misses  uint64
+	Sets    uint64
+	Expires uint64
+}
+
+// HitRate returns the fraction of gets that were hits.
+func (s ReplayStats) HitRate() float64 {
+	if s.Gets == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Gets)
+}
+
+type replayClock struct {
+	now time.Time
+}
+
+func (c *replayClock) Now() time.Time {
+	return c.now
+}
+
+// Replay feeds the operations of trace into the cache, as if they happened
+// at the time they were recorded, and reports the outcome. Set operations
+// store the zero value of V.
+//
+// This allows comparing how differently configured caches would have fared
+// against the same workload. The cache should be otherwise unused during the
+// replay, as its clock is temporarily replaced.
+func Replay[K comparable, V any](cache *Cache[K, V], trace []TraceRecord[K]) ReplayStats {
+	clock := &replayClock{}
+	prev := cache.Clock
+	cache.Clock = clock
+	defer func() { cache.Clock = prev }()
+
+	var stats ReplayStats
+	var zero V
+	for _, rec := range trace {
+		clock.now = rec.Time
+		switch rec.Op {
+		case OpGet:
+			stats.Gets++
+			if _, ok := cache.Get(rec.Key); ok {
+				stats.Hits++
+			} else {
+				stats.Misses++
+			}
+		case OpSet:
+			stats.Sets++
+			cache.Set(rec.Key, zero, rec.TTL)
+		case OpExpire:
+			stats.Expires++
+			cache.Expire(rec.Key)
+		}
+	}
+	return stats
+}
@@ -0,0 +1,61 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTraceReplay(t *testing.T) {
+	rec := NewRecorder[string](1)
+	c := New[string, int]()
+	c.Trace = rec.Record
+
+	c.Get("foo")
+	c.Set("foo", 1, time.Hour)
+	c.Get("foo")
+	c.Expire("foo")
+	c.Get("foo")
+
+	trace := rec.Records()
+	if len(trace) != 5 {
+		t.Fatalf("expected 5 records, got %v", trace)
+	}
+	if trace[1].Op != OpSet || trace[1].Key != "foo" || trace[1].TTL != time.Hour {
+		t.Fatalf("unexpected set record %v", trace[1])
+	}
+
+	stats := Replay(New[string, int](), trace)
+	if stats.Gets != 3 || stats.Hits != 1 || stats.Misses != 2 || stats.Sets != 1 || stats.Expires != 1 {
+		t.Fatalf("unexpected replay outcome %+v", stats)
+	}
+
+	// Replays happen at the recorded times, regardless of how long ago
+	// that was.
+	start := time.Now()
+	trace = []TraceRecord[string]{
+		{Op: OpSet, Key: "foo", TTL: time.Hour, Time: start},
+		{Op: OpSet, Key: "bar", TTL: time.Hour, Time: start.Add(2 * time.Hour)},
+		{Op: OpGet, Key: "foo", Time: start.Add(2 * time.Hour)},
+		{Op: OpGet, Key: "bar", Time: start.Add(2 * time.Hour)},
+	}
+	stats = Replay(New[string, int](), trace)
+	if stats.HitRate() != 0.5 {
+		t.Fatalf("expected hit rate of 0.5, got %+v", stats)
+	}
+
+	rec.Reset()
+	if len(rec.Records()) != 0 {
+		t.Fatal("expected recorder to be empty after a reset")
+	}
+	none := NewRecorder[string](0)
+	none.Record(TraceRecord[string]{Op: OpGet, Key: "foo"})
+	if len(none.Records()) != 0 {
+		t.Fatal("expected nothing to be recorded with a sampling rate of 0")
+	}
+}
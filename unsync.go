@@ -0,0 +1,59 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"sync"
+)
+
+// NewUnsynchronized returns a cache that does no locking at all, for
+// programs that already serialize accesses to it, like event loops. It
+// behaves like a cache returned by New otherwise, but must not be used by
+// several goroutines at once, including background work like Refreshers.
+func NewUnsynchronized[K comparable, V any]() *Cache[K, V] {
+	cache := New[K, V]()
+	cache.mux.unsync = true
+	return cache
+}
+
+// cacheMutex is a sync.RWMutex that can be turned into a no-op.
+type cacheMutex struct {
+	mux    sync.RWMutex
+	unsync bool
+}
+
+func (m *cacheMutex) Lock() {
+	if !m.unsync {
+		m.mux.Lock()
+	}
+}
+
+func (m *cacheMutex) Unlock() {
+	if !m.unsync {
+		m.mux.Unlock()
+	}
+}
+
+func (m *cacheMutex) RLock() {
+	if !m.unsync {
+		m.mux.RLock()
+	}
+}
+
+func (m *cacheMutex) RUnlock() {
+	if !m.unsync {
+		m.mux.RUnlock()
+	}
+}
+
+func (m *cacheMutex) TryLock() bool {
+	return m.unsync || m.mux.TryLock()
+}
+
+func (m *cacheMutex) TryRLock() bool {
+	return m.unsync || m.mux.TryRLock()
+}
@@ -0,0 +1,36 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUnsynchronized(t *testing.T) {
+	c := NewUnsynchronized[string, int]()
+	c.Set("foo", 1, time.Hour)
+	c.Set("bar", 2, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	c.Set("baz", 3, time.Hour)
+
+	if v, ok := c.Get("foo"); !ok || v != 1 {
+		t.Fatalf("expected foo to be found, got %v, %v", v, ok)
+	}
+	if _, ok := c.Get("bar"); ok {
+		t.Fatal("expected bar to be expired")
+	}
+
+	// Callbacks may call back into the cache, since nothing is locked.
+	c.OnExpire = func(key string, value int) {
+		c.Get(key)
+	}
+	if !c.TrySet("foo", 2, time.Hour) {
+		t.Fatal("expected TrySet to always succeed")
+	}
+	c.Expire("foo")
+}
@@ -0,0 +1,83 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"reflect"
+	"time"
+)
+
+// ValueTTL is implemented by values knowing how long they may be cached.
+//
+// Values set with DefaultTTL, including by loaders returning DefaultTTL, get
+// the TTL they specify, in preference to Adaptive and TTLFunc. Values set
+// with an explicit TTL never stay cached past the TTL they specify.
+type ValueTTL interface {
+	CacheTTL() time.Duration
+}
+
+// ValueExpiry is implemented by values knowing when they stop being valid,
+// like access tokens. A zero time means the value does not expire.
+//
+// Values implementing it are cached like ValueTTL values, with a TTL lasting
+// until the time they specify.
+type ValueExpiry interface {
+	ExpiresAt() time.Time
+}
+
+// Interfaces that values may implement, as a bitset.
+const (
+	valueIfacesKnown = 1 << iota
+	valueIfacesTTL
+	valueIfacesEvictee
+)
+
+var (
+	valueTTLType    = reflect.TypeOf((*ValueTTL)(nil)).Elem()
+	valueExpiryType = reflect.TypeOf((*ValueExpiry)(nil)).Elem()
+	evicteeType     = reflect.TypeOf((*Evictee)(nil)).Elem()
+)
+
+// valuesMay reports whether values may implement the specified interfaces.
+// Converting values to interfaces allocates, so it is only worth doing for
+// types that may implement them. cache.mux must be held for writing.
+func (cache *Cache[K, V]) valuesMay(ifaces uint8) bool {
+	if cache.ifaces == 0 {
+		cache.ifaces = valueIfacesKnown
+		t := reflect.TypeOf((*V)(nil)).Elem()
+		dynamic := t.Kind() == reflect.Interface
+		if dynamic || t.Implements(valueTTLType) || t.Implements(valueExpiryType) {
+			cache.ifaces |= valueIfacesTTL
+		}
+		if dynamic || t.Implements(evicteeType) {
+			cache.ifaces |= valueIfacesEvictee
+		}
+	}
+	return cache.ifaces&ifaces != 0
+}
+
+// valueTTL returns the TTL specified by value, if it specifies any. cache.mux
+// must be held for writing.
+func (cache *Cache[K, V]) valueTTL(value V) (time.Duration, bool) {
+	if !cache.valuesMay(valueIfacesTTL) {
+		return 0, false
+	}
+
+	switch v := any(value).(type) {
+	case ValueTTL:
+		var ttl time.Duration
+		ok := cache.guard("CacheTTL", func() { ttl = v.CacheTTL() })
+		return ttl, ok
+	case ValueExpiry:
+		var expiry time.Time
+		if !cache.guard("ExpiresAt", func() { expiry = v.ExpiresAt() }) || expiry.IsZero() {
+			return 0, false
+		}
+		return expiry.Sub(cache.now()), true
+	}
+	return 0, false
+}
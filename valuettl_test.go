@@ -0,0 +1,74 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"testing"
+	"time"
+)
+
+type testToken struct {
+	expiry time.Time
+}
+
+func (t testToken) ExpiresAt() time.Time {
+	return t.expiry
+}
+
+type testTTL time.Duration
+
+func (t testTTL) CacheTTL() time.Duration {
+	return time.Duration(t)
+}
+
+func TestValueExpiry(t *testing.T) {
+	now := time.Now()
+	c := New[string, testToken]()
+	c.Clock = fixedClock(now)
+	c.TTLFunc = func(string, testToken) time.Duration { return time.Hour }
+
+	c.Set("token", testToken{expiry: now.Add(time.Minute)}, DefaultTTL)
+	c.Set("capped", testToken{expiry: now.Add(time.Minute)}, time.Hour)
+	c.Set("shorter", testToken{expiry: now.Add(time.Hour)}, time.Second)
+	c.Set("forever", testToken{}, DefaultTTL)
+
+	want := map[string]time.Duration{
+		"token":   time.Minute,
+		"capped":  time.Minute,
+		"shorter": time.Second,
+		"forever": time.Hour,
+	}
+	for _, e := range c.ExpiringSoon(len(want)) {
+		if got := e.Expiry.Sub(now); got != want[e.Key] {
+			t.Fatalf("expected %q to be cached for %v, got %v", e.Key, want[e.Key], got)
+		}
+	}
+}
+
+func TestValueTTL(t *testing.T) {
+	now := time.Now()
+	c := New[string, any]()
+	c.Clock = fixedClock(now)
+
+	c.Set("ttl", testTTL(time.Minute), DefaultTTL)
+	c.Set("plain", 42, time.Hour)
+
+	want := map[string]time.Duration{"ttl": time.Minute, "plain": time.Hour}
+	for _, e := range c.ExpiringSoon(len(want)) {
+		if got := e.Expiry.Sub(now); got != want[e.Key] {
+			t.Fatalf("expected %q to be cached for %v, got %v", e.Key, want[e.Key], got)
+		}
+	}
+}
+
+func TestValueTTLAllocs(t *testing.T) {
+	c := New[string, string]()
+	c.Set("foo", "bar", time.Hour)
+	if allocs := testing.AllocsPerRun(100, func() { c.Set("foo", "bar", time.Hour) }); allocs != 0 {
+		t.Fatalf("expected values without their own TTL not to be boxed, got %v allocations", allocs)
+	}
+}
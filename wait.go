@@ -0,0 +1,83 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"context"
+)
+
+// WaitFor retrieves the value in the cache for the specified key. If there is
+// none, it blocks until another goroutine sets it, or until ctx is done, in
+// which case the context error is returned. If the cache is closed in the
+// meantime, ErrClosed is returned.
+func (cache *Cache[K, V]) WaitFor(ctx context.Context, key K) (value V, err error) {
+	cache.mux.Lock()
+	if cache.closed {
+		cache.mux.Unlock()
+		return value, ErrClosed
+	}
+	if bucket, found := cache.cache[key]; found {
+		if value, found := cache.load(bucket); found {
+			cache.mux.Unlock()
+			return value, nil
+		}
+	}
+	if cache.waiters == nil {
+		cache.waiters = make(map[K][]chan V)
+	}
+	// Buffered so that Set never blocks on a waiter that gave up.
+	ch := make(chan V, 1)
+	cache.waiters[key] = append(cache.waiters[key], ch)
+	// Values set while the cache drains still get to waiters.
+	done := cache.shutdownChan()
+	cache.mux.Unlock()
+
+	select {
+	case value = <-ch:
+		return value, nil
+	case <-done:
+		return value, ErrClosed
+	case <-ctx.Done():
+	}
+
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	// The value might have been sent while we were waiting for the lock.
+	select {
+	case value = <-ch:
+		return value, nil
+	default:
+	}
+
+	waiters := cache.waiters[key]
+	for i, w := range waiters {
+		if w == ch {
+			waiters = append(waiters[:i], waiters[i+1:]...)
+			break
+		}
+	}
+	if len(waiters) == 0 {
+		delete(cache.waiters, key)
+	} else {
+		cache.waiters[key] = waiters
+	}
+	return value, ctx.Err()
+}
+
+// wake hands over value to all goroutines waiting on key. cache.mux must be
+// held for writing.
+func (cache *Cache[K, V]) wake(key K, value V) {
+	waiters, ok := cache.waiters[key]
+	if !ok {
+		return
+	}
+	for _, ch := range waiters {
+		ch <- value
+	}
+	delete(cache.waiters, key)
+}
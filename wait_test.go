@@ -0,0 +1,43 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWaitFor(t *testing.T) {
+	c := New[string, int]()
+	c.Set("foo", 1, time.Hour)
+
+	v, err := c.WaitFor(context.Background(), "foo")
+	if err != nil || v != 1 {
+		t.Fatalf("expected WaitFor on foo to return 1, got %v (err: %v)", v, err)
+	}
+
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		c.Set("bar", 2, time.Hour)
+	}()
+	v, err = c.WaitFor(context.Background(), "bar")
+	if err != nil || v != 2 {
+		t.Fatalf("expected WaitFor on bar to return 2, got %v (err: %v)", v, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	_, err = c.WaitFor(ctx, "baz")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected WaitFor on baz to time out, got %v", err)
+	}
+	if len(c.waiters) != 0 {
+		t.Fatalf("expected waiter on baz to be removed, got %v", c.waiters)
+	}
+}
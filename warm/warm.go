@@ -0,0 +1,116 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package warm implements ttlcache.Warmers populating caches from files,
+// HTTP endpoints and SQL databases.
+//
+// Files and HTTP endpoints provide a stream of JSON records, each holding a
+// key, a value and a TTL given as a Go duration:
+//
+//	{"key": "foo", "value": 42, "ttl": "1h30m"}
+package warm
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"snai.pe/go-ttlcache"
+)
+
+type record[K, V any] struct {
+	Key   K      `json:"key"`
+	Value V      `json:"value"`
+	TTL   string `json:"ttl"`
+}
+
+// JSON returns a warmer decoding a stream of JSON records from r.
+func JSON[K comparable, V any](r io.Reader) ttlcache.Warmer[K, V] {
+	return ttlcache.WarmerFunc[K, V](func(ctx context.Context, set func(K, V, time.Duration)) error {
+		return decode(ctx, r, set)
+	})
+}
+
+// File returns a warmer decoding a stream of JSON records from the file at
+// path.
+func File[K comparable, V any](path string) ttlcache.Warmer[K, V] {
+	return ttlcache.WarmerFunc[K, V](func(ctx context.Context, set func(K, V, time.Duration)) error {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return decode(ctx, f, set)
+	})
+}
+
+// HTTP returns a warmer decoding a stream of JSON records from the response
+// to a GET request to url, sent with client, or http.DefaultClient if nil.
+func HTTP[K comparable, V any](client *http.Client, url string) ttlcache.Warmer[K, V] {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return ttlcache.WarmerFunc[K, V](func(ctx context.Context, set func(K, V, time.Duration)) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode/100 != 2 {
+			return fmt.Errorf("warm: GET %s: %s", url, resp.Status)
+		}
+		return decode(ctx, resp.Body, set)
+	})
+}
+
+// SQL returns a warmer running query on db, and calling scan for every row
+// to get the entry it holds.
+func SQL[K comparable, V any](db *sql.DB, query string, scan func(rows *sql.Rows) (K, V, time.Duration, error), args ...any) ttlcache.Warmer[K, V] {
+	return ttlcache.WarmerFunc[K, V](func(ctx context.Context, set func(K, V, time.Duration)) error {
+		rows, err := db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			key, value, ttl, err := scan(rows)
+			if err != nil {
+				return err
+			}
+			set(key, value, ttl)
+		}
+		return rows.Err()
+	})
+}
+
+func decode[K comparable, V any](ctx context.Context, r io.Reader, set func(K, V, time.Duration)) error {
+	dec := json.NewDecoder(r)
+	for {
+		var rec record[K, V]
+		if err := dec.Decode(&rec); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		ttl, err := time.ParseDuration(rec.TTL)
+		if err != nil {
+			return fmt.Errorf("warm: key %v: %w", rec.Key, err)
+		}
+		set(rec.Key, rec.Value, ttl)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
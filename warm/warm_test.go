@@ -0,0 +1,132 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package warm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"snai.pe/go-ttlcache"
+)
+
+const records = `{"key": "foo", "value": 1, "ttl": "1h"}
+{"key": "bar", "value": 2, "ttl": "30m"}`
+
+func checkWarmed(t *testing.T, c *ttlcache.Cache[string, int], err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]int{"foo": 1, "bar": 2} {
+		if v, ok := c.Get(key); !ok || v != want {
+			t.Fatalf("expected %q to be warmed, got %v, %v", key, v, ok)
+		}
+	}
+	if soon := c.ExpiringSoon(1); len(soon) != 1 || soon[0].Key != "bar" || time.Until(soon[0].Expiry) > 30*time.Minute {
+		t.Fatalf("expected bar to expire first, within 30m, got %v", soon)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	c, err := ttlcache.NewWarmed(context.Background(), JSON[string, int](strings.NewReader(records)))
+	checkWarmed(t, c, err)
+
+	_, err = ttlcache.NewWarmed(context.Background(), JSON[string, int](strings.NewReader(`{"key": "foo", "value": 1, "ttl": "soon"}`)))
+	if err == nil {
+		t.Fatal("expected invalid TTLs to be rejected")
+	}
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "warm.json")
+	if err := os.WriteFile(path, []byte(records), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := ttlcache.NewWarmed(context.Background(), File[string, int](path))
+	checkWarmed(t, c, err)
+}
+
+func TestHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/warm" {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, records)
+	}))
+	defer srv.Close()
+
+	c, err := ttlcache.NewWarmed(context.Background(), HTTP[string, int](srv.Client(), srv.URL+"/warm"))
+	checkWarmed(t, c, err)
+
+	_, err = ttlcache.NewWarmed(context.Background(), HTTP[string, int](srv.Client(), srv.URL+"/missing"))
+	if err == nil {
+		t.Fatal("expected error statuses to fail warming")
+	}
+}
+
+func TestSQL(t *testing.T) {
+	sql.Register("warmtest", testDriver{})
+	db, err := sql.Open("warmtest", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	w := SQL(db, "SELECT key, value, ttl FROM cache", func(rows *sql.Rows) (key string, value int, ttl time.Duration, err error) {
+		var seconds int64
+		err = rows.Scan(&key, &value, &seconds)
+		return key, value, time.Duration(seconds) * time.Second, err
+	})
+	c, err := ttlcache.NewWarmed(context.Background(), w)
+	checkWarmed(t, c, err)
+}
+
+// testDriver is a database/sql driver answering every query with the same
+// rows.
+type testDriver struct{}
+
+func (testDriver) Open(string) (driver.Conn, error) { return testConn{}, nil }
+
+type testConn struct{}
+
+func (testConn) Prepare(query string) (driver.Stmt, error) { return testStmt{}, nil }
+func (testConn) Close() error                              { return nil }
+func (testConn) Begin() (driver.Tx, error)                 { return nil, driver.ErrSkip }
+
+type testStmt struct{}
+
+func (testStmt) Close() error                                    { return nil }
+func (testStmt) NumInput() int                                   { return 0 }
+func (testStmt) Exec(args []driver.Value) (driver.Result, error) { return nil, driver.ErrSkip }
+func (testStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return &testRows{rows: [][]driver.Value{{"foo", int64(1), int64(3600)}, {"bar", int64(2), int64(1800)}}}, nil
+}
+
+type testRows struct {
+	rows [][]driver.Value
+}
+
+func (r *testRows) Columns() []string { return []string{"key", "value", "ttl"} }
+func (r *testRows) Close() error      { return nil }
+func (r *testRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
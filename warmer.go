@@ -0,0 +1,46 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"context"
+	"time"
+)
+
+// Warmer populates a cache from some source, like a file, a service or a
+// database, by calling set for every entry. See the warm package for
+// implementations.
+type Warmer[K comparable, V any] interface {
+	Warm(ctx context.Context, set func(key K, value V, ttl time.Duration)) error
+}
+
+// WarmerFunc adapts a function to the Warmer interface.
+type WarmerFunc[K comparable, V any] func(ctx context.Context, set func(key K, value V, ttl time.Duration)) error
+
+func (fn WarmerFunc[K, V]) Warm(ctx context.Context, set func(key K, value V, ttl time.Duration)) error {
+	return fn(ctx, set)
+}
+
+// NewWarmed returns a cache populated by the specified warmers, run one
+// after the other, so that services can fill their cache before accepting
+// traffic. It stops at the first warmer failing, and returns its error
+// along with the cache as populated so far.
+func NewWarmed[K comparable, V any](ctx context.Context, warmers ...Warmer[K, V]) (*Cache[K, V], error) {
+	cache := New[K, V]()
+	return cache, cache.WarmFrom(ctx, warmers...)
+}
+
+// WarmFrom populates the cache with the specified warmers, run one after the
+// other, and stops at the first one failing.
+func (cache *Cache[K, V]) WarmFrom(ctx context.Context, warmers ...Warmer[K, V]) error {
+	for _, w := range warmers {
+		if err := w.Warm(ctx, cache.Set); err != nil {
+			return err
+		}
+	}
+	return nil
+}
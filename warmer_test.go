@@ -0,0 +1,40 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNewWarmed(t *testing.T) {
+	ok := WarmerFunc[string, int](func(ctx context.Context, set func(string, int, time.Duration)) error {
+		set("foo", 1, time.Hour)
+		return nil
+	})
+	failed := errors.New("unavailable")
+	bad := WarmerFunc[string, int](func(ctx context.Context, set func(string, int, time.Duration)) error {
+		set("bar", 2, time.Hour)
+		return failed
+	})
+	never := WarmerFunc[string, int](func(ctx context.Context, set func(string, int, time.Duration)) error {
+		t.Fatal("expected warming to stop at the first failure")
+		return nil
+	})
+
+	c, err := NewWarmed[string, int](context.Background(), ok, bad, never)
+	if err != failed {
+		t.Fatalf("expected the warmer error, got %v", err)
+	}
+	for key, want := range map[string]int{"foo": 1, "bar": 2} {
+		if v, ok := c.Get(key); !ok || v != want {
+			t.Fatalf("expected %q to be warmed, got %v, %v", key, v, ok)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+//go:build go1.24
+
+package ttlcache
+
+import (
+	"runtime"
+	"sync"
+	"time"
+	"weak"
+)
+
+// WeakCache is a cache keyed by pointers that does not keep the objects they
+// point to alive: entries are removed once their key gets garbage collected,
+// which lets the cache annotate live objects without leaking them. Entries
+// also expire with their TTL, like in any cache.
+//
+// Values must not reference their key, or the key never becomes
+// unreachable.
+type WeakCache[T, V any] struct {
+	cache    *Cache[weak.Pointer[T], V]
+	cleanups map[weak.Pointer[T]]struct{}
+	mux      sync.Mutex
+}
+
+// NewWeak returns an empty cache keyed by weak pointers to values of type T.
+func NewWeak[T, V any]() *WeakCache[T, V] {
+	return &WeakCache[T, V]{
+		cache:    New[weak.Pointer[T], V](),
+		cleanups: make(map[weak.Pointer[T]]struct{}),
+	}
+}
+
+// Cache returns the underlying cache, to configure it or to use operations
+// that WeakCache does not provide, with keys made with weak.Make.
+func (c *WeakCache[T, V]) Cache() *Cache[weak.Pointer[T], V] {
+	return c.cache
+}
+
+// Set is like Cache.Set.
+func (c *WeakCache[T, V]) Set(key *T, value V, ttl time.Duration) {
+	wp := weak.Make(key)
+	c.watch(key, wp)
+	c.cache.Set(wp, value, ttl)
+}
+
+// Get is like Cache.Get.
+func (c *WeakCache[T, V]) Get(key *T) (value V, found bool) {
+	return c.cache.Get(weak.Make(key))
+}
+
+// Expire is like Cache.Expire.
+func (c *WeakCache[T, V]) Expire(key *T) (value V, found bool) {
+	return c.cache.Expire(weak.Make(key))
+}
+
+// watch arranges for the entry of key to be removed once key gets garbage
+// collected, unless it already is.
+func (c *WeakCache[T, V]) watch(key *T, wp weak.Pointer[T]) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if _, ok := c.cleanups[wp]; ok {
+		return
+	}
+	c.cleanups[wp] = struct{}{}
+	runtime.AddCleanup(key, c.collected, wp)
+}
+
+func (c *WeakCache[T, V]) collected(wp weak.Pointer[T]) {
+	c.mux.Lock()
+	delete(c.cleanups, wp)
+	c.mux.Unlock()
+
+	c.cache.Expire(wp)
+}
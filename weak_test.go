@@ -0,0 +1,53 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+//go:build go1.24
+
+package ttlcache
+
+import (
+	"runtime"
+	"testing"
+	"time"
+	"weak"
+)
+
+func TestWeakCache(t *testing.T) {
+	type object struct {
+		name string
+	}
+
+	c := NewWeak[object, string]()
+	expired := make(chan weak.Pointer[object], 1)
+	c.Cache().OnExpire = func(key weak.Pointer[object], _ string) { expired <- key }
+
+	kept := &object{"kept"}
+	c.Set(kept, "annotation", time.Hour)
+	c.Set(&object{"dropped"}, "annotation", time.Hour)
+	if v, ok := c.Get(kept); !ok || v != "annotation" {
+		t.Fatalf("expected kept to be annotated, got %v, %v", v, ok)
+	}
+
+	deadline := time.After(10 * time.Second)
+	for done := false; !done; {
+		runtime.GC()
+		select {
+		case key := <-expired:
+			if key.Value() != nil {
+				t.Fatal("expected only the entry of the dropped object to be removed")
+			}
+			done = true
+		case <-time.After(10 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("expected the entry of the dropped object to be removed")
+		}
+	}
+
+	if v, ok := c.Get(kept); !ok || v != "annotation" {
+		t.Fatalf("expected kept to still be annotated, got %v, %v", v, ok)
+	}
+	runtime.KeepAlive(kept)
+}
@@ -0,0 +1,174 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// writeBatchSize is the most writes a WriteBuffer applies per lock of the
+// cache.
+const writeBatchSize = 256
+
+// WriteBuffer queues up writes to a cache, for ingest paths that cannot wait
+// on the lock of the cache. Queued writes get applied in batches by Run,
+// locking the cache once per batch rather than once per write, and are
+// visible to Get through the buffer in the meantime.
+//
+// Like in ristretto, writes are dropped when the buffer is full rather than
+// blocking their caller.
+//
+// Queued writes are only applied while Run is running, on the goroutine
+// that called it; closing the cache waits for Run to return.
+type WriteBuffer[K comparable, V any] struct {
+	cache   *Cache[K, V]
+	writes  chan bufferedWrite[K, V]
+	pending map[K]bufferedWrite[K, V] // latest queued write of each key
+	seq     uint64
+	mux     sync.Mutex
+}
+
+type bufferedWrite[K, V any] struct {
+	key   K
+	value V
+	ttl   time.Duration
+	seq   uint64
+}
+
+// NewWriteBuffer returns a write buffer for the cache, holding at most size
+// writes waiting to be applied.
+func (cache *Cache[K, V]) NewWriteBuffer(size int) *WriteBuffer[K, V] {
+	if size <= 0 {
+		size = 1
+	}
+	return &WriteBuffer[K, V]{
+		cache:   cache,
+		writes:  make(chan bufferedWrite[K, V], size),
+		pending: make(map[K]bufferedWrite[K, V]),
+	}
+}
+
+// Set queues up setting the specified key like Cache.Set does, and reports
+// whether it was queued, which is not the case if the buffer is full.
+func (b *WriteBuffer[K, V]) Set(key K, value V, ttl time.Duration) bool {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	b.seq++
+	w := bufferedWrite[K, V]{key: key, value: value, ttl: ttl, seq: b.seq}
+	select {
+	case b.writes <- w:
+		b.pending[key] = w
+		return true
+	default:
+		return false
+	}
+}
+
+// Get retrieves the value for the specified key, as queued by the latest
+// call to Set that was not applied yet, or from the cache otherwise.
+func (b *WriteBuffer[K, V]) Get(key K) (value V, found bool) {
+	b.mux.Lock()
+	w, found := b.pending[key]
+	b.mux.Unlock()
+	if found {
+		return w.value, true
+	}
+	return b.cache.Get(key)
+}
+
+// Pending returns the number of keys with writes waiting to be applied.
+func (b *WriteBuffer[K, V]) Pending() int {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	return len(b.pending)
+}
+
+// Run applies queued writes until ctx is done, then applies the writes left
+// in the buffer and returns the context error. If the cache gets closed,
+// the writes left are applied as well, unless Close gives up on draining
+// first, in which case they are dropped; ErrClosed is returned either way.
+func (b *WriteBuffer[K, V]) Run(ctx context.Context) error {
+	done, shutdown, err := b.cache.startBackground()
+	if err != nil {
+		return err
+	}
+	defer b.cache.background.Done()
+	b.cache.addDrainer(b)
+	defer b.cache.removeDrainer(b)
+
+	batch := make([]bufferedWrite[K, V], 0, writeBatchSize)
+	for {
+		select {
+		case w := <-b.writes:
+			batch = b.apply(append(batch[:0], w))
+		case <-done:
+			for len(b.writes) > 0 {
+				select {
+				case <-shutdown:
+					return ErrClosed
+				default:
+				}
+				batch = b.apply(batch[:0])
+			}
+			return ErrClosed
+		case <-ctx.Done():
+			for len(b.writes) > 0 {
+				batch = b.apply(batch[:0])
+			}
+			return ctx.Err()
+		}
+	}
+}
+
+// apply applies batch, topped up with the writes waiting in the buffer, and
+// returns it.
+func (b *WriteBuffer[K, V]) apply(batch []bufferedWrite[K, V]) []bufferedWrite[K, V] {
+fill:
+	for len(batch) < writeBatchSize {
+		select {
+		case w := <-b.writes:
+			batch = append(batch, w)
+		default:
+			break fill
+		}
+	}
+
+	// Writes stop being pending with the cache still locked, so that Close
+	// never counts applied writes as dropped.
+	b.cache.mux.Lock()
+	for _, w := range batch {
+		b.cache.set(w.key, w.value, w.ttl, w.ttl)
+	}
+	b.mux.Lock()
+	for i, w := range batch {
+		if b.pending[w.key].seq == w.seq {
+			delete(b.pending, w.key)
+		}
+		batch[i] = bufferedWrite[K, V]{} // don't keep referencing the items
+	}
+	b.mux.Unlock()
+	b.cache.mux.Unlock()
+	return batch
+}
+
+// drop discards all queued writes, and returns the number of keys whose
+// writes were discarded.
+func (b *WriteBuffer[K, V]) drop() int {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	for len(b.writes) > 0 {
+		<-b.writes
+	}
+	n := len(b.pending)
+	b.pending = make(map[K]bufferedWrite[K, V])
+	return n
+}
@@ -0,0 +1,95 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWriteBuffer(t *testing.T) {
+	c := New[string, int]()
+	b := c.NewWriteBuffer(2)
+
+	if !b.Set("foo", 1, time.Hour) || !b.Set("foo", 2, time.Hour) {
+		t.Fatal("expected writes to be queued")
+	}
+	if b.Set("bar", 3, time.Hour) {
+		t.Fatal("expected writes to be dropped once the buffer is full")
+	}
+	if v, ok := b.Get("foo"); !ok || v != 2 {
+		t.Fatalf("expected the latest queued write to be visible, got %v, %v", v, ok)
+	}
+	if _, ok := c.Get("foo"); ok {
+		t.Fatal("expected writes not to be applied before Run")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := b.Run(ctx); err != context.Canceled {
+		t.Fatalf("expected Run to return the context error, got %v", err)
+	}
+	if v, ok := c.Get("foo"); !ok || v != 2 {
+		t.Fatalf("expected queued writes to be applied in order, got %v, %v", v, ok)
+	}
+	if n := b.Pending(); n != 0 {
+		t.Fatalf("expected no pending writes, got %d", n)
+	}
+}
+
+func TestWriteBufferConcurrent(t *testing.T) {
+	c := New[int, int]()
+	b := c.NewWriteBuffer(64)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error)
+	go func() { stopped <- b.Run(ctx) }()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				for !b.Set(i*100+j, j, time.Hour) {
+					time.Sleep(time.Millisecond)
+				}
+				if v, ok := b.Get(i*100 + j); !ok || v != j {
+					t.Errorf("expected %d to read back %d, got %v, %v", i*100+j, j, v, ok)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	cancel()
+	<-stopped
+
+	if n := c.Snapshot().Len(); n != 400 {
+		t.Fatalf("expected all 400 writes to be applied, got %d", n)
+	}
+}
+
+func TestWriteBufferClosed(t *testing.T) {
+	c := New[string, int]()
+	b := c.NewWriteBuffer(4)
+	b.Set("foo", 1, time.Hour)
+
+	stopped := make(chan error)
+	go func() { stopped <- b.Run(context.Background()) }()
+	for b.Pending() != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	c.Close(context.Background())
+	if err := <-stopped; err != ErrClosed {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+	if err := b.Run(context.Background()); err != ErrClosed {
+		t.Fatalf("expected Run on a closed cache to fail, got %v", err)
+	}
+}
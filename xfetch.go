@@ -0,0 +1,46 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// SetWithRecomputeTime is like Set, but also records how long the value
+// took to compute, which lets the key expire early at random when
+// EarlyExpiration is set.
+func (cache *Cache[K, V]) SetWithRecomputeTime(key K, value V, ttl, recompute time.Duration) {
+	cache.mux.Lock()
+	defer cache.mux.Unlock()
+
+	cache.setComputed(key, value, ttl, recompute)
+}
+
+// setComputed sets the specified key, and records how long its value took
+// to compute. cache.mux must be held for writing.
+func (cache *Cache[K, V]) setComputed(key K, value V, ttl, recompute time.Duration) {
+	if bucket := cache.set(key, value, ttl, ttl); bucket != nil {
+		bucket.recompute = recompute
+	}
+}
+
+// expiresEarly reports whether bucket should be considered expired ahead of
+// time, following XFetch: it is if now - recompute * beta * ln(rand) is past
+// its expiration time.
+func (cache *Cache[K, V]) expiresEarly(bucket *cacheBucket[K, V]) bool {
+	if bucket.recompute <= 0 {
+		return false
+	}
+	// 1 - rand.Float64 is in (0, 1], which keeps the logarithm finite.
+	gap := float64(bucket.recompute) * cache.EarlyExpiration * -math.Log(1-rand.Float64())
+	if gap >= math.MaxInt64 {
+		return true
+	}
+	return !cache.instant().Add(time.Duration(gap)).Before(bucket.expiry)
+}
@@ -0,0 +1,83 @@
+// Copyright © Franklin "Snaipe" Mathieu <me@snai.pe>, et al.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ttlcache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestEarlyExpiration(t *testing.T) {
+	now := time.Now()
+	c := New[string, int]()
+	c.Clock = clockFunc(func() time.Time { return now })
+	c.EarlyExpiration = 1
+
+	c.SetWithRecomputeTime("slow", 1, time.Hour, time.Second)
+	c.Set("unknown", 2, time.Hour)
+
+	misses := func(key string) (n int) {
+		for i := 0; i < 1000; i++ {
+			if _, ok := c.Get(key); !ok {
+				n++
+			}
+		}
+		return n
+	}
+
+	// An hour away from expiry, a second of recompute time makes early
+	// expiration vanishingly unlikely.
+	if n := misses("slow"); n != 0 {
+		t.Fatalf("expected no early expiration far from expiry, got %d misses", n)
+	}
+
+	// A second away from expiry, with a second of recompute time, keys
+	// expire early with a probability of 1/e per read.
+	now = now.Add(time.Hour - time.Second)
+	if n := misses("slow"); n < 250 || n > 500 {
+		t.Fatalf("expected about 368 early expirations out of 1000, got %d", n)
+	}
+	if n := misses("unknown"); n != 0 {
+		t.Fatalf("expected keys with an unknown recompute time never to expire early, got %d misses", n)
+	}
+
+	// Peek and Contains see keys the way Get does.
+	var peeked, contained int
+	for i := 0; i < 1000; i++ {
+		if _, ok := c.Peek("slow"); !ok {
+			peeked++
+		}
+		if !c.Contains("slow") {
+			contained++
+		}
+	}
+	if peeked < 250 || peeked > 500 || contained < 250 || contained > 500 {
+		t.Fatalf("expected Peek and Contains to expire keys early like Get, got %d and %d misses", peeked, contained)
+	}
+
+	c.Set("slow", 3, time.Hour)
+	if n := misses("slow"); n != 0 {
+		t.Fatalf("expected setting a key to forget its recompute time, got %d misses", n)
+	}
+}
+
+func TestEarlyExpirationLoader(t *testing.T) {
+	now := time.Now()
+	c := New[string, int]()
+	c.Clock = clockFunc(func() time.Time { return now })
+	c.Loader = func(ctx context.Context, key string) (int, time.Duration, error) {
+		now = now.Add(time.Second)
+		return 1, time.Minute, nil
+	}
+	if _, err := c.GetMulti(context.Background(), []string{"foo"}); err != nil {
+		t.Fatal(err)
+	}
+	if d := c.cache["foo"].recompute; d != time.Second {
+		t.Fatalf("expected the load time to be recorded, got %v", d)
+	}
+}